@@ -0,0 +1,283 @@
+// Package chunker splits extracted text into chunks for embedding. Every
+// chunk records where it came from in the source text, so a chunk can be
+// traced back to the exact passage of the document.
+package chunker
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Strategies
+const (
+	// Fixed cuts windows of Size runes, each starting Size-Overlap runes after
+	// the previous one.
+	Fixed = "fixed"
+	// Recursive splits at paragraphs, then lines, sentences and words until
+	// every piece fits, and packs neighbouring pieces up to Size runes.
+	Recursive = "recursive"
+	// Sentence packs whole sentences up to Size runes.
+	Sentence = "sentence"
+	// Markdown never lets a chunk cross a heading and labels every chunk with
+	// the headings above it.
+	Markdown = "markdown"
+	// Token works like Recursive with Size and Overlap counted in tokens.
+	Token = "token"
+)
+
+// Options select a strategy. Size and Overlap are counted in runes, or in
+// tokens for the token strategy. Except for the fixed strategy the overlap
+// repeats whole pieces (sentences, words), so it may come out shorter.
+type Options struct {
+	Strategy string
+	Size     int
+	Overlap  int
+}
+
+// Chunk is a passage of the source text. Text is always
+// source[Start:End], with Start and End in bytes.
+type Chunk struct {
+	Index   int
+	Text    string
+	Start   int
+	End     int
+	Heading string
+}
+
+// Chunker splits text with one strategy.
+type Chunker struct {
+	opts   Options
+	length func(string) int
+}
+
+type span struct {
+	start, end int
+}
+
+// Separators the recursive strategies split at, from the largest unit to
+// the smallest. Pieces keep their separator so chunks don't lose text.
+var (
+	textSeparators = []string{"\n\n", "\n", ". ", "! ", "? ", "; ", ", ", " "}
+	wordSeparators = []string{"; ", ", ", " "}
+)
+
+func Strategies() []string {
+	return []string{Fixed, Recursive, Sentence, Markdown, Token}
+}
+
+func New(opts Options) (*Chunker, error) {
+	switch opts.Strategy {
+	case Fixed, Recursive, Sentence, Markdown, Token:
+	default:
+		return nil, fmt.Errorf("unknown chunking strategy %q", opts.Strategy)
+	}
+	if opts.Size <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive")
+	}
+	if opts.Overlap < 0 || opts.Overlap >= opts.Size {
+		return nil, fmt.Errorf("chunk overlap must be at least 0 and less than the chunk size")
+	}
+
+	c := &Chunker{opts: opts, length: utf8.RuneCountInString}
+	if opts.Strategy == Token {
+		c.length = CountTokens
+	}
+	return c, nil
+}
+
+// Split returns the chunks of text in order. Whitespace around chunks is
+// left out and text without anything but whitespace has no chunks.
+func (c *Chunker) Split(text string) []Chunk {
+	all := span{0, len(text)}
+
+	var chunks []Chunk
+	switch c.opts.Strategy {
+	case Fixed:
+		chunks = c.build(text, c.fixed(text, all), "")
+	case Recursive, Token:
+		chunks = c.build(text, c.merge(text, c.recursive(text, all, textSeparators)), "")
+	case Sentence:
+		var pieces []span
+		for _, sentence := range sentences(text, all) {
+			pieces = append(pieces, c.recursive(text, sentence, wordSeparators)...)
+		}
+		chunks = c.build(text, c.merge(text, pieces), "")
+	case Markdown:
+		for _, section := range sections(text) {
+			pieces := c.recursive(text, section.span, textSeparators)
+			chunks = append(chunks, c.build(text, c.merge(text, pieces), section.heading)...)
+		}
+	}
+
+	for i := range chunks {
+		chunks[i].Index = i
+	}
+	return chunks
+}
+
+// build trims the spans to chunks. Empty ones are dropped, and so are ones
+// that only repeat the end of the chunk before, which is what trimming leaves
+// of a window that adds nothing but whitespace.
+func (c *Chunker) build(text string, spans []span, heading string) []Chunk {
+	chunks := make([]Chunk, 0, len(spans))
+	for _, s := range spans {
+		part := text[s.start:s.end]
+		start := s.start + len(part) - len(strings.TrimLeftFunc(part, unicode.IsSpace))
+		end := s.start + len(strings.TrimRightFunc(part, unicode.IsSpace))
+		if start >= end || (len(chunks) > 0 && end <= chunks[len(chunks)-1].End) {
+			continue
+		}
+		chunks = append(chunks, Chunk{Text: text[start:end], Start: start, End: end, Heading: heading})
+	}
+	return chunks
+}
+
+// fixed cuts overlapping windows of runes.
+func (c *Chunker) fixed(text string, s span) []span {
+	var offsets []int
+	for i := range text[s.start:s.end] {
+		offsets = append(offsets, s.start+i)
+	}
+	offsets = append(offsets, s.end)
+
+	runes := len(offsets) - 1
+	step := c.opts.Size - c.opts.Overlap
+	var spans []span
+	for first := 0; first < runes; first += step {
+		last := min(first+c.opts.Size, runes)
+		spans = append(spans, span{offsets[first], offsets[last]})
+		if last == runes {
+			break
+		}
+	}
+	return spans
+}
+
+// recursive splits s at the first separator that occurs in it and splits
+// pieces that are still too long at the following separators. Text without
+// any separator is cut where it reaches the size.
+func (c *Chunker) recursive(text string, s span, separators []string) []span {
+	if c.length(text[s.start:s.end]) <= c.opts.Size {
+		return []span{s}
+	}
+	if len(separators) == 0 {
+		return c.cut(text, s)
+	}
+
+	var pieces []span
+	start := s.start
+	for {
+		i := strings.Index(text[start:s.end], separators[0])
+		if i < 0 {
+			break
+		}
+		end := start + i + len(separators[0])
+		pieces = append(pieces, span{start, end})
+		start = end
+	}
+	if start < s.end {
+		pieces = append(pieces, span{start, s.end})
+	}
+	if len(pieces) == 1 {
+		return c.recursive(text, s, separators[1:])
+	}
+
+	var spans []span
+	for _, piece := range pieces {
+		spans = append(spans, c.recursive(text, piece, separators[1:])...)
+	}
+	return spans
+}
+
+// cut splits s into spans of at most the size, counted the way the chunker
+// counts.
+func (c *Chunker) cut(text string, s span) []span {
+	var spans []span
+	var counter tokenCounter
+	start, count := s.start, 0
+	for i, r := range text[s.start:s.end] {
+		n := 1
+		if c.opts.Strategy == Token {
+			n = counter.add(r)
+		}
+		if count+n > c.opts.Size && s.start+i > start {
+			spans = append(spans, span{start, s.start + i})
+			start, count = s.start+i, 0
+			counter = tokenCounter{}
+			if c.opts.Strategy == Token {
+				n = counter.add(r)
+			}
+		}
+		count += n
+	}
+	return append(spans, span{start, s.end})
+}
+
+// merge packs consecutive pieces into chunks of at most the size. The next
+// chunk repeats the trailing pieces of the previous one that fit into the
+// overlap.
+func (c *Chunker) merge(text string, pieces []span) []span {
+	lengths := make([]int, len(pieces))
+	for i, piece := range pieces {
+		lengths[i] = c.length(text[piece.start:piece.end])
+	}
+
+	var spans []span
+	for first := 0; first < len(pieces); {
+		last, size := first, lengths[first]
+		for last+1 < len(pieces) && size+lengths[last+1] <= c.opts.Size {
+			last++
+			size += lengths[last]
+		}
+		spans = append(spans, span{pieces[first].start, pieces[last].end})
+		if last+1 == len(pieces) {
+			break
+		}
+
+		// The overlap leaves room for the next piece, so every chunk adds text
+		next, overlap := last+1, 0
+		room := min(c.opts.Overlap, c.opts.Size-lengths[last+1])
+		for k := last; k > first && overlap+lengths[k] <= room; k-- {
+			overlap += lengths[k]
+			next = k
+		}
+		first = next
+	}
+	return spans
+}
+
+// CountTokens estimates the tokens a subword tokenizer makes of text: about
+// one per four letters or digits of a word and one per punctuation mark or
+// symbol.
+func CountTokens(text string) int {
+	var counter tokenCounter
+	count := 0
+	for _, r := range text {
+		count += counter.add(r)
+	}
+	return count
+}
+
+type tokenCounter struct {
+	word int
+}
+
+// add returns how many tokens r adds to the text before it.
+func (t *tokenCounter) add(r rune) int {
+	switch {
+	case unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Mn, r):
+		t.word++
+		if t.word%4 == 1 {
+			return 1
+		}
+		return 0
+	case unicode.IsSpace(r):
+		t.word = 0
+		return 0
+	default:
+		t.word = 0
+		return 1
+	}
+}
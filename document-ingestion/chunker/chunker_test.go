@@ -0,0 +1,175 @@
+package chunker
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var prose = strings.Repeat("The committee met on Tuesday. Dr. Smith presented the budget, which grew by 4.5% over last year! "+
+	"Questions followed; nobody objected.\n\nThe next meeting is in March.\n", 8)
+
+var markdownText = "# Setup\nInstall the tools first.\n\n## Database\nCreate the schema, then load the fixtures.\n\n" +
+	"```\n# not a heading\n```\n\n# Usage\nRun the server and open the dashboard.\n"
+
+// Inputs that once made splitters loop or cut runes apart
+var pathological = map[string]string{
+	"one long word":   strings.Repeat("a", 5000),
+	"only spaces":     strings.Repeat(" ", 5000),
+	"only newlines":   strings.Repeat("\n", 5000),
+	"only separators": strings.Repeat(". , ; ! ? \n\n", 500),
+	"only marks":      strings.Repeat(".", 5000),
+	"multi-byte word": strings.Repeat("é", 3000),
+	"emoji":           strings.Repeat("😀", 2000),
+	"combining marks": strings.Repeat("é", 2000),
+	"mixed scripts":   strings.Repeat("Привет мир. 你好世界。 مرحبا بالعالم! ", 100),
+	"headings only":   strings.Repeat("# \n", 1000),
+}
+
+func split(t *testing.T, opts Options, text string) []Chunk {
+	t.Helper()
+	c, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan []Chunk, 1)
+	go func() { done <- c.Split(text) }()
+	select {
+	case chunks := <-done:
+		return chunks
+	case <-time.After(10 * time.Second):
+		t.Fatalf("%s did not finish", opts.Strategy)
+		return nil
+	}
+}
+
+// checkChunks verifies what every strategy promises: chunks point back at
+// their source text, cut at rune boundaries, keep to the size and overlap,
+// move forward and leave no text out.
+func checkChunks(t *testing.T, name string, opts Options, text string, chunks []Chunk) {
+	t.Helper()
+	length := utf8.RuneCountInString
+	if opts.Strategy == Token {
+		length = CountTokens
+	}
+
+	covered := make([]bool, len(text))
+	for i, chunk := range chunks {
+		if chunk.Index != i {
+			t.Errorf("%s: chunk %d has index %d", name, i, chunk.Index)
+		}
+		if chunk.Start < 0 || chunk.End > len(text) || chunk.Start >= chunk.End {
+			t.Errorf("%s: chunk %d has offsets %d-%d", name, i, chunk.Start, chunk.End)
+			continue
+		}
+		if chunk.Text != text[chunk.Start:chunk.End] {
+			t.Errorf("%s: chunk %d text doesn't match its offsets", name, i)
+		}
+		if !utf8.ValidString(chunk.Text) || !utf8.RuneStart(text[chunk.Start]) || (chunk.End < len(text) && !utf8.RuneStart(text[chunk.End])) {
+			t.Errorf("%s: chunk %d splits a rune: %q", name, i, chunk.Text)
+		}
+		if n := length(chunk.Text); n > opts.Size {
+			t.Errorf("%s: chunk %d is %d long, the size is %d", name, i, n, opts.Size)
+		}
+
+		if i > 0 {
+			previous := chunks[i-1]
+			if chunk.Start < previous.Start || chunk.End <= previous.End {
+				t.Errorf("%s: chunk %d (%d-%d) doesn't move past chunk %d (%d-%d)", name, i, chunk.Start, chunk.End, i-1, previous.Start, previous.End)
+			}
+			if chunk.Start < previous.End {
+				if n := length(text[chunk.Start:previous.End]); n > opts.Overlap {
+					t.Errorf("%s: chunks %d and %d overlap by %d, the overlap is %d", name, i-1, i, n, opts.Overlap)
+				}
+			}
+		}
+		for j := chunk.Start; j < chunk.End; j++ {
+			covered[j] = true
+		}
+	}
+
+	for i, r := range text {
+		if !covered[i] && !unicode.IsSpace(r) {
+			t.Errorf("%s: %q at byte %d is in no chunk", name, r, i)
+			break
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	inputs := map[string]string{"prose": prose, "markdown": markdownText, "empty": ""}
+	for name, text := range pathological {
+		inputs[name] = text
+	}
+
+	for _, strategy := range Strategies() {
+		for _, sizes := range [][2]int{{60, 0}, {60, 15}, {200, 50}, {7, 3}, {1, 0}} {
+			opts := Options{Strategy: strategy, Size: sizes[0], Overlap: sizes[1]}
+			for name, text := range inputs {
+				chunks := split(t, opts, text)
+				checkChunks(t, strategy+"/"+name, opts, text, chunks)
+				if strings.TrimSpace(text) == "" && len(chunks) > 0 {
+					t.Errorf("%s/%s: got %d chunks of whitespace", strategy, name, len(chunks))
+				}
+			}
+		}
+	}
+}
+
+func TestFixedOverlap(t *testing.T) {
+	text := strings.Repeat("ab😀", 100)
+	chunks := split(t, Options{Strategy: Fixed, Size: 10, Overlap: 4}, text)
+
+	for i := 1; i < len(chunks); i++ {
+		overlap := text[chunks[i].Start:chunks[i-1].End]
+		if n := utf8.RuneCountInString(overlap); n != 4 {
+			t.Errorf("chunks %d and %d overlap by %d runes, want 4", i-1, i, n)
+		}
+	}
+	if n := utf8.RuneCountInString(chunks[0].Text); n != 10 {
+		t.Errorf("first chunk has %d runes, want 10", n)
+	}
+}
+
+func TestSentenceBoundaries(t *testing.T) {
+	text := "Dr. Smith arrived at 9 a.m. on Monday. He sat down. The meeting began."
+	chunks := split(t, Options{Strategy: Sentence, Size: 40, Overlap: 0}, text)
+
+	var got []string
+	for _, chunk := range chunks {
+		got = append(got, chunk.Text)
+	}
+	want := []string{"Dr. Smith arrived at 9 a.m. on Monday.", "He sat down. The meeting began."}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMarkdownHeadings(t *testing.T) {
+	chunks := split(t, Options{Strategy: Markdown, Size: 200, Overlap: 0}, markdownText)
+
+	var headings []string
+	for _, chunk := range chunks {
+		headings = append(headings, chunk.Heading)
+	}
+	want := "Setup|Setup > Database|Usage"
+	if strings.Join(headings, "|") != want {
+		t.Errorf("got headings %q, want %q", headings, want)
+	}
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	for _, opts := range []Options{
+		{Strategy: "paragraph", Size: 100},
+		{Strategy: Fixed, Size: 0},
+		{Strategy: Fixed, Size: 100, Overlap: 100},
+		{Strategy: Recursive, Size: 100, Overlap: -1},
+	} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("New(%+v) accepted invalid options", opts)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package chunker
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Words that end with a period without ending the sentence, lower case and
+// without the period.
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "st": true,
+	"vs": true, "etc": true, "e.g": true, "i.e": true, "no": true, "fig": true,
+	"inc": true, "ltd": true, "jr": true, "sr": true, "approx": true,
+	"z.b": true, "bzw": true, "usw": true, "vgl": true, "nr": true, "ca": true,
+	"s": true, "u.a": true, "d.h": true,
+	"yrd": true, "doç": true, "sn": true, "vb": true, "bkz": true,
+}
+
+// sentences splits s after sentence-ending punctuation that is followed by
+// whitespace and not by a lower case word, and at blank lines. Sentences keep
+// the whitespace after them.
+func sentences(text string, s span) []span {
+	var spans []span
+	start := s.start
+	i := s.start
+	for i < s.end {
+		r, size := utf8.DecodeRuneInString(text[i:s.end])
+		i += size
+
+		boundary := false
+		switch {
+		case r == '\n':
+			next, _ := utf8.DecodeRuneInString(text[i:s.end])
+			boundary = next == '\n'
+		case strings.ContainsRune(".!?…", r):
+			// Repeated marks and closing quotes or brackets belong to the sentence
+			for i < s.end {
+				next, size := utf8.DecodeRuneInString(text[i:s.end])
+				if !strings.ContainsRune(".!?…\"'”’»)]", next) {
+					break
+				}
+				i += size
+			}
+			boundary = endsSentence(text, start, i, s.end, r)
+		}
+		if !boundary {
+			continue
+		}
+
+		for i < s.end {
+			next, size := utf8.DecodeRuneInString(text[i:s.end])
+			if !unicode.IsSpace(next) {
+				break
+			}
+			i += size
+		}
+		spans = append(spans, span{start, i})
+		start = i
+	}
+	if start < s.end {
+		spans = append(spans, span{start, s.end})
+	}
+	return spans
+}
+
+// endsSentence reports whether the mark before end closes the sentence that
+// started at start.
+func endsSentence(text string, start, end, limit int, mark rune) bool {
+	if end == limit {
+		return true
+	}
+	next, size := utf8.DecodeRuneInString(text[end:limit])
+	if !unicode.IsSpace(next) {
+		return false
+	}
+
+	// The first word after the mark
+	rest := strings.TrimLeftFunc(text[end+size:limit], unicode.IsSpace)
+	if first, _ := utf8.DecodeRuneInString(rest); unicode.IsLower(first) {
+		return false
+	}
+
+	if mark == '.' {
+		before := strings.TrimRight(text[start:end], ".\"'”’»)]")
+		word := before[strings.LastIndexFunc(before, func(r rune) bool {
+			return unicode.IsSpace(r) || r == '(' || r == '"'
+		})+1:]
+		word = strings.ToLower(word)
+		if abbreviations[word] {
+			return false
+		}
+		// Initials such as "J. Smith"
+		if utf8.RuneCountInString(word) == 1 {
+			r, _ := utf8.DecodeRuneInString(word)
+			return !unicode.IsLetter(r)
+		}
+	}
+	return true
+}
+
+var headingPattern = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+
+type section struct {
+	span
+	heading string
+}
+
+// sections splits Markdown at ATX headings outside fenced code blocks. Each
+// section starts with its heading line and is labelled with the path of
+// headings above it, such as "Setup > Database".
+func sections(text string) []section {
+	var result []section
+	var titles []string
+	heading := ""
+	start := 0
+	fence := ""
+
+	for offset := 0; offset < len(text); {
+		line := text[offset:]
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i+1]
+		}
+		lineStart := offset
+		offset += len(line)
+		trimmed := strings.TrimRight(line, "\r\n")
+
+		if marker := fenceMarker(trimmed); marker != "" {
+			if fence == "" {
+				fence = marker
+			} else if strings.HasPrefix(marker, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+
+		match := headingPattern.FindStringSubmatch(trimmed)
+		if match == nil {
+			continue
+		}
+
+		if lineStart > start {
+			result = append(result, section{span{start, lineStart}, heading})
+		}
+		level := len(match[1])
+		if len(titles) >= level {
+			titles = titles[:level-1]
+		}
+		for len(titles) < level-1 {
+			titles = append(titles, "")
+		}
+		titles = append(titles, match[2])
+		heading = joinTitles(titles)
+		start = lineStart
+	}
+	if start < len(text) {
+		result = append(result, section{span{start, len(text)}, heading})
+	}
+	return result
+}
+
+func fenceMarker(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return ""
+	}
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(trimmed, marker) {
+			return trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, marker[:1]))]
+		}
+	}
+	return ""
+}
+
+// joinTitles skips the levels a document jumped over.
+func joinTitles(titles []string) string {
+	var parts []string
+	for _, title := range titles {
+		if title != "" {
+			parts = append(parts, title)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Helpers for reading optional settings from the environment. A value that
+// can't be parsed falls back to the default with a warning instead of
+// stopping the service.
+
+func String(key, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func Int(key string, fallback int) int {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid value %q for %s, using %d", value, key, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func Int64(key string, fallback int64) int64 {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("Warning: invalid value %q for %s, using %d", value, key, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func Bool(key string, fallback bool) bool {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: invalid value %q for %s, using %t", value, key, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func Duration(key string, fallback time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid value %q for %s, using %s", value, key, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// List splits a comma separated value, dropping empty items.
+func List(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// A batch is processing until every item is done. It is then completed when
+// every item was ingested, failed when none was and completed_with_errors
+// otherwise, failed items include rejected and quarantined ones.
+const StatusCompletedWithErrors = "completed_with_errors"
+
+type Batch struct {
+	ID        string      `json:"id" bson:"id"`
+	Status    string      `json:"status" bson:"status"`
+	CreatedAt time.Time   `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" bson:"updated_at"`
+	Items     []BatchItem `json:"items" bson:"items"`
+}
+
+type BatchItem struct {
+	FileName   string      `json:"filename" bson:"filename"`
+	DocumentID string      `json:"document_id,omitempty" bson:"document_id,omitempty"`
+	Status     string      `json:"status" bson:"status"`
+	Error      string      `json:"error,omitempty" bson:"error,omitempty"`
+	Rejections []Rejection `json:"rejections,omitempty" bson:"rejections,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// QueryCacheEntry is a cached query embedding. Query is the normalized query
+// text, entries of another model than the current one are never used.
+type QueryCacheEntry struct {
+	Query     string    `json:"query" bson:"query"`
+	Model     string    `json:"model" bson:"model"`
+	Vector    []float32 `json:"vector" bson:"vector"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
@@ -2,16 +2,63 @@ package models
 
 import (
 	"time"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
 )
 
 type Document struct {
-	ID string 			  `json:"id"`
-	FileName string		  `json:"filename"`
-	ContentType string	  `json:"content_type"`
-	Content []byte		  `json:"-"`
-	Size int64 			  `json:"size"`
-	UploadedAt  time.Time `json:"uploaded_at"`
-	Status      string    `json:"status"`
+	ID string 			  `json:"id" bson:"id"`
+	FileName string		  `json:"filename" bson:"filename"`
+	ContentType string	  `json:"content_type" bson:"content_type"`
+	Format string		  `json:"format" bson:"format"`
+	Encoding string		  `json:"encoding,omitempty" bson:"encoding"`
+	Content *spool.File	  `json:"-" bson:"-"`
+	Size int64 			  `json:"size" bson:"size"`
+	SHA256 string		  `json:"sha256" bson:"sha256"`
+	UploadedAt  time.Time `json:"uploaded_at" bson:"uploaded_at"`
+	Status      string    `json:"status" bson:"status"`
+	Error       string    `json:"error,omitempty" bson:"error"`
+	Kind        string    `json:"kind" bson:"kind"`
+	ParentID    string    `json:"parent_id,omitempty" bson:"parent_id"`
+	Metadata    map[string]string `json:"metadata,omitempty" bson:"metadata"`
+	Rejected    []Rejection `json:"rejected,omitempty" bson:"rejected"`
+	Source      *Source     `json:"source,omitempty" bson:"source"`
+	Version     int         `json:"version,omitempty" bson:"version"`
+	SupersededBy string     `json:"superseded_by,omitempty" bson:"superseded_by"`
+	PII         map[string]int `json:"pii,omitempty" bson:"pii,omitempty"`
+	Processing  *ProcessingOptions `json:"processing,omitempty" bson:"processing,omitempty"`
+	// OCRPages lists the pages that only hold images, when the ingestion
+	// service read a PDF without recognizing them
+	OCRPages    []int       `json:"ocr_pages,omitempty" bson:"ocr_pages,omitempty"`
+	// Language is the language most of the document's text is in
+	Language    string      `json:"language,omitempty" bson:"language,omitempty"`
+	Children    []*Document `json:"-" bson:"-"`
+	// InArchive is set for documents found in an archive, directly or as
+	// an attachment of an entry, archives within them are not expanded
+	InArchive   bool        `json:"-" bson:"-"`
+	// Depth counts the documents the document was found in, attachments of
+	// an upload are at depth 1
+	Depth       int         `json:"-" bson:"-"`
+}
+
+// Rejection records why an upload, or a part of it (archive entry,
+// attachment), was not ingested. Validation failures name the validator and a
+// machine readable code.
+type Rejection struct {
+	FileName  string `json:"filename" bson:"filename"`
+	Reason    string `json:"reason" bson:"reason"`
+	Validator string `json:"validator,omitempty" bson:"validator,omitempty"`
+	Code      string `json:"code,omitempty" bson:"code,omitempty"`
+}
+
+// Source records where a fetched document came from, the validators are sent
+// back when the document is refreshed
+type Source struct {
+	URL          string    `json:"url" bson:"url"`
+	FinalURL     string    `json:"final_url,omitempty" bson:"final_url,omitempty"`
+	ETag         string    `json:"etag,omitempty" bson:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty" bson:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at" bson:"fetched_at"`
 }
 
 type DocumentChunk struct {
@@ -19,10 +66,42 @@ type DocumentChunk struct {
     ChunkIndex  int       `json:"chunk_index" bson:"chunk_index"`
     Text        string    `json:"text" bson:"text"`
     Vector      []float32 `json:"vector" bson:"vector"`
+    Model       string    `json:"model" bson:"model"`
+    PII         []string  `json:"pii,omitempty" bson:"pii,omitempty"`
+    // Offsets into the extracted text, only chunks made by the ingestion
+    // service have them
+    StartOffset int       `json:"start_offset" bson:"start_offset"`
+    EndOffset   int       `json:"end_offset" bson:"end_offset"`
+    Heading     string    `json:"heading,omitempty" bson:"heading,omitempty"`
+    // Page is the PDF page the chunk starts on, when the ingestion service
+    // read the PDF
+    Page        int       `json:"page,omitempty" bson:"page,omitempty"`
+    Language    string    `json:"language,omitempty" bson:"language,omitempty"`
 }
 
+// ProcessingOptions are the settings a document was processed with, kept so
+// processing it again gives the same chunks. Without a chunk strategy the
+// processing service chunks with its defaults, a zero size as well.
+type ProcessingOptions struct {
+	ChunkStrategy string `json:"chunk_strategy,omitempty" bson:"chunk_strategy,omitempty"`
+	ChunkSize     int    `json:"chunk_size,omitempty" bson:"chunk_size,omitempty"`
+	ChunkOverlap  int    `json:"chunk_overlap" bson:"chunk_overlap"`
+	Language      string `json:"language,omitempty" bson:"language,omitempty"`
+	OCR           bool   `json:"ocr" bson:"ocr"`
+}
+
+const (
+	StatusReceived    = "received"
+	StatusProcessing  = "processing"
+	StatusCompleted   = "completed"
+	StatusFailed      = "failed"
+	StatusRejected    = "rejected"
+	StatusQuarantined = "quarantined"
+)
+
+// A collection only groups its children (mbox messages, archive entries) and
+// has no text of its own to process
 const (
-	StatusReceived   = "received"
-	StatusProcessing = "processing"
-	StatusCompleted  = "completed"
+	KindDocument   = "document"
+	KindCollection = "collection"
 )
\ No newline at end of file
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// Migration re-embeds every chunk with another model. Vectors are written to
+// the slot search doesn't use, so search keeps working until the switch.
+type Migration struct {
+	ID         string    `json:"id" bson:"id"`
+	FromModel  string    `json:"from_model" bson:"from_model"`
+	ToModel    string    `json:"to_model" bson:"to_model"`
+	FromPath   string    `json:"from_path" bson:"from_path"`
+	ToPath     string    `json:"to_path" bson:"to_path"`
+	Status     string    `json:"status" bson:"status"`
+	AutoSwitch bool      `json:"auto_switch" bson:"auto_switch"`
+	Checkpoint string    `json:"checkpoint,omitempty" bson:"checkpoint,omitempty"`
+	Processed  int64     `json:"processed" bson:"processed"`
+	Total      int64     `json:"total" bson:"total"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at" bson:"started_at"`
+	UpdatedAt  time.Time `json:"updated_at" bson:"updated_at"`
+	SwitchedAt time.Time `json:"switched_at,omitempty" bson:"switched_at,omitempty"`
+}
+
+const (
+	MigrationRunning    = "running"
+	MigrationPaused     = "paused"
+	MigrationReady      = "ready"
+	MigrationSwitched   = "switched"
+	MigrationRolledBack = "rolled_back"
+	MigrationFailed     = "failed"
+	MigrationCancelled  = "cancelled"
+)
+
+// VectorSlot is where search reads vectors from and which model wrote them.
+type VectorSlot struct {
+	Path  string `json:"path" bson:"path"`
+	Model string `json:"model" bson:"model"`
+}
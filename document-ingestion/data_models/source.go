@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshSource is a URL that is re-fetched on a schedule. DocumentID always
+// points at the current version of the document.
+type RefreshSource struct {
+	ID         string    `json:"id" bson:"id"`
+	URL        string    `json:"url" bson:"url"`
+	Schedule   string    `json:"schedule" bson:"schedule"`
+	Enabled    bool      `json:"enabled" bson:"enabled"`
+	DocumentID string    `json:"document_id,omitempty" bson:"document_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+	NextRun    time.Time `json:"next_run" bson:"next_run"`
+	LastRun    time.Time `json:"last_run,omitempty" bson:"last_run,omitempty"`
+	LastResult string    `json:"last_result,omitempty" bson:"last_result,omitempty"`
+}
+
+// Refresh is one entry of a source's refresh history
+type Refresh struct {
+	SourceID   string    `json:"source_id" bson:"source_id"`
+	StartedAt  time.Time `json:"started_at" bson:"started_at"`
+	FinishedAt time.Time `json:"finished_at" bson:"finished_at"`
+	Result     string    `json:"result" bson:"result"`
+	DocumentID string    `json:"document_id,omitempty" bson:"document_id,omitempty"`
+	PreviousID string    `json:"previous_id,omitempty" bson:"previous_id,omitempty"`
+	Version    int       `json:"version,omitempty" bson:"version,omitempty"`
+	SHA256     string    `json:"sha256,omitempty" bson:"sha256,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// Refresh results
+const (
+	RefreshNotModified = "not_modified"
+	RefreshUnchanged   = "unchanged"
+	RefreshUpdated     = "updated"
+	RefreshFailed      = "failed"
+)
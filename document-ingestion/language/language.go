@@ -0,0 +1,186 @@
+// Package language detects the language of texts and splits them into the
+// words the keyword index stores. Detection compares the character trigrams
+// of a text with profiles of English, German and Turkish built from sample
+// texts, so it works offline.
+package language
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Languages are ISO 639-1 codes.
+const (
+	English = "en"
+	German  = "de"
+	Turkish = "tr"
+)
+
+const (
+	// minTrigrams is how many trigrams a text needs to be detected, shorter
+	// texts such as titles don't tell the languages apart reliably
+	minTrigrams = 20
+	// maxRunes bounds how much of a text is read
+	maxRunes = 5000
+	// minMargin is how much better the best profile must fit than the
+	// next, in average log probability per trigram
+	minMargin = 0.1
+)
+
+// profile holds the log probabilities of the trigrams of a language. Unseen
+// trigrams share the probability left over by add-one smoothing.
+type profile struct {
+	language string
+	logProb  map[string]float64
+	unseen   float64
+}
+
+var profiles = buildProfiles()
+
+func buildProfiles() []profile {
+	var result []profile
+	for _, language := range Supported() {
+		counts := make(map[string]int)
+		total := 0
+		for _, gram := range trigrams(samples[language], -1) {
+			counts[gram]++
+			total++
+		}
+
+		vocabulary := float64(len(counts) + 1)
+		p := profile{
+			language: language,
+			logProb:  make(map[string]float64, len(counts)),
+			unseen:   math.Log(1 / (float64(total) + vocabulary)),
+		}
+		for gram, count := range counts {
+			p.logProb[gram] = math.Log(float64(count+1) / (float64(total) + vocabulary))
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// Supported returns the languages that can be detected.
+func Supported() []string {
+	return []string{English, German, Turkish}
+}
+
+// IsSupported reports whether language is detected and stemmed.
+func IsSupported(language string) bool {
+	for _, supported := range Supported() {
+		if language == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect returns the language of text, or "" when the text is too short or
+// doesn't clearly match one of the supported languages.
+func Detect(text string) string {
+	grams := trigrams(text, maxRunes)
+	if len(grams) < minTrigrams {
+		return ""
+	}
+
+	best, next := math.Inf(-1), math.Inf(-1)
+	detected := ""
+	for _, p := range profiles {
+		score := 0.0
+		for _, gram := range grams {
+			if logProb, ok := p.logProb[gram]; ok {
+				score += logProb
+			} else {
+				score += p.unseen
+			}
+		}
+		score /= float64(len(grams))
+
+		if score > best {
+			best, next = score, best
+			detected = p.language
+		} else if score > next {
+			next = score
+		}
+	}
+
+	if best-next < minMargin {
+		return ""
+	}
+	return detected
+}
+
+// trigrams returns the character trigrams of the words in the first limit
+// runes of text, a negative limit reads all of it. Words are padded with a
+// space so their beginnings and endings count.
+func trigrams(text string, limit int) []string {
+	if limit >= 0 && len(text) > limit*utf8.UTFMax {
+		text = text[:limit*utf8.UTFMax]
+	}
+
+	var grams []string
+	read := 0
+	for _, word := range strings.FieldsFunc(text, notLetter) {
+		runes := []rune(" " + strings.ToLower(word) + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			grams = append(grams, string(runes[i:i+3]))
+		}
+		read += len(runes) - 1
+		if limit >= 0 && read >= limit {
+			break
+		}
+	}
+	return grams
+}
+
+func notLetter(r rune) bool {
+	return !unicode.IsLetter(r)
+}
+
+// Keywords returns the words of text the way the keyword index stores them
+// for language. Words are lower cased by the rules of the language, so
+// Turkish keeps I and ı apart from İ and i. Suffixes after an apostrophe are
+// dropped in English and Turkish ("company's", "Ankara'da"), German words
+// keep them without the apostrophe. Stemming is left to the index.
+func Keywords(text, language string) []string {
+	text = norm.NFC.String(text)
+	if language == Turkish {
+		text = strings.ToLowerSpecial(unicode.TurkishCase, text)
+	} else {
+		text = strings.ToLower(text)
+	}
+
+	var words []string
+	for _, word := range strings.FieldsFunc(text, notWord) {
+		word = strings.TrimFunc(word, isApostrophe)
+		if language == German {
+			word = strings.Map(dropApostrophe, word)
+		} else if i := strings.IndexFunc(word, isApostrophe); i >= 0 {
+			word = word[:i]
+		}
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+func notWord(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !isApostrophe(r)
+}
+
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’'
+}
+
+func dropApostrophe(r rune) rune {
+	if isApostrophe(r) {
+		return -1
+	}
+	return r
+}
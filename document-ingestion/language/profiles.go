@@ -0,0 +1,91 @@
+package language
+
+// samples are the texts the n-gram profiles are built from. They cover the
+// same topics in each language so the profiles differ by language rather
+// than by subject.
+var samples = map[string]string{
+	English: `The quick development of new technology has changed the way people work
+and live. Most companies now store their documents in digital form, and they
+expect to find the information they need within seconds. This is why search
+systems have become an important part of every modern organization. When a
+user asks a question, the system should understand what the words mean and
+return the most relevant results. Language is not always simple, however. The
+same word can have different meanings, and people write in many different
+styles. Some documents are short and informal, while others are long reports
+with tables, figures and references. There are also contracts, invoices,
+emails and meeting notes that were written by different teams over many
+years. A good search engine must handle all of these cases and still be fast
+and reliable. It should also respect the privacy of the people who are
+mentioned in the documents. In the following sections we describe how the
+data is collected, how it is processed and how the results are ranked for
+each query. We also explain which settings can be changed and what happens
+when something goes wrong during the process.
+
+Please find attached the invoice for the services we provided last month.
+The payment is due within thirty days of the date shown on the invoice. If
+you have any questions about this bill, do not hesitate to contact our
+office. We would like to thank you for your order and look forward to working
+with you again. The meeting with the customer has been moved to Thursday
+morning because several members of the team were not available. Everyone
+should bring the latest version of the report and their notes from the
+previous discussion. After the meeting we will decide which of the proposed
+changes should be made first and who will be responsible for them.`,
+
+	German: `Die schnelle Entwicklung neuer Technologien hat die Art und Weise
+verändert, wie Menschen arbeiten und leben. Die meisten Unternehmen speichern
+ihre Dokumente heute in digitaler Form und erwarten, dass sie die benötigten
+Informationen innerhalb von Sekunden finden. Deshalb sind Suchsysteme zu
+einem wichtigen Bestandteil jeder modernen Organisation geworden. Wenn ein
+Benutzer eine Frage stellt, soll das System verstehen, was die Wörter
+bedeuten, und die passendsten Ergebnisse zurückgeben. Sprache ist jedoch
+nicht immer einfach. Dasselbe Wort kann unterschiedliche Bedeutungen haben,
+und Menschen schreiben in sehr verschiedenen Stilen. Manche Dokumente sind
+kurz und formlos, während andere lange Berichte mit Tabellen, Abbildungen und
+Verweisen sind. Außerdem gibt es Verträge, Rechnungen, E-Mails und
+Besprechungsnotizen, die über viele Jahre von verschiedenen Teams geschrieben
+wurden. Eine gute Suchmaschine muss alle diese Fälle behandeln und trotzdem
+schnell und zuverlässig sein. Sie sollte auch die Privatsphäre der Personen
+schützen, die in den Dokumenten erwähnt werden. In den folgenden Abschnitten
+beschreiben wir, wie die Daten gesammelt und verarbeitet werden und wie die
+Ergebnisse für jede Anfrage sortiert werden. Wir erklären auch, welche
+Einstellungen geändert werden können und was passiert, wenn während des
+Vorgangs ein Fehler auftritt.
+
+Anbei erhalten Sie die Rechnung für die Leistungen, die wir im letzten Monat
+erbracht haben. Die Zahlung ist innerhalb von dreißig Tagen nach dem auf der
+Rechnung angegebenen Datum fällig. Wenn Sie Fragen zu dieser Rechnung haben,
+wenden Sie sich bitte jederzeit an unser Büro. Wir bedanken uns für Ihren
+Auftrag und freuen uns auf die weitere Zusammenarbeit. Das Treffen mit dem
+Kunden wurde auf Donnerstagmorgen verschoben, weil mehrere Mitglieder des
+Teams nicht verfügbar waren. Alle sollten die neueste Version des Berichts
+und ihre Notizen aus der letzten Besprechung mitbringen. Nach dem Treffen
+entscheiden wir, welche der vorgeschlagenen Änderungen zuerst umgesetzt
+werden und wer dafür verantwortlich ist.`,
+
+	Turkish: `Yeni teknolojilerin hızlı gelişimi, insanların çalışma ve yaşama
+biçimini değiştirdi. Şirketlerin çoğu artık belgelerini dijital ortamda
+saklıyor ve ihtiyaç duydukları bilgiyi birkaç saniye içinde bulmayı bekliyor.
+Bu yüzden arama sistemleri her modern kuruluşun önemli bir parçası haline
+geldi. Bir kullanıcı soru sorduğunda, sistemin kelimelerin ne anlama
+geldiğini anlaması ve en uygun sonuçları döndürmesi gerekir. Ancak dil her
+zaman basit değildir. Aynı kelimenin farklı anlamları olabilir ve insanlar
+çok farklı üsluplarla yazarlar. Bazı belgeler kısa ve gayri resmi iken,
+diğerleri tablolar, şekiller ve kaynaklar içeren uzun raporlardır. Ayrıca
+yıllar boyunca farklı ekipler tarafından yazılmış sözleşmeler, faturalar,
+e-postalar ve toplantı notları da vardır. İyi bir arama motoru bütün bu
+durumları ele almalı ve yine de hızlı ve güvenilir olmalıdır. Belgelerde adı
+geçen kişilerin gizliliğine de saygı göstermelidir. Aşağıdaki bölümlerde
+verilerin nasıl toplandığını, nasıl işlendiğini ve her sorgu için sonuçların
+nasıl sıralandığını anlatıyoruz. Ayrıca hangi ayarların değiştirilebileceğini
+ve işlem sırasında bir hata oluştuğunda ne olduğunu da açıklıyoruz.
+
+Geçen ay sunduğumuz hizmetlere ait faturayı ekte bulabilirsiniz. Ödemenin
+fatura üzerinde belirtilen tarihten itibaren otuz gün içinde yapılması
+gerekmektedir. Bu faturayla ilgili sorularınız olursa lütfen ofisimizle
+iletişime geçmekten çekinmeyin. Siparişiniz için teşekkür eder, sizinle
+yeniden çalışmayı dört gözle bekleriz. Müşteriyle yapılacak toplantı, ekibin
+birkaç üyesi müsait olmadığı için perşembe sabahına ertelendi. Herkes raporun
+son sürümünü ve bir önceki görüşmeden aldığı notları yanında getirmelidir.
+Toplantıdan sonra önerilen değişikliklerden hangilerinin önce yapılacağına ve
+bunlardan kimin sorumlu olacağına karar vereceğiz.`,
+}
@@ -14,9 +14,13 @@ import (
 
 func main() {
 
-    processorClient, err := processor.NewClient()
+    if err := reader.LoadRedaction(); err != nil {
+        log.Fatalf("Failed to initialize PII detection: %v", err)
+    }
+
+    processorClient, err := processor.New()
     if err != nil {
-		log.Fatalf("Failed to initialize processor client: %v", err)
+		log.Fatalf("Failed to initialize processor: %v", err)
 	}
 	defer processorClient.Close()
 
@@ -38,10 +42,87 @@ func main() {
         reader.HandleUpload(w, r, processorClient, mongodb)
     })
 
+    batchQueue := reader.NewBatchQueue()
+
+    http.HandleFunc("/uploads/batch", func(w http.ResponseWriter, r *http.Request) {
+        reader.HandleBatchUpload(w, r, processorClient, mongodb, batchQueue)
+    })
+
+    http.HandleFunc("/batches/", func(w http.ResponseWriter, r *http.Request) {
+        reader.HandleGetBatch(w, r, mongodb)
+    })
+
+    tusStore, err := reader.NewTusStore()
+    if err != nil {
+        log.Fatalf("Failed to initialize resumable uploads: %v", err)
+    }
+
+    http.HandleFunc("/files/", func(w http.ResponseWriter, r *http.Request) {
+        reader.HandleTus(w, r, tusStore, processorClient, mongodb)
+    })
+
+    urlFetcher := reader.NewURLFetcher()
+
+    http.HandleFunc("/documents/from-url", func(w http.ResponseWriter, r *http.Request) {
+        reader.HandleFromURL(w, r, urlFetcher, processorClient, mongodb)
+    })
+
+    refreshScheduler := reader.NewRefreshScheduler(urlFetcher, processorClient, mongodb)
+    go refreshScheduler.Run()
+
+    http.HandleFunc("/sources", func(w http.ResponseWriter, r *http.Request) {
+        reader.HandleSources(w, r, refreshScheduler, mongodb)
+    })
+
+    http.HandleFunc("/sources/", func(w http.ResponseWriter, r *http.Request) {
+        reader.HandleSource(w, r, refreshScheduler, mongodb)
+    })
+
+    migrator, err := reader.NewMigrator(processorClient, mongodb)
+    if err != nil {
+        log.Fatalf("Failed to initialize migrator: %v", err)
+    }
+    go migrator.Run()
+
+    http.HandleFunc("/migrations", func(w http.ResponseWriter, r *http.Request) {
+        reader.HandleMigrations(w, r, migrator, mongodb)
+    })
+
+    http.HandleFunc("/migrations/", func(w http.ResponseWriter, r *http.Request) {
+        reader.HandleMigration(w, r, migrator, mongodb)
+    })
+
+    watcher, err := reader.NewDirectoryWatcher(processorClient, mongodb)
+    if err != nil {
+        log.Fatalf("Failed to initialize directory watcher: %v", err)
+    }
+    if watcher != nil {
+        go watcher.Run()
+    }
+
+    // Chunks stored before chunks carried their model were embedded by the
+    // configured one
+    if labeled, err := mongodb.LabelChunks(processorClient.Embedder().Model()); err != nil {
+        log.Printf("Warning: %v", err)
+    } else if labeled > 0 {
+        fmt.Printf("Labeled %d chunks with model %s\n", labeled, processorClient.Embedder().Model())
+    }
+
+    queryEmbedder, err := processor.NewQueryCache(processorClient.Embedder(), mongodb)
+    if err != nil {
+        log.Fatalf("Failed to initialize query cache: %v", err)
+    }
+
     http.HandleFunc("/search",func(w http.ResponseWriter, r *http.Request){
-        reader.HandleSearch(w, r, processorClient, mongodb)
+        reader.HandleSearch(w, r, queryEmbedder, mongodb)
+    })
+
+    http.HandleFunc("/search/cache", func(w http.ResponseWriter, r *http.Request) {
+        reader.HandleQueryCache(w, r, queryEmbedder)
     })
 
+    http.HandleFunc("/formats", reader.HandleFormats)
+
     http.HandleFunc("/health", reader.HealthCheckHandler)
 
     // Start HTTP server
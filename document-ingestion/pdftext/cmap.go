@@ -0,0 +1,167 @@
+package pdftext
+
+import (
+	"unicode/utf16"
+)
+
+// maxRange bounds how many codes one bfrange may map, so a corrupt range
+// can't exhaust memory.
+const maxRange = 0x10000
+
+// code is a character code of a shown string. Codes of different lengths are
+// different codes, <00> isn't <0000>.
+type code struct {
+	value  uint32
+	length int
+}
+
+type codeRange struct {
+	low, high []byte
+}
+
+// cmap maps character codes to text, as ToUnicode streams do, and knows how
+// many bytes the codes of a string take.
+type cmap struct {
+	codespace []codeRange
+	text      map[code]string
+}
+
+// parseCMap reads the codespace ranges, bfchar and bfrange mappings of a
+// CMap stream, everything else in it is skipped.
+func parseCMap(data []byte) *cmap {
+	m := &cmap{text: make(map[code]string)}
+	l := &lexer{data: data}
+	var operands []object
+
+	for {
+		obj, err := l.object()
+		if err != nil {
+			if err == errDelimiter {
+				continue
+			}
+			return m
+		}
+		op, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				low, ok1 := operands[i].([]byte)
+				high, ok2 := operands[i+1].([]byte)
+				if ok1 && ok2 && len(low) == len(high) && len(low) > 0 && len(low) <= 4 {
+					m.codespace = append(m.codespace, codeRange{low, high})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok := operands[i].([]byte)
+				if !ok || len(src) == 0 || len(src) > 4 {
+					continue
+				}
+				switch dst := operands[i+1].(type) {
+				case []byte:
+					m.text[codeOf(src)] = utf16Text(dst)
+				case name:
+					if text, ok := glyphText(string(dst)); ok {
+						m.text[codeOf(src)] = text
+					}
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				low, ok1 := operands[i].([]byte)
+				high, ok2 := operands[i+1].([]byte)
+				if !ok1 || !ok2 || len(low) != len(high) || len(low) == 0 || len(low) > 4 {
+					continue
+				}
+				m.addRange(codeOf(low), codeOf(high).value, operands[i+2])
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// addRange maps the codes from first to last. An array destination lists the
+// text of each code, a string destination is the text of the first code and
+// the codes after it increment its last character.
+func (m *cmap) addRange(first code, last uint32, dst object) {
+	if last < first.value || last-first.value >= maxRange {
+		return
+	}
+	switch dst := dst.(type) {
+	case array:
+		for i, item := range dst {
+			if s, ok := item.([]byte); ok && first.value+uint32(i) <= last {
+				m.text[code{first.value + uint32(i), first.length}] = utf16Text(s)
+			}
+		}
+	case []byte:
+		runes := []rune(utf16Text(dst))
+		if len(runes) == 0 {
+			return
+		}
+		end := len(runes) - 1
+		base := runes[end]
+		for i := uint32(0); i <= last-first.value; i++ {
+			runes[end] = base + rune(i)
+			m.text[code{first.value + i, first.length}] = string(runes)
+		}
+	}
+}
+
+// codeLength returns the length of the code at the start of data: the
+// codespace range it falls in, or fallback without one.
+func (m *cmap) codeLength(data []byte, fallback int) int {
+	if m != nil {
+		for n := 1; n <= 4 && n <= len(data); n++ {
+			for _, r := range m.codespace {
+				if len(r.low) == n && inRange(data[:n], r) {
+					return n
+				}
+			}
+		}
+	}
+	return min(fallback, len(data))
+}
+
+func inRange(data []byte, r codeRange) bool {
+	for i, b := range data {
+		if b < r.low[i] || b > r.high[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *cmap) lookup(c code) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	text, ok := m.text[c]
+	return text, ok
+}
+
+func codeOf(data []byte) code {
+	c := code{length: len(data)}
+	for _, b := range data {
+		c.value = c.value<<8 | uint32(b)
+	}
+	return c
+}
+
+// utf16Text decodes the UTF-16BE destination strings of ToUnicode maps. A
+// single byte is taken as a character of its own, as some writers emit them.
+func utf16Text(data []byte) string {
+	if len(data) == 1 {
+		return string(rune(data[0]))
+	}
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,494 @@
+package pdftext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// ErrEncrypted is returned for encrypted PDFs, their content can't be read
+// without decrypting it first.
+var ErrEncrypted = errors.New("pdf is encrypted")
+
+// Decompressed data counts against a budget per document, so small Flate
+// streams that expand to gigabytes are stopped. Files that compress unusually
+// well get less than maxDecodedSize, like the ratio checks on archives.
+const (
+	maxDecodedSize   = 256 * 1024 * 1024 // 256MB
+	maxDecodeRatio   = 100
+	decodedSizeFloor = 16 * 1024 * 1024 // 16MB
+)
+
+var errDecodeBudget = errors.New("pdf decompresses to more data than allowed")
+
+// Limits on the PNG predictor parameters of a stream
+const (
+	maxPredictorColumns = 1 << 16
+	maxPredictorColors  = 32
+)
+
+var (
+	objectPattern  = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	trailerPattern = regexp.MustCompile(`trailer\s*<<`)
+)
+
+// document holds the objects of a PDF. Objects are found by scanning the file
+// for their definitions rather than through the cross-reference table, which
+// is often damaged. Later definitions replace earlier ones, as incremental
+// updates append to the file.
+type document struct {
+	objects  map[int]object
+	trailers []dict
+	fonts    map[ref]*font
+	// budget is how much more data streams may decompress to
+	budget int64
+	// unreadStreams counts the object streams that couldn't be decoded,
+	// their objects are missing
+	unreadStreams int
+}
+
+func load(data []byte) (*document, error) {
+	d, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if d.encrypted() {
+		return nil, ErrEncrypted
+	}
+	return d, nil
+}
+
+// parse reads the objects of a PDF. The object streams of encrypted files
+// can't be decoded and are only counted as unread.
+func parse(data []byte) (*document, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n\x00"), []byte("%PDF-")) {
+		return nil, fmt.Errorf("not a pdf")
+	}
+
+	d := &document{
+		objects: make(map[int]object),
+		fonts:   make(map[ref]*font),
+		budget:  min(maxDecodedSize, max(decodedSizeFloor, int64(len(data))*maxDecodeRatio)),
+	}
+	var objectStreams []stream
+
+	for pos := 0; pos < len(data); {
+		match := objectPattern.FindSubmatchIndex(data[pos:])
+		if match == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+match[2] : pos+match[3]]))
+		l := &lexer{data: data, pos: pos + match[1], refs: true}
+
+		obj, err := l.object()
+		if err != nil {
+			pos += match[1]
+			continue
+		}
+		if dictionary, ok := obj.(dict); ok {
+			if s, ok := d.readStream(l, dictionary); ok {
+				obj = s
+				switch dictionary["Type"] {
+				case name("ObjStm"):
+					objectStreams = append(objectStreams, s)
+				case name("XRef"):
+					d.trailers = append(d.trailers, dictionary)
+				}
+			}
+		}
+		d.objects[num] = obj
+		pos = l.pos
+	}
+
+	for _, match := range trailerPattern.FindAllIndex(data, -1) {
+		l := &lexer{data: data, pos: match[1] - 2, refs: true}
+		if trailer, err := l.object(); err == nil {
+			if t, ok := trailer.(dict); ok {
+				d.trailers = append(d.trailers, t)
+			}
+		}
+	}
+
+	for _, s := range objectStreams {
+		if d.encrypted() || !d.loadObjectStream(s) {
+			d.unreadStreams++
+		}
+	}
+
+	return d, nil
+}
+
+func (d *document) encrypted() bool {
+	for _, t := range d.trailers {
+		if _, ok := t["Encrypt"]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readStream reads the data after a stream dictionary. A Length that doesn't
+// end at endstream is ignored and the data runs to endstream instead.
+func (d *document) readStream(l *lexer, dictionary dict) (stream, bool) {
+	pos := l.pos
+	for pos < len(l.data) && isSpace(l.data[pos]) {
+		pos++
+	}
+	if !bytes.HasPrefix(l.data[pos:], []byte("stream")) {
+		return stream{}, false
+	}
+	pos += len("stream")
+	if bytes.HasPrefix(l.data[pos:], []byte("\r\n")) {
+		pos += 2
+	} else if pos < len(l.data) && (l.data[pos] == '\n' || l.data[pos] == '\r') {
+		pos++
+	}
+
+	if length, ok := dictionary["Length"].(int); ok && length >= 0 && pos+length <= len(l.data) {
+		rest := bytes.TrimLeft(l.data[pos+length:], " \r\n")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			l.pos = pos + length
+			return stream{dict: dictionary, data: l.data[pos : pos+length]}, true
+		}
+	}
+
+	end := bytes.Index(l.data[pos:], []byte("endstream"))
+	if end < 0 {
+		l.pos = len(l.data)
+		return stream{dict: dictionary, data: l.data[pos:]}, true
+	}
+	l.pos = pos + end + len("endstream")
+	data := bytes.TrimSuffix(l.data[pos:pos+end], []byte("\n"))
+	data = bytes.TrimSuffix(data, []byte("\r"))
+	return stream{dict: dictionary, data: data}, true
+}
+
+// loadObjectStream adds the objects compressed into an object stream and
+// reports whether the stream could be read. Objects defined directly in the
+// file take precedence.
+func (d *document) loadObjectStream(s stream) bool {
+	data, err := d.decode(s)
+	if err != nil {
+		return false
+	}
+	count, _ := d.resolve(s.dict["N"]).(int)
+	first, _ := d.resolve(s.dict["First"]).(int)
+	if first <= 0 || first > len(data) {
+		return false
+	}
+
+	header := &lexer{data: data[:first]}
+	for i := 0; i < count; i++ {
+		num, err1 := header.object()
+		offset, err2 := header.object()
+		n, ok1 := num.(int)
+		o, ok2 := offset.(int)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			return false
+		}
+		if _, defined := d.objects[n]; defined || first+o >= len(data) {
+			continue
+		}
+		l := &lexer{data: data, pos: first + o, refs: true}
+		if obj, err := l.object(); err == nil {
+			d.objects[n] = obj
+		}
+	}
+	return true
+}
+
+// resolve follows references, a missing object is null.
+func (d *document) resolve(obj object) object {
+	for i := 0; i < 32; i++ {
+		r, ok := obj.(ref)
+		if !ok {
+			return obj
+		}
+		obj = d.objects[r.num]
+	}
+	return nil
+}
+
+func (d *document) dict(obj object) dict {
+	switch v := d.resolve(obj).(type) {
+	case dict:
+		return v
+	case stream:
+		return v.dict
+	}
+	return nil
+}
+
+// decode applies the filters of a stream. Image filters can't be decoded and
+// return an error.
+func (d *document) decode(s stream) ([]byte, error) {
+	var filters []object
+	switch f := d.resolve(s.dict["Filter"]).(type) {
+	case name:
+		filters = []object{f}
+	case array:
+		filters = f
+	}
+	var params []object
+	switch p := d.resolve(s.dict["DecodeParms"]).(type) {
+	case dict:
+		params = []object{p}
+	case array:
+		params = p
+	}
+
+	data := s.data
+	for i, filter := range filters {
+		var param dict
+		if i < len(params) {
+			param = d.dict(params[i])
+		}
+
+		var err error
+		switch f, _ := d.resolve(filter).(name); f {
+		case "FlateDecode", "Fl":
+			data, err = d.inflate(data)
+			if err == nil {
+				data, err = d.unpredict(data, param)
+			}
+		case "ASCIIHexDecode", "AHx":
+			data, err = decodeHex(data)
+		case "ASCII85Decode", "A85":
+			data, err = decode85(data)
+		default:
+			return nil, fmt.Errorf("unsupported filter %s", f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// inflate keeps what could be decompressed from truncated streams.
+func (d *document) inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	out, err := io.ReadAll(io.LimitReader(r, d.budget+1))
+	if int64(len(out)) > d.budget {
+		d.budget = 0
+		return nil, errDecodeBudget
+	}
+	d.budget -= int64(len(out))
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+// unpredict reverses the PNG predictors of Flate streams.
+func (d *document) unpredict(data []byte, param dict) ([]byte, error) {
+	predictor, _ := d.resolve(param["Predictor"]).(int)
+	if predictor < 10 {
+		return data, nil
+	}
+	columns, ok := d.resolve(param["Columns"]).(int)
+	if !ok || columns <= 0 {
+		columns = 1
+	}
+	colors, ok := d.resolve(param["Colors"]).(int)
+	if !ok || colors <= 0 {
+		colors = 1
+	}
+	bits, ok := d.resolve(param["BitsPerComponent"]).(int)
+	if !ok || bits <= 0 {
+		bits = 8
+	}
+	// The parameters come from the file, unchecked they can overflow the row
+	// size or ask for gigabytes per row
+	if columns > maxPredictorColumns || colors > maxPredictorColors ||
+		(bits != 1 && bits != 2 && bits != 4 && bits != 8 && bits != 16) {
+		return nil, fmt.Errorf("invalid predictor parameters: %d columns, %d colors, %d bits", columns, colors, bits)
+	}
+
+	bpp := max((colors*bits+7)/8, 1)
+	rowSize := (columns*colors*bits + 7) / 8
+	if rowSize <= 0 || rowSize > len(data) {
+		return nil, fmt.Errorf("predictor row of %d bytes doesn't fit %d bytes of data", rowSize, len(data))
+	}
+	out := make([]byte, 0, len(data))
+	previous := make([]byte, rowSize)
+	for start := 0; start+rowSize+1 <= len(data); start += rowSize + 1 {
+		kind, row := data[start], append([]byte(nil), data[start+1:start+1+rowSize]...)
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], previous[i-bpp]
+			}
+			up := previous[i]
+			switch kind {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		previous = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func decodeHex(data []byte) ([]byte, error) {
+	var digits []byte
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		if !isSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	_, err := hex.Decode(out, digits)
+	return out, err
+}
+
+func decode85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if end := bytes.Index(data, []byte("~>")); end >= 0 {
+		data = data[:end]
+	}
+	out := make([]byte, len(data))
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
+
+// page is a leaf of the page tree with the resources it inherits.
+type page struct {
+	dict      dict
+	resources dict
+}
+
+// pages returns the pages in reading order. Without a usable page tree the
+// page objects are taken in the order they were numbered.
+func (d *document) pages() []page {
+	var root dict
+	for i := len(d.trailers) - 1; i >= 0 && root == nil; i-- {
+		root = d.dict(d.trailers[i]["Root"])
+	}
+	if root == nil {
+		for _, obj := range d.objects {
+			if c, ok := obj.(dict); ok && c["Type"] == name("Catalog") {
+				root = c
+				break
+			}
+		}
+	}
+
+	var result []page
+	if root != nil {
+		d.walkPages(root["Pages"], nil, make(map[int]bool), &result)
+	}
+	if len(result) > 0 {
+		return result
+	}
+
+	var numbers []int
+	for num, obj := range d.objects {
+		if p, ok := obj.(dict); ok && p["Type"] == name("Page") {
+			numbers = append(numbers, num)
+		}
+	}
+	sort.Ints(numbers)
+	for _, num := range numbers {
+		p := d.objects[num].(dict)
+		result = append(result, page{dict: p, resources: d.dict(p["Resources"])})
+	}
+	return result
+}
+
+func (d *document) walkPages(node object, resources dict, seen map[int]bool, result *[]page) {
+	if r, ok := node.(ref); ok {
+		if seen[r.num] {
+			return
+		}
+		seen[r.num] = true
+	}
+	n := d.dict(node)
+	if n == nil {
+		return
+	}
+	if own := d.dict(n["Resources"]); own != nil {
+		resources = own
+	}
+
+	kids, ok := d.resolve(n["Kids"]).(array)
+	if !ok {
+		if n["Type"] != name("Pages") {
+			*result = append(*result, page{dict: n, resources: resources})
+		}
+		return
+	}
+	for _, kid := range kids {
+		d.walkPages(kid, resources, seen, result)
+	}
+}
+
+// contents returns the decoded content streams of a page joined together.
+func (d *document) contents(p page) []byte {
+	var streams []object
+	switch c := d.resolve(p.dict["Contents"]).(type) {
+	case stream:
+		streams = []object{c}
+	case array:
+		streams = c
+	}
+
+	var out []byte
+	for _, obj := range streams {
+		s, ok := d.resolve(obj).(stream)
+		if !ok {
+			continue
+		}
+		data, err := d.decode(s)
+		if err != nil {
+			continue
+		}
+		out = append(out, data...)
+		out = append(out, '\n')
+	}
+	return out
+}
@@ -0,0 +1,195 @@
+package pdftext
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+)
+
+// encoding maps the byte codes of a simple font to text. Codes without a
+// glyph are empty.
+type encoding [256]string
+
+var (
+	winAnsiEncoding  = charmapEncoding(charmap.Windows1252)
+	macRomanEncoding = charmapEncoding(charmap.Macintosh)
+	standardEncoding = adobeStandardEncoding()
+)
+
+func charmapEncoding(m *charmap.Charmap) *encoding {
+	e := &encoding{}
+	for b := 0x20; b < 256; b++ {
+		if r := m.DecodeByte(byte(b)); r != utf8.RuneError && unicode.IsGraphic(r) {
+			e[b] = string(r)
+		}
+	}
+	return e
+}
+
+// adobeStandardEncoding is ASCII with curly quotes, and a set of accents,
+// ligatures and punctuation above 0x7F.
+func adobeStandardEncoding() *encoding {
+	e := &encoding{}
+	for b := 0x20; b < 0x7F; b++ {
+		e[b] = string(rune(b))
+	}
+	e['\''], e['`'] = "’", "‘"
+
+	high := map[byte]string{
+		0xA1: "¡", 0xA2: "¢", 0xA3: "£", 0xA4: "⁄", 0xA5: "¥", 0xA6: "ƒ", 0xA7: "§",
+		0xA8: "¤", 0xA9: "'", 0xAA: "“", 0xAB: "«", 0xAC: "‹", 0xAD: "›", 0xAE: "fi",
+		0xAF: "fl", 0xB1: "–", 0xB2: "†", 0xB3: "‡", 0xB4: "·", 0xB6: "¶", 0xB7: "•",
+		0xB8: "‚", 0xB9: "„", 0xBA: "”", 0xBB: "»", 0xBC: "…", 0xBD: "‰", 0xBF: "¿",
+		0xC1: "`", 0xC2: "´", 0xC3: "ˆ", 0xC4: "˜", 0xC5: "¯", 0xC6: "˘", 0xC7: "˙",
+		0xC8: "¨", 0xCA: "˚", 0xCB: "¸", 0xCD: "˝", 0xCE: "˛", 0xCF: "ˇ", 0xD0: "—",
+		0xE1: "Æ", 0xE3: "ª", 0xE8: "Ł", 0xE9: "Ø", 0xEA: "Œ", 0xEB: "º", 0xF1: "æ",
+		0xF5: "ı", 0xF8: "ł", 0xF9: "ø", 0xFA: "œ", 0xFB: "ß",
+	}
+	for b, text := range high {
+		e[b] = text
+	}
+	return e
+}
+
+// namedEncoding returns the encoding a font's Encoding or BaseEncoding names.
+func namedEncoding(n name) *encoding {
+	switch n {
+	case "WinAnsiEncoding":
+		return winAnsiEncoding
+	case "MacRomanEncoding", "MacExpertEncoding":
+		return macRomanEncoding
+	case "StandardEncoding":
+		return standardEncoding
+	}
+	return nil
+}
+
+// glyphs holds the glyph names of the Adobe Glyph List that the rules in
+// glyphText can't derive. Ligatures are spelled out so they can be searched.
+var glyphs = map[string]string{
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#", "dollar": "$",
+	"percent": "%", "ampersand": "&", "quotesingle": "'", "parenleft": "(",
+	"parenright": ")", "asterisk": "*", "plus": "+", "comma": ",", "hyphen": "-",
+	"period": ".", "slash": "/", "zero": "0", "one": "1", "two": "2", "three": "3",
+	"four": "4", "five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+	"colon": ":", "semicolon": ";", "less": "<", "equal": "=", "greater": ">",
+	"question": "?", "at": "@", "bracketleft": "[", "backslash": "\\",
+	"bracketright": "]", "asciicircum": "^", "underscore": "_", "grave": "`",
+	"braceleft": "{", "bar": "|", "braceright": "}", "asciitilde": "~",
+
+	"quoteleft": "‘", "quoteright": "’", "quotedblleft": "“", "quotedblright": "”",
+	"quotesinglbase": "‚", "quotedblbase": "„", "guillemotleft": "«",
+	"guillemotright": "»", "guilsinglleft": "‹", "guilsinglright": "›",
+	"endash": "–", "emdash": "—", "ellipsis": "…", "bullet": "•", "dagger": "†",
+	"daggerdbl": "‡", "periodcentered": "·", "middot": "·", "exclamdown": "¡",
+	"questiondown": "¿", "section": "§", "paragraph": "¶", "perthousand": "‰",
+	"sfthyphen": "-", "softhyphen": "-", "nbspace": " ", "nonbreakingspace": " ",
+	"visiblespace": "␣",
+
+	"cent": "¢", "sterling": "£", "currency": "¤", "yen": "¥", "florin": "ƒ",
+	"Euro": "€", "euro": "€", "brokenbar": "¦", "copyright": "©", "registered": "®",
+	"trademark": "™", "ordfeminine": "ª", "ordmasculine": "º", "logicalnot": "¬",
+	"degree": "°", "plusminus": "±", "multiply": "×", "divide": "÷", "minus": "−",
+	"fraction": "⁄", "onequarter": "¼", "onehalf": "½", "threequarters": "¾",
+	"onesuperior": "¹", "twosuperior": "²", "threesuperior": "³", "mu": "µ",
+	"notequal": "≠", "lessequal": "≤", "greaterequal": "≥", "approxequal": "≈",
+	"infinity": "∞", "partialdiff": "∂", "summation": "∑", "product": "∏",
+	"integral": "∫", "radical": "√", "lozenge": "◊", "arrowright": "→",
+	"arrowleft": "←", "arrowup": "↑", "arrowdown": "↓", "arrowboth": "↔",
+	"element": "∈", "emptyset": "∅", "universal": "∀", "existential": "∃",
+	"logicaland": "∧", "logicalor": "∨", "intersection": "∩", "union": "∪",
+
+	"acute": "´", "circumflex": "ˆ", "tilde": "˜", "macron": "¯", "breve": "˘",
+	"dotaccent": "˙", "dieresis": "¨", "ring": "˚", "cedilla": "¸",
+	"hungarumlaut": "˝", "ogonek": "˛", "caron": "ˇ",
+
+	"AE": "Æ", "ae": "æ", "OE": "Œ", "oe": "œ", "Oslash": "Ø", "oslash": "ø",
+	"Lslash": "Ł", "lslash": "ł", "Eth": "Ð", "eth": "ð", "Thorn": "Þ",
+	"thorn": "þ", "germandbls": "ß", "dotlessi": "ı", "dotlessj": "ȷ",
+	"kgreenlandic": "ĸ", "Dcroat": "Đ", "dcroat": "đ",
+
+	"ff": "ff", "fi": "fi", "fl": "fl", "ffi": "ffi", "ffl": "ffl",
+
+	"Alpha": "Α", "Beta": "Β", "Gamma": "Γ", "Delta": "Δ", "Epsilon": "Ε",
+	"Zeta": "Ζ", "Eta": "Η", "Theta": "Θ", "Iota": "Ι", "Kappa": "Κ",
+	"Lambda": "Λ", "Mu": "Μ", "Nu": "Ν", "Xi": "Ξ", "Omicron": "Ο", "Pi": "Π",
+	"Rho": "Ρ", "Sigma": "Σ", "Tau": "Τ", "Upsilon": "Υ", "Phi": "Φ", "Chi": "Χ",
+	"Psi": "Ψ", "Omega": "Ω", "alpha": "α", "beta": "β", "gamma": "γ",
+	"delta": "δ", "epsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ",
+	"iota": "ι", "kappa": "κ", "lambda": "λ", "nu": "ν", "xi": "ξ",
+	"omicron": "ο", "pi": "π", "rho": "ρ", "sigma": "σ", "sigma1": "ς",
+	"tau": "τ", "upsilon": "υ", "phi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+}
+
+// accents are the diacritic suffixes of glyph names such as "Aacute" or
+// "gbreve", they compose with the letter before them.
+var accents = map[string]rune{
+	"acute": '\u0301', "grave": '\u0300', "circumflex": '\u0302', "tilde": '\u0303',
+	"macron": '\u0304', "breve": '\u0306', "dotaccent": '\u0307', "dieresis": '\u0308',
+	"ring": '\u030A', "hungarumlaut": '\u030B', "caron": '\u030C',
+	"commaaccent": '\u0326', "cedilla": '\u0327', "ogonek": '\u0328',
+}
+
+// glyphText returns the text of a glyph name. Names not in the glyph list
+// are read by the Adobe conventions: uniXXXX and uXXXX[XX] code points,
+// underscores between the parts of a ligature and suffixes after a period,
+// as well as letters followed by an accent.
+func glyphText(n string) (string, bool) {
+	if text, ok := glyphs[n]; ok {
+		return text, true
+	}
+	if i := strings.IndexByte(n, '.'); i > 0 {
+		return glyphText(n[:i])
+	}
+	if strings.Contains(n, "_") {
+		var b strings.Builder
+		for _, part := range strings.Split(n, "_") {
+			text, ok := glyphText(part)
+			if !ok {
+				return "", false
+			}
+			b.WriteString(text)
+		}
+		return b.String(), true
+	}
+
+	if len(n) == 1 && (n[0] >= 'A' && n[0] <= 'Z' || n[0] >= 'a' && n[0] <= 'z') {
+		return n, true
+	}
+	if rest, ok := strings.CutPrefix(n, "uni"); ok && len(rest) > 0 && len(rest)%4 == 0 {
+		var b strings.Builder
+		for i := 0; i < len(rest); i += 4 {
+			r, err := strconv.ParseUint(rest[i:i+4], 16, 32)
+			if err != nil {
+				return "", false
+			}
+			b.WriteRune(rune(r))
+		}
+		return b.String(), true
+	}
+	if rest, ok := strings.CutPrefix(n, "u"); ok && len(rest) >= 4 && len(rest) <= 6 {
+		if r, err := strconv.ParseUint(rest, 16, 32); err == nil && utf8.ValidRune(rune(r)) {
+			return string(rune(r)), true
+		}
+	}
+
+	if mark, ok := accents[n[1:]]; ok {
+		if base, ok := glyphText(n[:1]); ok {
+			composed := norm.NFC.String(base + string(mark))
+			if utf8.RuneCountInString(composed) == 1 {
+				return composed, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ligatures spells out the presentation forms ToUnicode maps often use.
+var ligatures = strings.NewReplacer(
+	"ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi",
+	"ﬄ", "ffl", "ﬅ", "st", "ﬆ", "st",
+)
@@ -0,0 +1,272 @@
+package pdftext
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultFont reads strings shown without a font.
+var defaultFont = &font{encoding: winAnsiEncoding}
+
+// builtinPattern finds the entries of the encoding vector in the clear text
+// part of embedded Type 1 fonts.
+var builtinPattern = regexp.MustCompile(`dup\s+(\d+)\s*/([^\s/\[\]{}()<>]+)\s+put`)
+
+// font turns the codes of shown strings into text. Simple fonts have one
+// byte codes read through their encoding, composite (Type0) fonts have codes
+// of one to four bytes that only their ToUnicode map can read, unless their
+// CMap is a Unicode one. ToUnicode takes precedence for both.
+type font struct {
+	encoding  *encoding
+	toUnicode *cmap
+
+	composite bool
+	// codes holds the codespace of a composite font's CMap
+	codes *cmap
+	// utf16 is set for composite fonts whose codes are UTF-16
+	utf16 bool
+
+	// widths are in thousandths of the font size, by code for simple fonts
+	// and by CID for composite ones. Fonts without widths don't place text
+	// precisely enough to find the gaps between words.
+	widths       map[uint32]float64
+	defaultWidth float64
+	hasWidths    bool
+}
+
+// font returns the font of a font resource, loaded once per document.
+func (d *document) font(obj object) *font {
+	r, isRef := obj.(ref)
+	if isRef {
+		if f, ok := d.fonts[r]; ok {
+			return f
+		}
+	}
+
+	f := d.loadFont(d.dict(obj))
+	if isRef {
+		d.fonts[r] = f
+	}
+	return f
+}
+
+func (d *document) loadFont(fontDict dict) *font {
+	if fontDict == nil {
+		return nil
+	}
+	f := &font{}
+	if s, ok := d.resolve(fontDict["ToUnicode"]).(stream); ok {
+		if data, err := d.decode(s); err == nil {
+			f.toUnicode = parseCMap(data)
+		}
+	}
+
+	if fontDict["Subtype"] == name("Type0") {
+		f.composite = true
+		switch enc := d.resolve(fontDict["Encoding"]).(type) {
+		case name:
+			n := string(enc)
+			f.utf16 = f.toUnicode == nil && (strings.Contains(n, "-UCS2-") || strings.Contains(n, "-UTF16-"))
+		case stream:
+			if data, err := d.decode(enc); err == nil {
+				f.codes = parseCMap(data)
+			}
+		}
+		if f.codes == nil || len(f.codes.codespace) == 0 {
+			f.codes = f.toUnicode
+		}
+		if descendants, ok := d.resolve(fontDict["DescendantFonts"]).(array); ok && len(descendants) > 0 {
+			d.cidWidths(f, d.dict(descendants[0]))
+		}
+		return f
+	}
+
+	f.encoding = d.simpleEncoding(fontDict)
+	d.simpleWidths(f, fontDict)
+	return f
+}
+
+// simpleWidths reads the Widths of a simple font. Type 3 glyphs are measured
+// in their own units, which FontMatrix scales.
+func (d *document) simpleWidths(f *font, fontDict dict) {
+	widths, ok := d.resolve(fontDict["Widths"]).(array)
+	if !ok {
+		return
+	}
+	factor := 1.0
+	if fontDict["Subtype"] == name("Type3") {
+		matrix, ok := d.resolve(fontDict["FontMatrix"]).(array)
+		if !ok || len(matrix) == 0 {
+			return
+		}
+		factor = number(d.resolve(matrix[0])) * 1000
+	}
+
+	first, _ := d.resolve(fontDict["FirstChar"]).(int)
+	f.widths = make(map[uint32]float64, len(widths))
+	for i, width := range widths {
+		if first+i >= 0 {
+			f.widths[uint32(first+i)] = number(d.resolve(width)) * factor
+		}
+	}
+	f.defaultWidth = number(d.resolve(d.dict(fontDict["FontDescriptor"])["MissingWidth"])) * factor
+	f.hasWidths = true
+}
+
+// cidWidths reads the W array of a CIDFont, which lists the widths of
+// consecutive CIDs ("c [w1 w2 ...]") or gives a range one width
+// ("first last w"). Codes are taken as CIDs, as Identity CMaps map them.
+func (d *document) cidWidths(f *font, cidFont dict) {
+	f.widths = make(map[uint32]float64)
+	f.defaultWidth = 1000
+	if dw, ok := d.resolve(cidFont["DW"]).(int); ok {
+		f.defaultWidth = float64(dw)
+	}
+	f.hasWidths = true
+
+	w, _ := d.resolve(cidFont["W"]).(array)
+	for i := 0; i < len(w); {
+		first, ok := d.resolve(w[i]).(int)
+		if !ok || i+1 >= len(w) || first < 0 {
+			return
+		}
+		if list, ok := d.resolve(w[i+1]).(array); ok {
+			for j, width := range list {
+				f.widths[uint32(first+j)] = number(d.resolve(width))
+			}
+			i += 2
+			continue
+		}
+		last, ok := d.resolve(w[i+1]).(int)
+		if !ok || i+2 >= len(w) || last < first || last-first >= maxRange {
+			return
+		}
+		width := number(d.resolve(w[i+2]))
+		for cid := first; cid <= last; cid++ {
+			f.widths[uint32(cid)] = width
+		}
+		i += 3
+	}
+}
+
+func (f *font) width(c uint32) float64 {
+	if width, ok := f.widths[c]; ok {
+		return width
+	}
+	return f.defaultWidth
+}
+
+// simpleEncoding builds the encoding of a simple font from its base encoding
+// and Differences. Fonts that don't name a base encoding use the one built
+// into an embedded Type 1 font program, or StandardEncoding.
+func (d *document) simpleEncoding(fontDict dict) *encoding {
+	var base *encoding
+	var differences array
+	switch enc := d.resolve(fontDict["Encoding"]).(type) {
+	case name:
+		base = namedEncoding(enc)
+	case dict:
+		if n, ok := d.resolve(enc["BaseEncoding"]).(name); ok {
+			base = namedEncoding(n)
+		}
+		differences, _ = d.resolve(enc["Differences"]).(array)
+	}
+	if base == nil {
+		base = d.builtinEncoding(d.dict(fontDict["FontDescriptor"]))
+	}
+	if base == nil {
+		base = standardEncoding
+	}
+	if len(differences) == 0 {
+		return base
+	}
+
+	e := *base
+	next := -1
+	for _, item := range differences {
+		switch v := d.resolve(item).(type) {
+		case int:
+			next = v
+		case name:
+			if next >= 0 && next < len(e) {
+				e[next], _ = glyphText(string(v))
+				next++
+			}
+		}
+	}
+	return &e
+}
+
+// builtinEncoding reads the encoding vector of an embedded Type 1 font,
+// which TeX fonts rely on instead of an Encoding entry.
+func (d *document) builtinEncoding(descriptor dict) *encoding {
+	s, ok := d.resolve(descriptor["FontFile"]).(stream)
+	if !ok {
+		return nil
+	}
+	data, err := d.decode(s)
+	if err != nil {
+		return nil
+	}
+	if end := bytes.Index(data, []byte("eexec")); end >= 0 {
+		data = data[:end]
+	}
+	if bytes.Contains(data, []byte("/Encoding StandardEncoding def")) {
+		return standardEncoding
+	}
+
+	matches := builtinPattern.FindAllSubmatch(data, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	e := &encoding{}
+	for _, m := range matches {
+		if b, err := strconv.Atoi(string(m[1])); err == nil && b >= 0 && b < len(e) {
+			e[b], _ = glyphText(string(m[2]))
+		}
+	}
+	return e
+}
+
+// show returns the text of a shown string and how far it moves the text
+// position, which is only known for fonts with widths. Without a font the
+// bytes are read as WinAnsiEncoding, which most simple fonts use.
+func (f *font) show(obj object, ts *textState) (string, float64, bool) {
+	data, ok := obj.([]byte)
+	if !ok {
+		return "", 0, false
+	}
+	if f == nil {
+		f = defaultFont
+	}
+
+	var b strings.Builder
+	advance := 0.0
+	for pos := 0; pos < len(data); {
+		n := 1
+		if f.composite {
+			n = f.codes.codeLength(data[pos:], 2)
+		}
+		c := codeOf(data[pos : pos+n])
+		pos += n
+
+		advance += f.width(c.value)/1000*ts.size + ts.charSpace
+		if n == 1 && c.value == ' ' {
+			advance += ts.wordSpace
+		}
+
+		if text, ok := f.toUnicode.lookup(c); ok {
+			b.WriteString(text)
+		} else if !f.composite {
+			b.WriteString(f.encoding[c.value])
+		}
+	}
+
+	text := b.String()
+	if f.utf16 {
+		text = utf16Text(data)
+	}
+	return ligatures.Replace(text), advance * ts.scale, f.hasWidths
+}
@@ -0,0 +1,106 @@
+package pdftext
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+)
+
+var (
+	namePattern   = regexp.MustCompile(`/[^\s/<>\[\]()%{}]+`)
+	hexEscape     = regexp.MustCompile(`#[0-9A-Fa-f]{2}`)
+	streamPattern = regexp.MustCompile(`>>\s*stream(?:\r\n|\r|\n)`)
+)
+
+// Info is what a PDF declares about itself, read without extracting text.
+type Info struct {
+	Encrypted bool
+	Pages     int
+	// UnreadStreams counts the object streams that couldn't be decoded,
+	// whatever they define is missing from the names and pages
+	UnreadStreams int
+
+	names map[string]bool
+}
+
+// HasName reports whether the document uses a name, given without its
+// leading slash, as a key or value of any object.
+func (i *Info) HasName(n string) bool {
+	return i.names[n]
+}
+
+// Inspect reads the objects of a PDF, those compressed into object streams
+// included, and collects the names they use and the pages they define.
+// Objects too damaged to parse are still searched for names in the raw file.
+func Inspect(content []byte) (*Info, error) {
+	d, err := parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{
+		Encrypted:     d.encrypted(),
+		Pages:         len(d.pages()),
+		UnreadStreams: d.unreadStreams,
+		names:         rawNames(content),
+	}
+	for _, obj := range d.objects {
+		info.collect(obj, 0)
+	}
+	for _, t := range d.trailers {
+		info.collect(t, 0)
+	}
+	return info, nil
+}
+
+// collect adds the names of an object and the objects inside it.
+func (i *Info) collect(obj object, depth int) {
+	if depth > maxNesting {
+		return
+	}
+	switch v := obj.(type) {
+	case name:
+		i.names[string(v)] = true
+	case dict:
+		for key, value := range v {
+			i.names[string(key)] = true
+			i.collect(value, depth+1)
+		}
+	case array:
+		for _, value := range v {
+			i.collect(value, depth+1)
+		}
+	case stream:
+		i.collect(v.dict, depth+1)
+	}
+}
+
+// rawNames returns the name tokens found in the file outside stream data,
+// compressed bytes can look like any name. Names may hide characters as #xx
+// escapes, so they are decoded.
+func rawNames(content []byte) map[string]bool {
+	names := make(map[string]bool)
+	for len(content) > 0 {
+		objects := content
+		content = nil
+		if start := streamPattern.FindIndex(objects); start != nil {
+			rest := objects[start[1]:]
+			objects = objects[:start[0]]
+			if end := bytes.Index(rest, []byte("endstream")); end >= 0 {
+				content = rest[end+len("endstream"):]
+			}
+		}
+
+		for _, match := range namePattern.FindAll(objects, -1) {
+			match = match[1:]
+			if bytes.IndexByte(match, '#') >= 0 {
+				match = hexEscape.ReplaceAllFunc(match, func(escape []byte) []byte {
+					b, _ := strconv.ParseUint(string(escape[1:]), 16, 8)
+					return []byte{byte(b)}
+				})
+			}
+			names[string(match)] = true
+		}
+	}
+	return names
+}
@@ -0,0 +1,74 @@
+package pdftext
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// objectStream compresses objects numbered from first into an object stream.
+func objectStream(first int, objects ...string) string {
+	var header, body strings.Builder
+	for i, obj := range objects {
+		fmt.Fprintf(&header, "%d %d ", first+i, body.Len())
+		body.WriteString(obj + "\n")
+	}
+	data := deflate([]byte(header.String() + body.String()))
+	return fmt.Sprintf("<< /Type /ObjStm /N %d /First %d /Filter /FlateDecode /Length %d >>\x00%s",
+		len(objects), header.Len(), len(data), data)
+}
+
+func TestInspect(t *testing.T) {
+	// Everything but the catalog is hidden in an object stream
+	pdf := buildPDF(
+		"<< /Type /Catalog /Pages 3 0 R /OpenAction 6 0 R >>",
+		objectStream(3,
+			"<< /Type /Pages /Kids [4 0 R 5 0 R] /Count 2 >>",
+			"<< /Type /Page /Parent 3 0 R >>",
+			"<< /Type /Page /Parent 3 0 R >>",
+			"<< /S /Java#53cript /JS (app.alert(1)) >>",
+		),
+	)
+
+	info, err := Inspect(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Pages != 2 || info.UnreadStreams != 0 || info.Encrypted {
+		t.Errorf("got %+v", info)
+	}
+	for _, n := range []string{"JavaScript", "JS", "OpenAction"} {
+		if !info.HasName(n) {
+			t.Errorf("name %s not found", n)
+		}
+	}
+	if info.HasName("Launch") {
+		t.Error("found a name the document doesn't use")
+	}
+}
+
+func TestInspectUnreadStreams(t *testing.T) {
+	pdf := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /ObjStm /N 1 /First 4 /Filter /FlateDecode /Length 8 >>\x00not zlib",
+	)
+	info, err := Inspect(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.UnreadStreams != 1 {
+		t.Errorf("got %d unread object streams, want 1", info.UnreadStreams)
+	}
+
+	encrypted := strings.Replace(string(buildPDF(
+		"<< /Type /Catalog >>",
+		objectStream(2, "<< /S /Launch >>"),
+	)), "<< /Root 1 0 R >>", "<< /Root 1 0 R /Encrypt 9 0 R >>", 1)
+	info, err = Inspect([]byte(encrypted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Encrypted || info.UnreadStreams != 1 || info.HasName("Launch") {
+		t.Errorf("got %+v for an encrypted document", info)
+	}
+}
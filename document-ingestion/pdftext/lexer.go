@@ -0,0 +1,358 @@
+package pdftext
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// PDF objects. Strings are kept as bytes, their meaning depends on the font
+// or context they are used in.
+type (
+	name    string
+	dict    map[name]object
+	array   []object
+	keyword string
+	object  interface{}
+)
+
+type ref struct {
+	num, gen int
+}
+
+type stream struct {
+	dict dict
+	data []byte
+}
+
+var errDelimiter = errors.New("unexpected delimiter")
+
+// maxNesting bounds how deeply arrays and dictionaries nest, objects are read
+// recursively and real files stay far below it.
+const maxNesting = 100
+
+var errNesting = errors.New("objects nested too deeply")
+
+// lexer reads objects from file or content stream syntax. References (1 0 R)
+// are only recognized in files, content streams don't have them.
+type lexer struct {
+	data []byte
+	pos  int
+	refs bool
+	// depth is how many arrays and dictionaries are open
+	depth int
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// object reads the next object, or a keyword such as an operator.
+func (l *lexer) object() (object, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+
+	switch c := l.data[l.pos]; {
+	case c == '/':
+		return l.name(), nil
+	case c == '(':
+		return l.literal(), nil
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			return l.dict()
+		}
+		return l.hexString(), nil
+	case c == '[':
+		return l.array()
+	case c == ']' || c == '>' || c == ')':
+		l.pos++
+		if c == '>' && l.pos < len(l.data) && l.data[l.pos] == '>' {
+			l.pos++
+		}
+		return nil, errDelimiter
+	case c == '{' || c == '}':
+		l.pos++
+		return keyword(c), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return l.number()
+	default:
+		return l.keyword(), nil
+	}
+}
+
+func (l *lexer) name() name {
+	l.pos++
+	start := l.pos
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	raw := l.data[start:l.pos]
+	if bytes.IndexByte(raw, '#') < 0 {
+		return name(raw)
+	}
+
+	decoded := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if b, err := strconv.ParseUint(string(raw[i+1:i+3]), 16, 8); err == nil {
+				decoded = append(decoded, byte(b))
+				i += 2
+				continue
+			}
+		}
+		decoded = append(decoded, raw[i])
+	}
+	return name(decoded)
+}
+
+func (l *lexer) literal() []byte {
+	l.pos++
+	var out []byte
+	depth := 0
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return out
+			}
+			depth--
+		case '\r':
+			// Line ends in strings read as \n
+			if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+				l.pos++
+			}
+			c = '\n'
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					n := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(n)
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (l *lexer) hexString() []byte {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	n, _ := hex.Decode(out, digits)
+	return out[:n]
+}
+
+// nest opens an array or dictionary, the returned function closes it.
+func (l *lexer) nest() (func(), error) {
+	if l.depth >= maxNesting {
+		return nil, errNesting
+	}
+	l.depth++
+	return func() { l.depth-- }, nil
+}
+
+func (l *lexer) dict() (object, error) {
+	done, err := l.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	l.pos += 2
+	d := make(dict)
+	for {
+		key, err := l.object()
+		if err == errDelimiter {
+			return d, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		k, ok := key.(name)
+		if !ok {
+			return nil, fmt.Errorf("dictionary key %v is not a name", key)
+		}
+		value, err := l.object()
+		if err == errDelimiter {
+			// A key without a value, the dictionary ends here
+			return d, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		d[k] = value
+	}
+}
+
+func (l *lexer) array() (object, error) {
+	done, err := l.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	l.pos++
+	var a array
+	for {
+		item, err := l.object()
+		if err == errDelimiter {
+			return a, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, item)
+	}
+}
+
+func (l *lexer) number() (object, error) {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.data) && ((l.data[l.pos] >= '0' && l.data[l.pos] <= '9') || l.data[l.pos] == '.') {
+		l.pos++
+	}
+	text := string(l.data[start:l.pos])
+
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			// Malformed numbers such as "--5" count as zero, like readers do
+			return 0.0, nil
+		}
+		return f, nil
+	}
+
+	if l.refs && n >= 0 {
+		if r, ok := l.reference(int(n)); ok {
+			return r, nil
+		}
+	}
+	return int(n), nil
+}
+
+// reference reads the "gen R" that follows an object number, leaving the
+// position alone when it isn't there.
+func (l *lexer) reference(num int) (ref, bool) {
+	saved := l.pos
+	l.skipSpace()
+	start := l.pos
+	for l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '9' {
+		l.pos++
+	}
+	if l.pos > start {
+		gen, _ := strconv.Atoi(string(l.data[start:l.pos]))
+		l.skipSpace()
+		if l.pos < len(l.data) && l.data[l.pos] == 'R' && (l.pos+1 == len(l.data) || isSpace(l.data[l.pos+1]) || isDelimiter(l.data[l.pos+1])) {
+			l.pos++
+			return ref{num, gen}, true
+		}
+	}
+	l.pos = saved
+	return ref{}, false
+}
+
+func (l *lexer) keyword() object {
+	start := l.pos
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		// A stray delimiter
+		l.pos++
+	}
+
+	switch word := string(l.data[start:l.pos]); word {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	default:
+		return keyword(word)
+	}
+}
+
+// skipInlineImage moves past the data of an inline image, which starts after
+// the ID operator and ends at a standalone EI.
+func (l *lexer) skipInlineImage() {
+	l.pos++
+	for l.pos+2 <= len(l.data) {
+		i := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if i < 0 {
+			break
+		}
+		at := l.pos + i
+		l.pos = at + 2
+		if at > 0 && isSpace(l.data[at-1]) && (l.pos == len(l.data) || isSpace(l.data[l.pos]) || isDelimiter(l.data[l.pos])) {
+			return
+		}
+	}
+	l.pos = len(l.data)
+}
@@ -0,0 +1,300 @@
+// Package pdftext extracts the text of PDF documents without external tools.
+// It reads the page content streams and decodes the shown strings through
+// the fonts' encodings and ToUnicode maps. Scanned pages have no text to
+// extract, pages that only draw images are reported as needing OCR.
+package pdftext
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Page is the text of one page, numbered from 1. NeedsOCR is set for pages
+// that draw images but have no text, such as scanned pages, their content
+// can only be read by recognizing the images.
+type Page struct {
+	Number   int
+	Text     string
+	NeedsOCR bool
+}
+
+// Extract returns the text of every page in order.
+func Extract(content []byte) ([]Page, error) {
+	d, err := load(content)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := d.pages()
+	result := make([]Page, len(pages))
+	for i, p := range pages {
+		w := &textWriter{}
+		d.runContent(d.contents(p), p.resources, w, make(map[ref]bool))
+		text := w.String()
+		result[i] = Page{
+			Number:   i + 1,
+			Text:     text,
+			NeedsOCR: w.images > 0 && !strings.ContainsFunc(text, isWordRune),
+		}
+	}
+	return result, nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// textWriter collects the text shown on a page. It compares where text is
+// shown with where the text before it ended to break lines and separate
+// words, and counts the images drawn.
+type textWriter struct {
+	b      strings.Builder
+	images int
+	// operators counts the operators run for the page, forms included
+	operators int
+
+	shown  bool
+	lastY  float64
+	height float64
+	// endX is where the last text ended, when its font has widths
+	endX     float64
+	endKnown bool
+}
+
+// show writes text shown at x, y in glyphs of the given height. Vertical
+// moves of less than half a line are sub- and superscripts or rules drawn
+// under words, not new lines. Without the widths of the previous text, any
+// move of the text position separates words.
+func (w *textWriter) show(text string, x, y, height, advance float64, known, moved bool) {
+	if text == "" {
+		return
+	}
+	if w.shown {
+		switch {
+		case math.Abs(y-w.lastY) > max(height, w.height)/2:
+			w.newline()
+		case w.endKnown:
+			if gap := x - w.endX; gap > height*0.15 || gap < -height {
+				w.space()
+			}
+		case moved:
+			w.space()
+		}
+	}
+	w.b.WriteString(text)
+	w.shown, w.lastY, w.height = true, y, height
+	w.endX, w.endKnown = x+advance, known
+}
+
+func (w *textWriter) space() {
+	s := w.b.String()
+	if s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		w.b.WriteByte(' ')
+	}
+}
+
+func (w *textWriter) newline() {
+	s := w.b.String()
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		w.b.WriteByte('\n')
+	}
+}
+
+// String trims the spaces at line ends and collapses runs of blank lines.
+func (w *textWriter) String() string {
+	lines := strings.Split(w.b.String(), "\n")
+	var out []string
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if line == "" {
+			blank = len(out) > 0
+			continue
+		}
+		if blank {
+			out = append(out, "")
+			blank = false
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// textState is the part of the text state that places text: the start of
+// the current line and the position after the last text, both in user space
+// without the transformations outside text objects, and the scale of the
+// text matrix.
+type textState struct {
+	font                 *font
+	size                 float64
+	charSpace, wordSpace float64
+	leading              float64
+	lineX, lineY         float64
+	x, y                 float64
+	scale                float64
+	// moved is set when the position changed since text was last shown
+	moved bool
+}
+
+func (ts *textState) moveTo(x, y float64) {
+	ts.lineX, ts.lineY = x, y
+	ts.x, ts.y = x, y
+	ts.moved = true
+}
+
+func (ts *textState) nextLine() {
+	leading := ts.leading
+	if leading == 0 {
+		leading = ts.size * 1.2
+	}
+	ts.moveTo(ts.lineX, ts.lineY-leading*ts.scale)
+}
+
+func (ts *textState) show(w *textWriter, obj object) {
+	text, advance, known := ts.font.show(obj, ts)
+	w.show(text, ts.x, ts.y, ts.size*ts.scale, advance, known, ts.moved)
+	ts.x += advance
+	ts.moved = false
+}
+
+const (
+	maxFormDepth = 8
+	// maxOperators bounds the work per page, forms drawn many times
+	// multiply the operators run
+	maxOperators = 1000000
+)
+
+// runContent interprets the text operators of a content stream. Form
+// XObjects drawn on the page are run with their own resources, forms holds
+// the forms being run so a form that draws itself isn't entered again.
+func (d *document) runContent(content []byte, resources dict, w *textWriter, forms map[ref]bool) {
+	l := &lexer{data: content}
+	var operands []object
+	fonts := make(map[name]*font)
+	ts := &textState{scale: 1}
+
+	for {
+		obj, err := l.object()
+		if err != nil {
+			if err == errDelimiter {
+				continue
+			}
+			return
+		}
+		op, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+		w.operators++
+		if w.operators > maxOperators {
+			return
+		}
+
+		n := len(operands)
+		switch op {
+		case "BT":
+			ts.scale = 1
+			ts.moveTo(0, 0)
+		case "Tf":
+			if n >= 2 {
+				if key, ok := operands[n-2].(name); ok {
+					if _, loaded := fonts[key]; !loaded {
+						fonts[key] = d.font(d.dict(resources["Font"])[key])
+					}
+					ts.font = fonts[key]
+				}
+				ts.size = math.Abs(number(operands[n-1]))
+			}
+		case "Tc":
+			if n >= 1 {
+				ts.charSpace = number(operands[n-1])
+			}
+		case "Tw":
+			if n >= 1 {
+				ts.wordSpace = number(operands[n-1])
+			}
+		case "TL":
+			if n >= 1 {
+				ts.leading = number(operands[n-1])
+			}
+		case "Td", "TD":
+			if n >= 2 {
+				tx, ty := number(operands[n-2]), number(operands[n-1])
+				if op == "TD" {
+					ts.leading = -ty
+				}
+				ts.moveTo(ts.lineX+tx*ts.scale, ts.lineY+ty*ts.scale)
+			}
+		case "Tm":
+			if n >= 6 {
+				ts.scale = math.Hypot(number(operands[n-6]), number(operands[n-5]))
+				ts.moveTo(number(operands[n-2]), number(operands[n-1]))
+			}
+		case "T*":
+			ts.nextLine()
+		case "Tj":
+			if n >= 1 {
+				ts.show(w, operands[n-1])
+			}
+		case "'", "\"":
+			ts.nextLine()
+			if n >= 1 {
+				ts.show(w, operands[n-1])
+			}
+		case "TJ":
+			if n >= 1 {
+				items, _ := operands[n-1].(array)
+				for _, item := range items {
+					switch v := item.(type) {
+					case []byte:
+						ts.show(w, v)
+					case int, float64:
+						ts.x -= number(v) / 1000 * ts.size * ts.scale
+						// Without widths, large negative adjustments
+						// separate words
+						if number(v) < -200 {
+							ts.moved = true
+						}
+					}
+				}
+			}
+		case "Do":
+			if n >= 1 && len(forms) < maxFormDepth {
+				key, _ := operands[n-1].(name)
+				entry := d.dict(resources["XObject"])[key]
+				form, ok := d.resolve(entry).(stream)
+				r, _ := entry.(ref)
+				if ok && form.dict["Subtype"] == name("Image") {
+					w.images++
+				}
+				if ok && form.dict["Subtype"] == name("Form") && !forms[r] {
+					formResources := d.dict(form.dict["Resources"])
+					if formResources == nil {
+						formResources = resources
+					}
+					if data, err := d.decode(form); err == nil {
+						forms[r] = true
+						d.runContent(data, formResources, w, forms)
+						delete(forms, r)
+					}
+				}
+			}
+		case "ID":
+			w.images++
+			l.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+func number(obj object) float64 {
+	switch v := obj.(type) {
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
@@ -0,0 +1,110 @@
+package pdftext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF numbers objects from 1 in order. A "stream" entry becomes a
+// stream object with its data after the dictionary, separated by a NUL.
+func buildPDF(objects ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&b, "%d 0 obj\n", i+1)
+		if dictionary, data, ok := strings.Cut(obj, "\x00"); ok {
+			fmt.Fprintf(&b, "%s\nstream\n%s\nendstream\n", dictionary, data)
+		} else {
+			b.WriteString(obj + "\n")
+		}
+		b.WriteString("endobj\n")
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+func deflate(data []byte) string {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write(data)
+	w.Close()
+	return b.String()
+}
+
+// helloPDF has one page showing text, with content compressed by filters.
+func helloPDF(filter, content string) []byte {
+	return buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< %s /Length %d >>\x00%s", filter, len(content), content),
+	)
+}
+
+func TestExtract(t *testing.T) {
+	const content = "BT /F1 12 Tf 72 720 Td (Hello world) Tj ET"
+
+	// PNG Up predictor rows of four bytes, each row the difference to the
+	// one above
+	var predicted []byte
+	previous := make([]byte, 4)
+	for row := range (len(content) + 3) / 4 {
+		line := []byte(content[row*4 : min(row*4+4, len(content))])
+		line = append(line, bytes.Repeat([]byte(" "), 4-len(line))...)
+		predicted = append(predicted, 2)
+		for i := range line {
+			predicted = append(predicted, line[i]-previous[i])
+		}
+		previous = line
+	}
+
+	for name, pdf := range map[string][]byte{
+		"plain":     helloPDF("", content),
+		"flate":     helloPDF("/Filter /FlateDecode", deflate([]byte(content))),
+		"predictor": helloPDF("/Filter /FlateDecode /DecodeParms << /Predictor 12 /Columns 4 >>", deflate(predicted)),
+	} {
+		pages, err := Extract(pdf)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(pages) != 1 || !strings.Contains(pages[0].Text, "Hello world") {
+			t.Errorf("%s: got %+v", name, pages)
+		}
+	}
+}
+
+func TestExtractPredictorLimits(t *testing.T) {
+	objects := "1 0 2 30 << /Type /Catalog /Pages 2 0 R >> << /Type /Pages /Kids [] /Count 0 >>"
+	for _, params := range []string{
+		"/Predictor 12 /Columns 1152921504606846977",
+		"/Predictor 12 /Columns 1000000000 /Colors 4 /BitsPerComponent 16",
+		"/Predictor 12 /Columns 4 /Colors 1000",
+		"/Predictor 12 /Columns 4 /BitsPerComponent 3",
+		"/Predictor 12 /Columns 60000",
+	} {
+		pdf := buildPDF(fmt.Sprintf("<< /Type /ObjStm /N 2 /First 8 /Filter /FlateDecode /DecodeParms << %s >> >>\x00%s",
+			params, deflate([]byte(objects))))
+		if _, err := Extract(pdf); err != nil {
+			t.Errorf("%s: %v", params, err)
+		}
+	}
+}
+
+func FuzzExtract(f *testing.F) {
+	const content = "BT /F1 12 Tf (Hello world) Tj ET"
+	f.Add(helloPDF("", content))
+	f.Add(helloPDF("/Filter /FlateDecode", deflate([]byte(content))))
+	f.Add(helloPDF("/Filter /FlateDecode /DecodeParms << /Predictor 12 /Columns 1152921504606846977 >>", deflate([]byte(content))))
+	f.Add(helloPDF("/Filter /ASCIIHexDecode", fmt.Sprintf("%x>", content)))
+	f.Add(buildPDF("<< /Type /Catalog /Pages 1 0 R /Kids [1 0 R] >>"))
+	f.Add(buildPDF(strings.Repeat("[", 200) + strings.Repeat("]", 200)))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		Extract(data)
+	})
+}
@@ -0,0 +1,81 @@
+package processor
+
+import (
+	"fmt"
+	"mime"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/chunker"
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	pb "github.com/ozgurnsahin/document-processor-pp/document-ingestion/proto"
+)
+
+// chunksLocally reports whether the document is chunked in the ingestion
+// service. That needs a chunk strategy and text the ingestion service
+// extracted itself, PDFs are left to the processing service.
+func chunksLocally(doc *models.Document) bool {
+	if doc.Processing == nil || doc.Processing.ChunkStrategy == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(doc.ContentType)
+	return err == nil && (mediaType == "text/plain" || mediaType == "text/markdown")
+}
+
+// processingOptions translates the options of the document for the
+// processing service, nil keeps its defaults.
+func processingOptions(doc *models.Document) *pb.ProcessingOptions {
+	if doc.Processing == nil {
+		return nil
+	}
+	return &pb.ProcessingOptions{
+		ChunkStrategy: doc.Processing.ChunkStrategy,
+		ChunkSize:     int32(doc.Processing.ChunkSize),
+		ChunkOverlap:  int32(doc.Processing.ChunkOverlap),
+		Language:      doc.Processing.Language,
+		Ocr:           doc.Processing.OCR,
+	}
+}
+
+// chunkText splits the extracted text of the document with its chunk options,
+// recursive chunks of 1000 characters without them, and embeds the chunks.
+func chunkText(doc *models.Document, text string, embedder Embedder) ([]*models.DocumentChunk, error) {
+	options := chunker.Options{Strategy: chunker.Recursive, Size: 1000, Overlap: 100}
+	if doc.Processing != nil && doc.Processing.ChunkStrategy != "" {
+		options = chunker.Options{
+			Strategy: doc.Processing.ChunkStrategy,
+			Size:     doc.Processing.ChunkSize,
+			Overlap:  doc.Processing.ChunkOverlap,
+		}
+	}
+	split, err := chunker.New(options)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := split.Split(text)
+	texts := make([]string, len(parts))
+	for i, part := range parts {
+		texts[i] = part.Text
+	}
+
+	vectors, err := embedder.EmbedBatch(texts)
+	if err != nil {
+		return nil, fmt.Errorf("error embedding chunks: %w", err)
+	}
+
+	chunks := make([]*models.DocumentChunk, len(parts))
+	for i, part := range parts {
+		chunks[i] = &models.DocumentChunk{
+			DocumentID:  doc.ID,
+			ChunkIndex:  part.Index,
+			Text:        part.Text,
+			Vector:      vectors[i],
+			Model:       embedder.Model(),
+			StartOffset: part.Start,
+			EndOffset:   part.End,
+			Heading:     part.Heading,
+		}
+	}
+
+	fmt.Printf("Chunked document %s into %d %s chunks\n", doc.ID, len(chunks), options.Strategy)
+	return chunks, nil
+}
@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/joho/godotenv"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
 	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
 	pb "github.com/ozgurnsahin/document-processor-pp/document-ingestion/proto"
 )
@@ -19,6 +23,10 @@ type Client struct{
   	serviceAddr string
 	client      pb.DocumentProcessorServiceClient
 	conn        *grpc.ClientConn
+	embedder    Embedder
+
+	modelMu sync.RWMutex
+	model   string
 }
 
 func NewClient() (*Client, error){
@@ -42,26 +50,58 @@ func NewClient() (*Client, error){
 
 	client := pb.NewDocumentProcessorServiceClient(conn)
 
-	return &Client{
+	c := &Client{
 		serviceAddr: serviceAddr,
 		client: client,
 		conn: conn,
-	}, nil
+		model: config.String("EMBEDDING_MODEL", "text-embedding-3-small"),
+	}
+
+	c.embedder, err = newEmbedder(config.String("EMBEDDER", "grpc"), c)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	fmt.Printf("Embedding with %s\n", c.embedder.Model())
+
+	return c, nil
 
 }
 
 func (c *Client) ProcessDocument(doc *models.Document) ([]*models.DocumentChunk, error){
+	if chunksLocally(doc) {
+		content, err := doc.Content.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("error loading document content: %w", err)
+		}
+		return chunkText(doc, string(content), c.embedder)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second * 600)
 	defer cancel()
 
+	content, err := doc.Content.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("error loading document content: %w", err)
+	}
+
 	req := &pb.ProcessRequest{
 		DocumentId: doc.ID,
 		Filename: doc.FileName,
-		Content: doc.Content,
+		Content: content,
 		ContentType: doc.ContentType,
+		Options: processingOptions(doc),
+		// Other embedders embed the chunks here, the service doesn't need
+		// to call OpenAI for vectors that are replaced
+		SkipEmbedding: !c.UsesService(),
 	}
 
 	resp, err := c.client.ProcessDocument(ctx, req)
+	// PDFs can still be read in process, without OCR, when the service is down
+	if status.Code(err) == codes.Unavailable && isPDF(doc) && config.Bool("PDF_FALLBACK", true) {
+		log.Printf("Warning: processing service unavailable, reading PDF %s in process: %v", doc.ID, err)
+		return chunkPDF(doc, content, c.embedder)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error calling processing service: %w", err)
 	}
@@ -79,11 +119,37 @@ func (c *Client) ProcessDocument(doc *models.Document) ([]*models.DocumentChunk,
             ChunkIndex: i,
             Text:       chunk.Text,
             Vector:     chunk.Vector,
+            Model:      c.embedder.Model(),
         })
     }
 
     fmt.Printf("Received %d processed chunks for document: %s\n", len(chunks), doc.ID)
 
+	// The service embeds with its default model unless asked not to. Other
+	// embedders, and the service's active model when a migration switched
+	// models, replace those vectors so chunks and queries are comparable
+	if _, ok := c.embedder.(*serviceEmbedder); ok && (resp.Model == "" || resp.Model == c.Model()) {
+		vectors := make([][]float32, len(chunks))
+		for i, chunk := range chunks {
+			vectors[i] = chunk.Vector
+		}
+		if err := checkEmbeddings(c.Model(), resp.Model, resp.Dimensions, vectors); err != nil {
+			return nil, err
+		}
+	} else {
+		texts := make([]string, len(chunks))
+		for i, chunk := range chunks {
+			texts[i] = chunk.Text
+		}
+		vectors, err := c.embedder.EmbedBatch(texts)
+		if err != nil {
+			return nil, fmt.Errorf("error embedding chunks: %w", err)
+		}
+		for i, chunk := range chunks {
+			chunk.Vector = vectors[i]
+		}
+	}
+
     return chunks, nil
 
 }
@@ -105,10 +171,106 @@ func (c *Client) CreateInputEmbeddings(text string) ([]float32, error) {
         return nil, fmt.Errorf("embedding service error: %s", resp.Error)
     }
 
+	// CreateEmbedding always uses the service's default model
+	if resp.Model != "" && resp.Model != c.Model() {
+		vectors, err := c.CreateEmbeddings([]string{text}, c.Model())
+		if err != nil {
+			return nil, err
+		}
+		return vectors[0], nil
+	}
+
+	if err := checkEmbeddings(c.Model(), resp.Model, resp.Dimensions, [][]float32{resp.Vector}); err != nil {
+		return nil, err
+	}
+
     return resp.Vector, nil
 	
 }
 
+// checkEmbeddings refuses vectors of another model than the expected one, so
+// a model change on the processing service doesn't mix vectors of different
+// models in the index. Services that don't report a model are trusted.
+func checkEmbeddings(expected, model string, dimensions int32, vectors [][]float32) error {
+	if model != "" && model != expected {
+		return fmt.Errorf("embedding service used model %s, expected %s", model, expected)
+	}
+	if dimensions <= 0 {
+		return nil
+	}
+	for _, vector := range vectors {
+		if len(vector) != int(dimensions) {
+			return fmt.Errorf("embedding service returned a vector of %d dimensions, expected %d", len(vector), dimensions)
+		}
+	}
+	return nil
+}
+
+// Model is the embedding model of the vectors search uses. It starts as
+// EMBEDDING_MODEL and changes when a migration switches models.
+func (c *Client) Model() string {
+	c.modelMu.RLock()
+	defer c.modelMu.RUnlock()
+	return c.model
+}
+
+func (c *Client) SetModel(model string) {
+	c.modelMu.Lock()
+	c.model = model
+	c.modelMu.Unlock()
+}
+
+// Embedder returns the embedder that produces the vectors of chunks and
+// queries.
+func (c *Client) Embedder() Embedder {
+	return c.embedder
+}
+
+// UsesService reports whether chunks and queries are embedded by the
+// processing service, the only embedder that can switch models.
+func (c *Client) UsesService() bool {
+	_, ok := c.embedder.(*serviceEmbedder)
+	return ok
+}
+
+// CreateEmbeddings embeds many texts with model using the CreateEmbeddings
+// call, sending at most EMBEDDING_BATCH_SIZE texts per call. Vectors are in
+// the order of the texts.
+func (c *Client) CreateEmbeddings(texts []string, model string) ([][]float32, error) {
+	batchSize := config.Int("EMBEDDING_BATCH_SIZE", 256)
+	if batchSize <= 0 {
+		batchSize = len(texts)
+	}
+
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		batch := texts[start:min(start+batchSize, len(texts))]
+
+		ctx, cancel := context.WithTimeout(context.Background(), 120 * time.Second)
+		resp, err := c.client.CreateEmbeddings(ctx, &pb.EmbeddingsRequest{Texts: batch, Model: model})
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("error calling embedding service: %w", err)
+		}
+		if resp.Error != "" {
+			return nil, fmt.Errorf("embedding service error: %s", resp.Error)
+		}
+		if len(resp.Embeddings) != len(batch) {
+			return nil, fmt.Errorf("embedding service returned %d vectors for %d texts", len(resp.Embeddings), len(batch))
+		}
+
+		first := len(vectors)
+		for _, embedding := range resp.Embeddings {
+			vectors = append(vectors, embedding.Vector)
+		}
+		if err := checkEmbeddings(model, resp.Model, resp.Dimensions, vectors[first:]); err != nil {
+			return nil, err
+		}
+	}
+
+	return vectors, nil
+}
+
 func (c *Client) Close() error{
 	if c.conn != nil {
 		return c.conn.Close()
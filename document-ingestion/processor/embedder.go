@@ -0,0 +1,54 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+)
+
+// Embedder turns text into vectors. Chunks and queries have to be embedded by
+// the same model for search to compare them.
+type Embedder interface {
+	Embed(text string) ([]float32, error)
+	// EmbedBatch returns one vector per text, in order.
+	EmbedBatch(texts []string) ([][]float32, error)
+	Model() string
+}
+
+// newEmbedder picks the embedder named by EMBEDDER. The processing service
+// embeds with OpenAI through c, the hash embedder works offline.
+func newEmbedder(name string, c *Client) (Embedder, error) {
+	switch name {
+	case "grpc":
+		if c == nil {
+			return nil, fmt.Errorf("the grpc embedder needs the processing service")
+		}
+		return &serviceEmbedder{client: c}, nil
+	case "hash":
+		dimensions := config.Int("EMBEDDING_DIMENSIONS", 1536)
+		if dimensions <= 0 {
+			return nil, fmt.Errorf("EMBEDDING_DIMENSIONS must be positive")
+		}
+		return NewHashEmbedder(dimensions), nil
+	default:
+		return nil, fmt.Errorf("unknown embedder %q", name)
+	}
+}
+
+// serviceEmbedder embeds with the CreateEmbedding call of the processing
+// service.
+type serviceEmbedder struct {
+	client *Client
+}
+
+func (e *serviceEmbedder) Embed(text string) ([]float32, error) {
+	return e.client.CreateInputEmbeddings(text)
+}
+
+func (e *serviceEmbedder) EmbedBatch(texts []string) ([][]float32, error) {
+	return e.client.CreateEmbeddings(texts, e.client.Model())
+}
+
+func (e *serviceEmbedder) Model() string {
+	return e.client.Model()
+}
@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// HashEmbedder maps text to vectors without a model: words, word pairs and
+// character trigrams are hashed into a fixed number of dimensions. Texts that
+// share words end up close together, which is enough to run ingestion and
+// search offline in development, CI and air-gapped sites. The same text
+// always gets the same vector.
+type HashEmbedder struct {
+	dimensions int
+}
+
+func NewHashEmbedder(dimensions int) *HashEmbedder {
+	return &HashEmbedder{dimensions: dimensions}
+}
+
+func (e *HashEmbedder) Model() string {
+	return fmt.Sprintf("hash-v1-%d", e.dimensions)
+}
+
+// Embed returns a unit vector, or a zero vector for text without words.
+func (e *HashEmbedder) Embed(text string) ([]float32, error) {
+	vector := make([]float64, e.dimensions)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for i, word := range words {
+		e.add(vector, "w:"+word, 1)
+		if i > 0 {
+			e.add(vector, "b:"+words[i-1]+" "+word, 0.5)
+		}
+
+		// Trigrams let inflected forms of a word share most features
+		runes := []rune("^" + word + "$")
+		for j := 0; j+3 <= len(runes); j++ {
+			e.add(vector, "c:"+string(runes[j:j+3]), 0.25)
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	embedding := make([]float32, e.dimensions)
+	if norm == 0 {
+		return embedding, nil
+	}
+	for i, v := range vector {
+		embedding[i] = float32(v / norm)
+	}
+	return embedding, nil
+}
+
+func (e *HashEmbedder) EmbedBatch(texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = e.Embed(text)
+	}
+	return vectors, nil
+}
+
+// add hashes a feature to a dimension and a sign, the sign keeps collisions
+// from adding up.
+func (e *HashEmbedder) add(vector []float64, feature string, weight float64) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vector[(sum&(1<<63-1))%uint64(e.dimensions)] += weight
+}
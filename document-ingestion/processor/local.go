@@ -0,0 +1,76 @@
+package processor
+
+import (
+	"fmt"
+	"log"
+	"mime"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+)
+
+// Local processes documents without the processing service. Text and
+// Markdown, which the formats already extracted, and PDFs read by pdftext are
+// chunked with the Go chunker and embedded with the configured embedder.
+type Local struct {
+	embedder Embedder
+	// client embeds through the processing service with EMBEDDER=grpc
+	client *Client
+}
+
+// NewLocal embeds with the hash embedder unless EMBEDDER names another one.
+func NewLocal() (*Local, error) {
+	p := &Local{}
+
+	switch name := config.String("EMBEDDER", "hash"); name {
+	case "grpc":
+		client, err := NewClient()
+		if err != nil {
+			return nil, err
+		}
+		p.client = client
+		p.embedder = client.Embedder()
+	default:
+		embedder, err := newEmbedder(name, nil)
+		if err != nil {
+			return nil, err
+		}
+		p.embedder = embedder
+		fmt.Printf("Embedding with %s\n", embedder.Model())
+	}
+
+	fmt.Println("Processing documents in process")
+	return p, nil
+}
+
+func (p *Local) ProcessDocument(doc *models.Document) ([]*models.DocumentChunk, error) {
+	content, err := doc.Content.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("error loading document content: %w", err)
+	}
+
+	if doc.Processing != nil && doc.Processing.OCR {
+		log.Printf("Warning: OCR needs the processing service, document %s is read without it", doc.ID)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(doc.ContentType)
+	switch mediaType {
+	case "text/plain", "text/markdown":
+		return chunkText(doc, string(content), p.embedder)
+	case "application/pdf":
+		return chunkPDF(doc, content, p.embedder)
+	default:
+		return nil, fmt.Errorf("%s can't be processed without the processing service", doc.ContentType)
+	}
+}
+
+func (p *Local) Embedder() Embedder {
+	return p.embedder
+}
+
+func (p *Local) Close() error {
+	if p.client != nil {
+		return p.client.Close()
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"sort"
+	"strings"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/pdftext"
+)
+
+func isPDF(doc *models.Document) bool {
+	mediaType, _, _ := mime.ParseMediaType(doc.ContentType)
+	return mediaType == "application/pdf"
+}
+
+// chunkPDF reads a PDF with pdftext and chunks its text, each chunk records
+// the page it starts on. Pages that only hold images are listed in the
+// document's OCRPages, a PDF without any text fails as there is nothing to
+// index.
+func chunkPDF(doc *models.Document, content []byte, embedder Embedder) ([]*models.DocumentChunk, error) {
+	pages, err := pdftext.Extract(content)
+	if errors.Is(err, pdftext.ErrEncrypted) {
+		return nil, fmt.Errorf("pdf is encrypted and can't be read without the processing service")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading pdf: %w", err)
+	}
+
+	// Pages are joined by blank lines, starts holds the offset of each page
+	// in the joined text
+	var text strings.Builder
+	starts := make([]int, 0, len(pages))
+	numbers := make([]int, 0, len(pages))
+	doc.OCRPages = nil
+	for _, page := range pages {
+		if page.NeedsOCR {
+			doc.OCRPages = append(doc.OCRPages, page.Number)
+		}
+		if page.Text == "" {
+			continue
+		}
+		if text.Len() > 0 {
+			text.WriteString("\n\n")
+		}
+		starts = append(starts, text.Len())
+		numbers = append(numbers, page.Number)
+		text.WriteString(page.Text)
+	}
+
+	if len(doc.OCRPages) > 0 {
+		log.Printf("Warning: %d pages of document %s only hold images and need OCR: %v", len(doc.OCRPages), doc.ID, doc.OCRPages)
+	}
+	if strings.TrimSpace(text.String()) == "" {
+		if len(doc.OCRPages) > 0 {
+			return nil, fmt.Errorf("pdf has no text, its pages only hold images and need OCR")
+		}
+		return nil, fmt.Errorf("pdf has no text to extract")
+	}
+
+	chunks, err := chunkText(doc, text.String(), embedder)
+	if err != nil {
+		return nil, err
+	}
+	for _, chunk := range chunks {
+		i := sort.Search(len(starts), func(i int) bool { return starts[i] > chunk.StartOffset }) - 1
+		chunk.Page = numbers[max(i, 0)]
+	}
+	return chunks, nil
+}
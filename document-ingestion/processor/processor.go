@@ -0,0 +1,44 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+)
+
+// Processor turns a document into embedded chunks.
+type Processor interface {
+	ProcessDocument(doc *models.Document) ([]*models.DocumentChunk, error)
+	// Embedder embeds queries the way the chunks were embedded.
+	Embedder() Embedder
+	Close() error
+}
+
+// New returns the processor named by PROCESSOR: "grpc" sends documents to
+// the processing service, "local" processes them in this process so the
+// ingestion service runs on its own.
+func New() (Processor, error) {
+	switch name := config.String("PROCESSOR", "grpc"); name {
+	case "grpc":
+		return NewClient()
+	case "local":
+		return NewLocal()
+	default:
+		return nil, fmt.Errorf("unknown processor %q", name)
+	}
+}
+
+// ServiceClient returns the processing service client p embeds with, or nil
+// when its vectors don't come from the processing service.
+func ServiceClient(p Processor) *Client {
+	switch p := p.(type) {
+	case *Client:
+		if p.UsesService() {
+			return p
+		}
+	case *Local:
+		return p.client
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+package processor
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+)
+
+// QueryCacheStore persists snapshots of the query cache.
+type QueryCacheStore interface {
+	LoadQueryCache() ([]models.QueryCacheEntry, error)
+	SaveQueryCache(entries []models.QueryCacheEntry) error
+}
+
+// QueryCache is an Embedder that remembers the vectors of recent queries, so
+// popular searches don't cost an embedding call each time. Entries are keyed
+// on the normalized query and the embedding model, the least recently used
+// entry is dropped when the cache is full.
+type QueryCache struct {
+	embedder Embedder
+	capacity int
+	ttl      time.Duration
+	store    QueryCacheStore
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	dirty   bool
+	stats   QueryCacheStats
+}
+
+// QueryCacheStats counts cache use since the service started.
+type QueryCacheStats struct {
+	Model     string  `json:"model"`
+	Entries   int     `json:"entries"`
+	Capacity  int     `json:"capacity"`
+	TTL       string  `json:"ttl"`
+	Hits      int64   `json:"hits"`
+	Misses    int64   `json:"misses"`
+	Evictions int64   `json:"evictions"`
+	Expired   int64   `json:"expired"`
+	HitRate   float64 `json:"hit_rate"`
+}
+
+// NewQueryCache wraps embedder with a cache of QUERY_CACHE_SIZE entries,
+// zero returns the embedder itself. mongoStore is used when QUERY_CACHE_STORE
+// is "mongo", "file" keeps the cache in QUERY_CACHE_FILE.
+func NewQueryCache(embedder Embedder, mongoStore QueryCacheStore) (Embedder, error) {
+	capacity := config.Int("QUERY_CACHE_SIZE", 1000)
+	if capacity <= 0 {
+		return embedder, nil
+	}
+
+	c := &QueryCache{
+		embedder: embedder,
+		capacity: capacity,
+		ttl:      config.Duration("QUERY_CACHE_TTL", 24*time.Hour),
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+
+	switch name := config.String("QUERY_CACHE_STORE", ""); name {
+	case "":
+	case "mongo":
+		c.store = mongoStore
+	case "file":
+		c.store = &fileCacheStore{path: config.String("QUERY_CACHE_FILE", "query-cache.json")}
+	default:
+		return nil, fmt.Errorf("unknown query cache store %q", name)
+	}
+
+	if c.store != nil {
+		if err := c.load(); err != nil {
+			log.Printf("Warning: starting with an empty query cache: %v", err)
+		}
+		go c.saveLoop(config.Duration("QUERY_CACHE_SAVE_INTERVAL", 5*time.Minute))
+	}
+
+	return c, nil
+}
+
+func (c *QueryCache) Model() string {
+	return c.embedder.Model()
+}
+
+func (c *QueryCache) Embed(text string) ([]float32, error) {
+	// The model is read once, a switch while the query is embedded must not
+	// file the vector under the new model
+	model := c.embedder.Model()
+	query := normalizeQuery(text)
+
+	if vector := c.get(cacheKey(model, query)); vector != nil {
+		return vector, nil
+	}
+
+	// Normalizing only picks the cache entry, the query is embedded as written
+	vector, err := c.embedder.Embed(text)
+	if err != nil {
+		return nil, err
+	}
+
+	c.put(models.QueryCacheEntry{
+		Query:     query,
+		Model:     model,
+		Vector:    vector,
+		CreatedAt: time.Now(),
+	})
+	return vector, nil
+}
+
+// EmbedBatch serves cached queries from the cache and embeds the others in one
+// call. Queries that share an entry are embedded once, as the first of them
+// was written.
+func (c *QueryCache) EmbedBatch(texts []string) ([][]float32, error) {
+	model := c.embedder.Model()
+	vectors := make([][]float32, len(texts))
+	var missing, queries []string
+	var positions [][]int
+	seen := make(map[string]int)
+
+	for i, text := range texts {
+		query := normalizeQuery(text)
+		if vector := c.get(cacheKey(model, query)); vector != nil {
+			vectors[i] = vector
+			continue
+		}
+		if j, ok := seen[query]; ok {
+			positions[j] = append(positions[j], i)
+			continue
+		}
+		seen[query] = len(missing)
+		missing = append(missing, text)
+		queries = append(queries, query)
+		positions = append(positions, []int{i})
+	}
+
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	embedded, err := c.embedder.EmbedBatch(missing)
+	if err != nil {
+		return nil, err
+	}
+	for j, query := range queries {
+		c.put(models.QueryCacheEntry{
+			Query:     query,
+			Model:     model,
+			Vector:    embedded[j],
+			CreatedAt: time.Now(),
+		})
+		for _, i := range positions[j] {
+			vectors[i] = embedded[j]
+		}
+	}
+
+	return vectors, nil
+}
+
+func (c *QueryCache) get(key string) []float32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil
+	}
+
+	entry := element.Value.(*models.QueryCacheEntry)
+	if c.expired(entry) {
+		c.remove(element)
+		c.stats.Expired++
+		c.stats.Misses++
+		return nil
+	}
+
+	c.order.MoveToFront(element)
+	c.stats.Hits++
+	return entry.Vector
+}
+
+// put stores an entry. It is dropped when the model changed while the query
+// was embedded, the vector may come from either model.
+func (c *QueryCache) put(entry models.QueryCacheEntry) {
+	if c.embedder.Model() != entry.Model {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey(entry.Model, entry.Query)
+	if element, ok := c.entries[key]; ok {
+		element.Value = &entry
+		c.order.MoveToFront(element)
+	} else {
+		c.entries[key] = c.order.PushFront(&entry)
+	}
+
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+	c.dirty = true
+}
+
+func (c *QueryCache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*models.QueryCacheEntry)
+	delete(c.entries, cacheKey(entry.Model, entry.Query))
+	c.dirty = true
+}
+
+func (c *QueryCache) expired(entry *models.QueryCacheEntry) bool {
+	return c.ttl > 0 && time.Since(entry.CreatedAt) > c.ttl
+}
+
+// Invalidate drops every entry, e.g. after the embedding model was changed in
+// place under the same name. It returns how many entries were dropped.
+func (c *QueryCache) Invalidate() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dropped := c.order.Len()
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	c.dirty = true
+	return dropped
+}
+
+func (c *QueryCache) Stats() QueryCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Model = c.embedder.Model()
+	stats.Entries = c.order.Len()
+	stats.Capacity = c.capacity
+	stats.TTL = c.ttl.String()
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// load restores a snapshot, skipping entries of other models and expired
+// ones. Entries are stored most recently used first.
+func (c *QueryCache) load() error {
+	entries, err := c.store.LoadQueryCache()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	model := c.embedder.Model()
+	for i := range entries {
+		entry := entries[i]
+		if entry.Model != model || c.expired(&entry) || c.order.Len() == c.capacity {
+			continue
+		}
+		key := cacheKey(entry.Model, entry.Query)
+		if _, ok := c.entries[key]; !ok {
+			c.entries[key] = c.order.PushBack(&entry)
+		}
+	}
+
+	fmt.Printf("Loaded %d cached query embeddings\n", c.order.Len())
+	return nil
+}
+
+func (c *QueryCache) saveLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := c.Save(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
+
+// Save writes a snapshot to the store if anything changed since the last one.
+func (c *QueryCache) Save() error {
+	if c.store == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	entries := make([]models.QueryCacheEntry, 0, c.order.Len())
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		entries = append(entries, *element.Value.(*models.QueryCacheEntry))
+	}
+	c.dirty = false
+	c.mu.Unlock()
+
+	if err := c.store.SaveQueryCache(entries); err != nil {
+		c.mu.Lock()
+		c.dirty = true
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// normalizeQuery makes queries that differ only in case, spacing or Unicode
+// composition share an entry.
+func normalizeQuery(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(norm.NFC.String(text))), " ")
+}
+
+func cacheKey(model, query string) string {
+	return model + "\x00" + query
+}
+
+type fileCacheStore struct {
+	path string
+}
+
+func (s *fileCacheStore) LoadQueryCache() ([]models.QueryCacheEntry, error) {
+	content, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query cache: %w", err)
+	}
+
+	var entries []models.QueryCacheEntry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("invalid query cache file: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *fileCacheStore) SaveQueryCache(entries []models.QueryCacheEntry) error {
+	content, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode query cache: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".query-cache-*")
+	if err != nil {
+		return fmt.Errorf("failed to save query cache: %w", err)
+	}
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save query cache: %w", err)
+	}
+	return nil
+}
@@ -23,10 +23,12 @@ const (
 
 type ProcessRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Content       []byte                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`                            // The document content as binary data
-	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`                          // The filename
-	ContentType   string                 `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"` // MIME type like "application/pdf"
-	DocumentId    string                 `protobuf:"bytes,4,opt,name=document_id,json=documentId,proto3" json:"document_id,omitempty"`    // Unique ID for the document
+	Content       []byte                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`                                   // The document content as binary data
+	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`                                 // The filename
+	ContentType   string                 `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`        // MIME type like "application/pdf"
+	DocumentId    string                 `protobuf:"bytes,4,opt,name=document_id,json=documentId,proto3" json:"document_id,omitempty"`           // Unique ID for the document
+	Options       *ProcessingOptions     `protobuf:"bytes,5,opt,name=options,proto3" json:"options,omitempty"`                                   // How to process it, service defaults if unset
+	SkipEmbedding bool                   `protobuf:"varint,6,opt,name=skip_embedding,json=skipEmbedding,proto3" json:"skip_embedding,omitempty"` // Only chunk, the caller embeds the chunks itself
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -89,19 +91,111 @@ func (x *ProcessRequest) GetDocumentId() string {
 	return ""
 }
 
+func (x *ProcessRequest) GetOptions() *ProcessingOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *ProcessRequest) GetSkipEmbedding() bool {
+	if x != nil {
+		return x.SkipEmbedding
+	}
+	return false
+}
+
+type ProcessingOptions struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChunkStrategy string                 `protobuf:"bytes,1,opt,name=chunk_strategy,json=chunkStrategy,proto3" json:"chunk_strategy,omitempty"` // "fixed", "recursive", "sentence", "markdown" or "token"
+	ChunkSize     int32                  `protobuf:"varint,2,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`            // Characters per chunk, tokens for "token"
+	ChunkOverlap  int32                  `protobuf:"varint,3,opt,name=chunk_overlap,json=chunkOverlap,proto3" json:"chunk_overlap,omitempty"`   // Characters or tokens neighbouring chunks share
+	Language      string                 `protobuf:"bytes,4,opt,name=language,proto3" json:"language,omitempty"`                                // ISO 639-1 code like "en", used for OCR
+	Ocr           bool                   `protobuf:"varint,5,opt,name=ocr,proto3" json:"ocr,omitempty"`                                         // Recognize text on PDF pages that only have images
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessingOptions) Reset() {
+	*x = ProcessingOptions{}
+	mi := &file_proto_document_process_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessingOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessingOptions) ProtoMessage() {}
+
+func (x *ProcessingOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_document_process_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessingOptions.ProtoReflect.Descriptor instead.
+func (*ProcessingOptions) Descriptor() ([]byte, []int) {
+	return file_proto_document_process_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ProcessingOptions) GetChunkStrategy() string {
+	if x != nil {
+		return x.ChunkStrategy
+	}
+	return ""
+}
+
+func (x *ProcessingOptions) GetChunkSize() int32 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
+func (x *ProcessingOptions) GetChunkOverlap() int32 {
+	if x != nil {
+		return x.ChunkOverlap
+	}
+	return 0
+}
+
+func (x *ProcessingOptions) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+func (x *ProcessingOptions) GetOcr() bool {
+	if x != nil {
+		return x.Ocr
+	}
+	return false
+}
+
 type ProcessResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	DocumentId    string                 `protobuf:"bytes,1,opt,name=document_id,json=documentId,proto3" json:"document_id,omitempty"` // The ID of the processed document
 	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`                           // Status like "completed", "failed"
 	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`                             // Error message if any
 	Chunks        []*ProcessedChunk      `protobuf:"bytes,4,rep,name=chunks,proto3" json:"chunks,omitempty"`                           // Processed text chunks with embeddings
+	Model         string                 `protobuf:"bytes,5,opt,name=model,proto3" json:"model,omitempty"`                             // Embedding model that produced the vectors
+	Dimensions    int32                  `protobuf:"varint,6,opt,name=dimensions,proto3" json:"dimensions,omitempty"`                  // Length of every vector
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ProcessResponse) Reset() {
 	*x = ProcessResponse{}
-	mi := &file_proto_document_process_proto_msgTypes[1]
+	mi := &file_proto_document_process_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -113,7 +207,7 @@ func (x *ProcessResponse) String() string {
 func (*ProcessResponse) ProtoMessage() {}
 
 func (x *ProcessResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_document_process_proto_msgTypes[1]
+	mi := &file_proto_document_process_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -126,7 +220,7 @@ func (x *ProcessResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ProcessResponse.ProtoReflect.Descriptor instead.
 func (*ProcessResponse) Descriptor() ([]byte, []int) {
-	return file_proto_document_process_proto_rawDescGZIP(), []int{1}
+	return file_proto_document_process_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *ProcessResponse) GetDocumentId() string {
@@ -157,6 +251,20 @@ func (x *ProcessResponse) GetChunks() []*ProcessedChunk {
 	return nil
 }
 
+func (x *ProcessResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ProcessResponse) GetDimensions() int32 {
+	if x != nil {
+		return x.Dimensions
+	}
+	return 0
+}
+
 type ProcessedChunk struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`              // The text segment
@@ -167,7 +275,7 @@ type ProcessedChunk struct {
 
 func (x *ProcessedChunk) Reset() {
 	*x = ProcessedChunk{}
-	mi := &file_proto_document_process_proto_msgTypes[2]
+	mi := &file_proto_document_process_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -179,7 +287,7 @@ func (x *ProcessedChunk) String() string {
 func (*ProcessedChunk) ProtoMessage() {}
 
 func (x *ProcessedChunk) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_document_process_proto_msgTypes[2]
+	mi := &file_proto_document_process_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -192,7 +300,7 @@ func (x *ProcessedChunk) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ProcessedChunk.ProtoReflect.Descriptor instead.
 func (*ProcessedChunk) Descriptor() ([]byte, []int) {
-	return file_proto_document_process_proto_rawDescGZIP(), []int{2}
+	return file_proto_document_process_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *ProcessedChunk) GetText() string {
@@ -218,7 +326,7 @@ type EmbeddingRequest struct {
 
 func (x *EmbeddingRequest) Reset() {
 	*x = EmbeddingRequest{}
-	mi := &file_proto_document_process_proto_msgTypes[3]
+	mi := &file_proto_document_process_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -230,7 +338,7 @@ func (x *EmbeddingRequest) String() string {
 func (*EmbeddingRequest) ProtoMessage() {}
 
 func (x *EmbeddingRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_document_process_proto_msgTypes[3]
+	mi := &file_proto_document_process_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -243,7 +351,7 @@ func (x *EmbeddingRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EmbeddingRequest.ProtoReflect.Descriptor instead.
 func (*EmbeddingRequest) Descriptor() ([]byte, []int) {
-	return file_proto_document_process_proto_rawDescGZIP(), []int{3}
+	return file_proto_document_process_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *EmbeddingRequest) GetText() string {
@@ -257,13 +365,15 @@ type EmbeddingResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Vector        []float32              `protobuf:"fixed32,1,rep,packed,name=vector,proto3" json:"vector,omitempty"`
 	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Model         string                 `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	Dimensions    int32                  `protobuf:"varint,4,opt,name=dimensions,proto3" json:"dimensions,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *EmbeddingResponse) Reset() {
 	*x = EmbeddingResponse{}
-	mi := &file_proto_document_process_proto_msgTypes[4]
+	mi := &file_proto_document_process_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -275,7 +385,7 @@ func (x *EmbeddingResponse) String() string {
 func (*EmbeddingResponse) ProtoMessage() {}
 
 func (x *EmbeddingResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_document_process_proto_msgTypes[4]
+	mi := &file_proto_document_process_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -288,7 +398,7 @@ func (x *EmbeddingResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EmbeddingResponse.ProtoReflect.Descriptor instead.
 func (*EmbeddingResponse) Descriptor() ([]byte, []int) {
-	return file_proto_document_process_proto_rawDescGZIP(), []int{4}
+	return file_proto_document_process_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *EmbeddingResponse) GetVector() []float32 {
@@ -305,34 +415,244 @@ func (x *EmbeddingResponse) GetError() string {
 	return ""
 }
 
+func (x *EmbeddingResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *EmbeddingResponse) GetDimensions() int32 {
+	if x != nil {
+		return x.Dimensions
+	}
+	return 0
+}
+
+type EmbeddingsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Texts         []string               `protobuf:"bytes,1,rep,name=texts,proto3" json:"texts,omitempty"` // Texts to embed in one call
+	Model         string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"` // Model to embed with, the service default if empty
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EmbeddingsRequest) Reset() {
+	*x = EmbeddingsRequest{}
+	mi := &file_proto_document_process_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EmbeddingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EmbeddingsRequest) ProtoMessage() {}
+
+func (x *EmbeddingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_document_process_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EmbeddingsRequest.ProtoReflect.Descriptor instead.
+func (*EmbeddingsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_document_process_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *EmbeddingsRequest) GetTexts() []string {
+	if x != nil {
+		return x.Texts
+	}
+	return nil
+}
+
+func (x *EmbeddingsRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+type EmbeddingsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Embeddings    []*Embedding           `protobuf:"bytes,1,rep,name=embeddings,proto3" json:"embeddings,omitempty"` // One per text, in request order
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Model         string                 `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	Dimensions    int32                  `protobuf:"varint,4,opt,name=dimensions,proto3" json:"dimensions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EmbeddingsResponse) Reset() {
+	*x = EmbeddingsResponse{}
+	mi := &file_proto_document_process_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EmbeddingsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EmbeddingsResponse) ProtoMessage() {}
+
+func (x *EmbeddingsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_document_process_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EmbeddingsResponse.ProtoReflect.Descriptor instead.
+func (*EmbeddingsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_document_process_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *EmbeddingsResponse) GetEmbeddings() []*Embedding {
+	if x != nil {
+		return x.Embeddings
+	}
+	return nil
+}
+
+func (x *EmbeddingsResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *EmbeddingsResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *EmbeddingsResponse) GetDimensions() int32 {
+	if x != nil {
+		return x.Dimensions
+	}
+	return 0
+}
+
+type Embedding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vector        []float32              `protobuf:"fixed32,1,rep,packed,name=vector,proto3" json:"vector,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Embedding) Reset() {
+	*x = Embedding{}
+	mi := &file_proto_document_process_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Embedding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Embedding) ProtoMessage() {}
+
+func (x *Embedding) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_document_process_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Embedding.ProtoReflect.Descriptor instead.
+func (*Embedding) Descriptor() ([]byte, []int) {
+	return file_proto_document_process_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Embedding) GetVector() []float32 {
+	if x != nil {
+		return x.Vector
+	}
+	return nil
+}
+
 var File_proto_document_process_proto protoreflect.FileDescriptor
 
 const file_proto_document_process_proto_rawDesc = "" +
 	"\n" +
-	"\x1cproto/document_process.proto\x12\bdocument\"\x8a\x01\n" +
+	"\x1cproto/document_process.proto\x12\bdocument\"\xe8\x01\n" +
 	"\x0eProcessRequest\x12\x18\n" +
 	"\acontent\x18\x01 \x01(\fR\acontent\x12\x1a\n" +
 	"\bfilename\x18\x02 \x01(\tR\bfilename\x12!\n" +
 	"\fcontent_type\x18\x03 \x01(\tR\vcontentType\x12\x1f\n" +
 	"\vdocument_id\x18\x04 \x01(\tR\n" +
-	"documentId\"\x92\x01\n" +
+	"documentId\x125\n" +
+	"\aoptions\x18\x05 \x01(\v2\x1b.document.ProcessingOptionsR\aoptions\x12%\n" +
+	"\x0eskip_embedding\x18\x06 \x01(\bR\rskipEmbedding\"\xac\x01\n" +
+	"\x11ProcessingOptions\x12%\n" +
+	"\x0echunk_strategy\x18\x01 \x01(\tR\rchunkStrategy\x12\x1d\n" +
+	"\n" +
+	"chunk_size\x18\x02 \x01(\x05R\tchunkSize\x12#\n" +
+	"\rchunk_overlap\x18\x03 \x01(\x05R\fchunkOverlap\x12\x1a\n" +
+	"\blanguage\x18\x04 \x01(\tR\blanguage\x12\x10\n" +
+	"\x03ocr\x18\x05 \x01(\bR\x03ocr\"\xc8\x01\n" +
 	"\x0fProcessResponse\x12\x1f\n" +
 	"\vdocument_id\x18\x01 \x01(\tR\n" +
 	"documentId\x12\x16\n" +
 	"\x06status\x18\x02 \x01(\tR\x06status\x12\x14\n" +
 	"\x05error\x18\x03 \x01(\tR\x05error\x120\n" +
-	"\x06chunks\x18\x04 \x03(\v2\x18.document.ProcessedChunkR\x06chunks\"<\n" +
+	"\x06chunks\x18\x04 \x03(\v2\x18.document.ProcessedChunkR\x06chunks\x12\x14\n" +
+	"\x05model\x18\x05 \x01(\tR\x05model\x12\x1e\n" +
+	"\n" +
+	"dimensions\x18\x06 \x01(\x05R\n" +
+	"dimensions\"<\n" +
 	"\x0eProcessedChunk\x12\x12\n" +
 	"\x04text\x18\x01 \x01(\tR\x04text\x12\x16\n" +
 	"\x06vector\x18\x02 \x03(\x02R\x06vector\"&\n" +
 	"\x10EmbeddingRequest\x12\x12\n" +
-	"\x04text\x18\x01 \x01(\tR\x04text\"A\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\"w\n" +
 	"\x11EmbeddingResponse\x12\x16\n" +
 	"\x06vector\x18\x01 \x03(\x02R\x06vector\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error2\xae\x01\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\x12\x1e\n" +
+	"\n" +
+	"dimensions\x18\x04 \x01(\x05R\n" +
+	"dimensions\"?\n" +
+	"\x11EmbeddingsRequest\x12\x14\n" +
+	"\x05texts\x18\x01 \x03(\tR\x05texts\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\"\x95\x01\n" +
+	"\x12EmbeddingsResponse\x123\n" +
+	"\n" +
+	"embeddings\x18\x01 \x03(\v2\x13.document.EmbeddingR\n" +
+	"embeddings\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\x12\x1e\n" +
+	"\n" +
+	"dimensions\x18\x04 \x01(\x05R\n" +
+	"dimensions\"#\n" +
+	"\tEmbedding\x12\x16\n" +
+	"\x06vector\x18\x01 \x03(\x02R\x06vector2\xfd\x01\n" +
 	"\x18DocumentProcessorService\x12F\n" +
 	"\x0fProcessDocument\x12\x18.document.ProcessRequest\x1a\x19.document.ProcessResponse\x12J\n" +
-	"\x0fCreateEmbedding\x12\x1a.document.EmbeddingRequest\x1a\x1b.document.EmbeddingResponseB4Z2github.com/ozgurnsahin/document-processor-pp/protob\x06proto3"
+	"\x0fCreateEmbedding\x12\x1a.document.EmbeddingRequest\x1a\x1b.document.EmbeddingResponse\x12M\n" +
+	"\x10CreateEmbeddings\x12\x1b.document.EmbeddingsRequest\x1a\x1c.document.EmbeddingsResponseB4Z2github.com/ozgurnsahin/document-processor-pp/protob\x06proto3"
 
 var (
 	file_proto_document_process_proto_rawDescOnce sync.Once
@@ -346,25 +666,33 @@ func file_proto_document_process_proto_rawDescGZIP() []byte {
 	return file_proto_document_process_proto_rawDescData
 }
 
-var file_proto_document_process_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_proto_document_process_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
 var file_proto_document_process_proto_goTypes = []any{
-	(*ProcessRequest)(nil),    // 0: document.ProcessRequest
-	(*ProcessResponse)(nil),   // 1: document.ProcessResponse
-	(*ProcessedChunk)(nil),    // 2: document.ProcessedChunk
-	(*EmbeddingRequest)(nil),  // 3: document.EmbeddingRequest
-	(*EmbeddingResponse)(nil), // 4: document.EmbeddingResponse
+	(*ProcessRequest)(nil),     // 0: document.ProcessRequest
+	(*ProcessingOptions)(nil),  // 1: document.ProcessingOptions
+	(*ProcessResponse)(nil),    // 2: document.ProcessResponse
+	(*ProcessedChunk)(nil),     // 3: document.ProcessedChunk
+	(*EmbeddingRequest)(nil),   // 4: document.EmbeddingRequest
+	(*EmbeddingResponse)(nil),  // 5: document.EmbeddingResponse
+	(*EmbeddingsRequest)(nil),  // 6: document.EmbeddingsRequest
+	(*EmbeddingsResponse)(nil), // 7: document.EmbeddingsResponse
+	(*Embedding)(nil),          // 8: document.Embedding
 }
 var file_proto_document_process_proto_depIdxs = []int32{
-	2, // 0: document.ProcessResponse.chunks:type_name -> document.ProcessedChunk
-	0, // 1: document.DocumentProcessorService.ProcessDocument:input_type -> document.ProcessRequest
-	3, // 2: document.DocumentProcessorService.CreateEmbedding:input_type -> document.EmbeddingRequest
-	1, // 3: document.DocumentProcessorService.ProcessDocument:output_type -> document.ProcessResponse
-	4, // 4: document.DocumentProcessorService.CreateEmbedding:output_type -> document.EmbeddingResponse
-	3, // [3:5] is the sub-list for method output_type
-	1, // [1:3] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	1, // 0: document.ProcessRequest.options:type_name -> document.ProcessingOptions
+	3, // 1: document.ProcessResponse.chunks:type_name -> document.ProcessedChunk
+	8, // 2: document.EmbeddingsResponse.embeddings:type_name -> document.Embedding
+	0, // 3: document.DocumentProcessorService.ProcessDocument:input_type -> document.ProcessRequest
+	4, // 4: document.DocumentProcessorService.CreateEmbedding:input_type -> document.EmbeddingRequest
+	6, // 5: document.DocumentProcessorService.CreateEmbeddings:input_type -> document.EmbeddingsRequest
+	2, // 6: document.DocumentProcessorService.ProcessDocument:output_type -> document.ProcessResponse
+	5, // 7: document.DocumentProcessorService.CreateEmbedding:output_type -> document.EmbeddingResponse
+	7, // 8: document.DocumentProcessorService.CreateEmbeddings:output_type -> document.EmbeddingsResponse
+	6, // [6:9] is the sub-list for method output_type
+	3, // [3:6] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_proto_document_process_proto_init() }
@@ -378,7 +706,7 @@ func file_proto_document_process_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_document_process_proto_rawDesc), len(file_proto_document_process_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   9,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,8 +19,9 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	DocumentProcessorService_ProcessDocument_FullMethodName = "/document.DocumentProcessorService/ProcessDocument"
-	DocumentProcessorService_CreateEmbedding_FullMethodName = "/document.DocumentProcessorService/CreateEmbedding"
+	DocumentProcessorService_ProcessDocument_FullMethodName  = "/document.DocumentProcessorService/ProcessDocument"
+	DocumentProcessorService_CreateEmbedding_FullMethodName  = "/document.DocumentProcessorService/CreateEmbedding"
+	DocumentProcessorService_CreateEmbeddings_FullMethodName = "/document.DocumentProcessorService/CreateEmbeddings"
 )
 
 // DocumentProcessorServiceClient is the client API for DocumentProcessorService service.
@@ -29,6 +30,7 @@ const (
 type DocumentProcessorServiceClient interface {
 	ProcessDocument(ctx context.Context, in *ProcessRequest, opts ...grpc.CallOption) (*ProcessResponse, error)
 	CreateEmbedding(ctx context.Context, in *EmbeddingRequest, opts ...grpc.CallOption) (*EmbeddingResponse, error)
+	CreateEmbeddings(ctx context.Context, in *EmbeddingsRequest, opts ...grpc.CallOption) (*EmbeddingsResponse, error)
 }
 
 type documentProcessorServiceClient struct {
@@ -59,12 +61,23 @@ func (c *documentProcessorServiceClient) CreateEmbedding(ctx context.Context, in
 	return out, nil
 }
 
+func (c *documentProcessorServiceClient) CreateEmbeddings(ctx context.Context, in *EmbeddingsRequest, opts ...grpc.CallOption) (*EmbeddingsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EmbeddingsResponse)
+	err := c.cc.Invoke(ctx, DocumentProcessorService_CreateEmbeddings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DocumentProcessorServiceServer is the server API for DocumentProcessorService service.
 // All implementations must embed UnimplementedDocumentProcessorServiceServer
 // for forward compatibility.
 type DocumentProcessorServiceServer interface {
 	ProcessDocument(context.Context, *ProcessRequest) (*ProcessResponse, error)
 	CreateEmbedding(context.Context, *EmbeddingRequest) (*EmbeddingResponse, error)
+	CreateEmbeddings(context.Context, *EmbeddingsRequest) (*EmbeddingsResponse, error)
 	mustEmbedUnimplementedDocumentProcessorServiceServer()
 }
 
@@ -81,6 +94,9 @@ func (UnimplementedDocumentProcessorServiceServer) ProcessDocument(context.Conte
 func (UnimplementedDocumentProcessorServiceServer) CreateEmbedding(context.Context, *EmbeddingRequest) (*EmbeddingResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateEmbedding not implemented")
 }
+func (UnimplementedDocumentProcessorServiceServer) CreateEmbeddings(context.Context, *EmbeddingsRequest) (*EmbeddingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateEmbeddings not implemented")
+}
 func (UnimplementedDocumentProcessorServiceServer) mustEmbedUnimplementedDocumentProcessorServiceServer() {
 }
 func (UnimplementedDocumentProcessorServiceServer) testEmbeddedByValue() {}
@@ -139,6 +155,24 @@ func _DocumentProcessorService_CreateEmbedding_Handler(srv interface{}, ctx cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DocumentProcessorService_CreateEmbeddings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmbeddingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DocumentProcessorServiceServer).CreateEmbeddings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DocumentProcessorService_CreateEmbeddings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DocumentProcessorServiceServer).CreateEmbeddings(ctx, req.(*EmbeddingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DocumentProcessorService_ServiceDesc is the grpc.ServiceDesc for DocumentProcessorService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -154,6 +188,10 @@ var DocumentProcessorService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CreateEmbedding",
 			Handler:    _DocumentProcessorService_CreateEmbedding_Handler,
 		},
+		{
+			MethodName: "CreateEmbeddings",
+			Handler:    _DocumentProcessorService_CreateEmbeddings_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/document_process.proto",
@@ -0,0 +1,223 @@
+package reader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
+)
+
+const (
+	maxArchiveEntries     = 1000
+	maxArchiveTotalSize   = 200 * 1024 * 1024 // 200MB
+	maxArchiveEntrySize   = 20 * 1024 * 1024  // 20MB
+	maxCompressionRatio   = 100
+	compressionRatioFloor = 1024 * 1024 // tiny entries compress well and are harmless
+)
+
+var errArchiveBudget = errors.New("archive exceeds the total uncompressed size limit")
+
+// archiveWalker tracks the limits shared by every entry of one archive so a
+// bomb is stopped no matter how its entries are spread out.
+type archiveWalker struct {
+	doc     *models.Document
+	entries int
+	total   int64
+}
+
+func preprocessArchive(doc *models.Document) error {
+	walker := &archiveWalker{doc: doc}
+
+	// Entries are read straight from the spooled upload
+	content, err := doc.Content.Open()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	head := doc.Content.Head()
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")) || bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		err = walker.walkZip(content, doc.Content.Size())
+	case bytes.HasPrefix(head, []byte("\x1f\x8b")):
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(content)
+		if err == nil {
+			err = walker.walkTar(gz)
+			gz.Close()
+		}
+	default:
+		err = walker.walkTar(content)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(doc.Children) == 0 {
+		return fmt.Errorf("archive contains no supported documents")
+	}
+
+	setMetadata(doc, "entries", fmt.Sprint(walker.entries))
+	doc.Kind = models.KindCollection
+	return replaceContent(doc, nil)
+}
+
+func (a *archiveWalker) walkZip(content io.ReaderAt, size int64) error {
+	archive, err := zip.NewReader(content, size)
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %w", err)
+	}
+
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if err := a.countEntry(); err != nil {
+			return err
+		}
+
+		name, ok := a.entryName(f.Name)
+		if !ok || !f.Mode().IsRegular() {
+			if ok {
+				a.reject(name, "only regular files are ingested")
+			}
+			continue
+		}
+
+		size := int64(f.UncompressedSize64)
+		if f.CompressedSize64 > 0 && size > compressionRatioFloor && f.UncompressedSize64/f.CompressedSize64 > maxCompressionRatio {
+			a.reject(name, "suspicious compression ratio")
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			a.reject(name, "failed to open entry: "+err.Error())
+			continue
+		}
+		err = a.readEntry(name, rc, size)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *archiveWalker) walkTar(r io.Reader) error {
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		if err := a.countEntry(); err != nil {
+			return err
+		}
+
+		name, ok := a.entryName(header.Name)
+		if !ok {
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			a.reject(name, "only regular files are ingested")
+			continue
+		}
+
+		if err := a.readEntry(name, archive, header.Size); err != nil {
+			return err
+		}
+	}
+}
+
+func (a *archiveWalker) countEntry() error {
+	a.entries++
+	if a.entries > maxArchiveEntries {
+		return fmt.Errorf("archive has more than %d entries", maxArchiveEntries)
+	}
+	return nil
+}
+
+// entryName cleans the stored path and refuses anything that would resolve
+// outside the archive root. Metadata files added by OS tools are skipped
+// silently.
+func (a *archiveWalker) entryName(raw string) (string, bool) {
+	name := strings.ReplaceAll(raw, "\\", "/")
+	base := path.Base(name)
+	if strings.HasPrefix(name, "__MACOSX/") || base == ".DS_Store" || base == "Thumbs.db" {
+		return "", false
+	}
+
+	cleaned := path.Clean(name)
+	if path.IsAbs(name) || cleaned == ".." || strings.HasPrefix(cleaned, "../") || strings.Contains(name, "\x00") || (len(name) > 1 && name[1] == ':') {
+		a.reject(raw, "unsafe path")
+		return "", false
+	}
+
+	return cleaned, true
+}
+
+// readEntry reads an entry and adds it as a child. Problems with the entry
+// reject it, the errors returned stop the whole archive.
+func (a *archiveWalker) readEntry(name string, r io.Reader, declaredSize int64) error {
+	if declaredSize > maxArchiveEntrySize {
+		a.reject(name, fmt.Sprintf("entry exceeds the %d byte limit", maxArchiveEntrySize))
+		return nil
+	}
+	if a.total+declaredSize > maxArchiveTotalSize {
+		return errArchiveBudget
+	}
+
+	// The entry is held in memory until it is spooled and pre-processed
+	release, err := spool.Reserve(declaredSize)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Declared sizes can lie, so never read past them
+	content, err := io.ReadAll(io.LimitReader(r, declaredSize+1))
+	if err != nil {
+		a.reject(name, fmt.Sprintf("failed to read entry: %v", err))
+		return nil
+	}
+	if int64(len(content)) > declaredSize {
+		a.reject(name, "entry is larger than its declared size")
+		return nil
+	}
+
+	a.total += int64(len(content))
+	a.addEntry(name, content)
+	return nil
+}
+
+func (a *archiveWalker) addEntry(name string, content []byte) {
+	child, err := FileReader(content, name, a.doc)
+	if err != nil {
+		a.doc.Rejected = append(a.doc.Rejected, rejectionsFor(name, err)...)
+		return
+	}
+
+	setMetadata(child, "archive_path", name)
+	child.FileName = path.Base(name)
+	a.doc.Children = append(a.doc.Children, child)
+}
+
+func (a *archiveWalker) reject(name, reason string) {
+	a.doc.Rejected = append(a.doc.Rejected, models.Rejection{FileName: name, Reason: reason})
+}
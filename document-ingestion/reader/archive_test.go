@@ -0,0 +1,163 @@
+package reader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
+)
+
+const entryText = "Quarterly numbers for the archive test."
+
+// Entry names that would resolve outside the archive root
+var unsafeNames = []string{"../escape.txt", "docs/../../escape.txt", "/etc/passwd", "C:\\Windows\\win.ini", "C:evil.txt", "..\\escape.txt", "nul\x00byte.txt"}
+
+func readArchive(t *testing.T, content []byte, filename string) *models.Document {
+	t.Helper()
+	file, err := spool.FromBytes(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ReadSpooled(file, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { discardContent(doc) })
+	return doc
+}
+
+// rejectedNames maps the rejected entries to their reasons.
+func rejectedNames(doc *models.Document) map[string]string {
+	rejected := make(map[string]string)
+	for _, rejection := range doc.Rejected {
+		rejected[rejection.FileName] = rejection.Reason
+	}
+	return rejected
+}
+
+func childNames(doc *models.Document) string {
+	var names []string
+	for _, child := range doc.Children {
+		names = append(names, child.Metadata["archive_path"])
+	}
+	sort.Strings(names)
+	return strings.Join(names, "|")
+}
+
+func TestArchiveZip(t *testing.T) {
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, name := range append([]string{"docs/report.txt"}, unsafeNames...) {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(entryText))
+	}
+
+	// Headers are written as they are, the entries are refused by their
+	// declared sizes before any data is read
+	raw := []struct {
+		name             string
+		method           uint16
+		size, compressed uint64
+	}{
+		{"bomb.txt", zip.Deflate, 500 * 1024 * 1024, 1024 * 1024},
+		{"large.txt", zip.Store, maxArchiveEntrySize + 1, maxArchiveEntrySize + 1},
+	}
+	for _, entry := range raw {
+		f, err := w.CreateRaw(&zip.FileHeader{
+			Name:               entry.name,
+			Method:             entry.method,
+			UncompressedSize64: entry.size,
+			CompressedSize64:   entry.compressed,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte("data"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := readArchive(t, b.Bytes(), "bundle.zip")
+	if names := childNames(doc); names != "docs/report.txt" {
+		t.Errorf("got entries %q", names)
+	}
+
+	rejected := rejectedNames(doc)
+	for _, name := range unsafeNames {
+		if rejected[name] != "unsafe path" {
+			t.Errorf("%q rejected with %q, want an unsafe path", name, rejected[name])
+		}
+	}
+	if !strings.Contains(rejected["bomb.txt"], "compression ratio") {
+		t.Errorf("bomb rejected with %q", rejected["bomb.txt"])
+	}
+	if !strings.Contains(rejected["large.txt"], "byte limit") {
+		t.Errorf("large entry rejected with %q", rejected["large.txt"])
+	}
+}
+
+func TestArchiveTar(t *testing.T) {
+	var b bytes.Buffer
+	w := tar.NewWriter(&b)
+	// Tar ends names at a NUL byte, there is no way to store one
+	names := append([]string{"./docs/report.txt"}, unsafeNames[:len(unsafeNames)-1]...)
+	for _, name := range names {
+		if err := w.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(entryText)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("%q: %v", name, err)
+		}
+		w.Write([]byte(entryText))
+	}
+	if err := w.WriteHeader(&tar.Header{Name: "link.txt", Linkname: "/etc/passwd", Typeflag: tar.TypeSymlink}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := readArchive(t, b.Bytes(), "bundle.tar")
+	if names := childNames(doc); names != "docs/report.txt" {
+		t.Errorf("got entries %q", names)
+	}
+
+	rejected := rejectedNames(doc)
+	for _, name := range names[1:] {
+		if rejected[name] != "unsafe path" {
+			t.Errorf("%q rejected with %q, want an unsafe path", name, rejected[name])
+		}
+	}
+	if rejected["link.txt"] != "only regular files are ingested" {
+		t.Errorf("symlink rejected with %q", rejected["link.txt"])
+	}
+}
+
+func TestArchiveTotalBudget(t *testing.T) {
+	var b bytes.Buffer
+	w := tar.NewWriter(&b)
+	for _, name := range []string{"first.txt", "second.txt"} {
+		w.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(entryText)), Typeflag: tar.TypeReg})
+		w.Write([]byte(entryText))
+	}
+	w.Close()
+
+	// The entries before took all but a little of the budget
+	doc := &models.Document{FileName: "bundle.tar", Format: "archive"}
+	walker := &archiveWalker{doc: doc, total: maxArchiveTotalSize - int64(len(entryText)) - 1}
+	if err := walker.walkTar(&b); !errors.Is(err, errArchiveBudget) {
+		t.Fatalf("got %v, want %v", err, errArchiveBudget)
+	}
+	defer discardContent(doc)
+
+	if len(doc.Children) != 1 || doc.Children[0].FileName != "first.txt" {
+		t.Errorf("got %d entries before the budget ran out", len(doc.Children))
+	}
+}
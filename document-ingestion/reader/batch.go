@@ -0,0 +1,258 @@
+package reader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/processor"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/storage"
+)
+
+// BatchQueue limits how many batch documents are processed at once. Every
+// document needs one of the shared slots and a batch never holds more than
+// perBatch of them, so a large batch can't starve the others.
+type BatchQueue struct {
+	slots    chan struct{}
+	perBatch int
+	maxFiles int
+	maxBytes int64
+}
+
+func NewBatchQueue() *BatchQueue {
+	workers := max(config.Int("BATCH_WORKERS", 4), 1)
+
+	return &BatchQueue{
+		slots:    make(chan struct{}, workers),
+		perBatch: min(max(config.Int("BATCH_PARALLELISM", 2), 1), workers),
+		maxFiles: config.Int("BATCH_MAX_FILES", 100),
+		maxBytes: config.Int64("BATCH_MAX_BYTES", 200*1024*1024), // 200MB
+	}
+}
+
+func HandleBatchUpload(w http.ResponseWriter, r *http.Request, client processor.Processor, mongodb *storage.MongoDB, queue *BatchQueue) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	options, err := processingOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, "Invalid processing options: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, queue.maxBytes)
+
+	uploads, err := readBatchFiles(r, queue.maxFiles)
+	if err != nil {
+		http.Error(w, "Error reading batch: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(uploads) == 0 {
+		http.Error(w, "Batch contains no files", http.StatusBadRequest)
+		return
+	}
+
+	batch := &models.Batch{
+		ID:        uuid.New().String(),
+		Status:    models.StatusProcessing,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Items:     make([]models.BatchItem, len(uploads)),
+	}
+
+	// Files are validated up front so rejections are in the first response
+	docs := make([]*models.Document, len(uploads))
+	for i, upload := range uploads {
+		item := models.BatchItem{FileName: upload.filename, Status: models.StatusReceived}
+
+		doc, err := ReadSpooled(upload.file, upload.filename)
+		if err != nil {
+			item.Status = models.StatusRejected
+			item.Error = err.Error()
+			var invalid *ValidationError
+			if errors.As(err, &invalid) {
+				item.Rejections = invalid.Rejections
+			}
+			if quarantined := recordQuarantine(err, mongodb); quarantined != nil {
+				item.Status = quarantined.Status
+				item.DocumentID = quarantined.ID
+			}
+		} else {
+			doc.ID = uuid.New().String()
+			doc.Processing = options
+			item.DocumentID = doc.ID
+			docs[i] = doc
+		}
+
+		batch.Items[i] = item
+	}
+
+	if err := mongodb.InsertBatch(batch); err != nil {
+		for _, doc := range docs {
+			if doc != nil {
+				discardContent(doc)
+			}
+		}
+		http.Error(w, "Error saving batch: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	go queue.run(batch, docs, client, mongodb)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(batchSummary(batch))
+}
+
+// readBatchFiles accepts either a multipart form with any number of file
+// fields or a JSON manifest of base64 encoded files.
+func readBatchFiles(r *http.Request, maxFiles int) ([]spooledUpload, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		return spoolFormFiles(r, "", maxFiles)
+	}
+
+	return readManifest(r.Body, maxFiles)
+}
+
+func (q *BatchQueue) run(batch *models.Batch, docs []*models.Document, client processor.Processor, mongodb *storage.MongoDB) {
+	var (
+		wg      sync.WaitGroup
+		inBatch = make(chan struct{}, q.perBatch)
+		// The upload response still reads batch
+		items = append([]models.BatchItem(nil), batch.Items...)
+	)
+
+	for i, doc := range docs {
+		if doc == nil {
+			continue
+		}
+
+		inBatch <- struct{}{}
+		q.slots <- struct{}{}
+		wg.Add(1)
+
+		go func(i int, doc *models.Document) {
+			defer func() {
+				<-q.slots
+				<-inBatch
+				wg.Done()
+			}()
+
+			item := items[i]
+			item.Status = models.StatusProcessing
+			if err := mongodb.UpdateBatchItem(batch.ID, i, item); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+
+			item.Status = models.StatusCompleted
+			if err := IngestDocument(doc, client, mongodb); err != nil {
+				// The document may not have been saved, so its status
+				// can still read processing
+				item.Status = models.StatusFailed
+				item.Error = err.Error()
+			}
+
+			if err := mongodb.UpdateBatchItem(batch.ID, i, item); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			items[i] = item
+		}(i, doc)
+	}
+
+	wg.Wait()
+
+	status := batchResult(items)
+	if err := mongodb.UpdateBatchStatus(batch.ID, status); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	fmt.Printf("Batch %s finished: %s\n", batch.ID, status)
+}
+
+// batchResult is the final status of a batch whose items are all done.
+func batchResult(items []models.BatchItem) string {
+	completed := 0
+	for _, item := range items {
+		if item.Status == models.StatusCompleted {
+			completed++
+		}
+	}
+
+	switch completed {
+	case len(items):
+		return models.StatusCompleted
+	case 0:
+		return models.StatusFailed
+	}
+	return models.StatusCompletedWithErrors
+}
+
+func HandleGetBatch(w http.ResponseWriter, r *http.Request, mongodb *storage.MongoDB) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	batchID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/batches/"), "/")
+	if batchID == "" {
+		http.Error(w, "Batch id is required", http.StatusBadRequest)
+		return
+	}
+
+	batch, err := mongodb.GetBatch(batchID)
+	if err != nil {
+		http.Error(w, "Error reading batch: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if batch == nil {
+		http.Error(w, "Batch not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batchSummary(batch))
+}
+
+func batchSummary(batch *models.Batch) map[string]interface{} {
+	counts := map[string]int{
+		models.StatusReceived:    0,
+		models.StatusProcessing:  0,
+		models.StatusCompleted:   0,
+		models.StatusFailed:      0,
+		models.StatusRejected:    0,
+		models.StatusQuarantined: 0,
+	}
+	for _, item := range batch.Items {
+		counts[item.Status]++
+	}
+
+	total := len(batch.Items)
+	done := counts[models.StatusCompleted] + counts[models.StatusFailed] + counts[models.StatusRejected] + counts[models.StatusQuarantined]
+	progress := 100.0
+	if total > 0 {
+		progress = float64(done) * 100 / float64(total)
+	}
+
+	return map[string]interface{}{
+		"batch_id":   batch.ID,
+		"status":     batch.Status,
+		"created_at": batch.CreatedAt,
+		"updated_at": batch.UpdatedAt,
+		"total":      total,
+		"counts":     counts,
+		"progress":   progress,
+		"items":      batch.Items,
+	}
+}
@@ -0,0 +1,27 @@
+package reader
+
+import (
+	"testing"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+)
+
+func TestBatchResult(t *testing.T) {
+	for _, test := range []struct {
+		statuses []string
+		want     string
+	}{
+		{[]string{models.StatusCompleted, models.StatusCompleted}, models.StatusCompleted},
+		{[]string{models.StatusCompleted, models.StatusFailed}, models.StatusCompletedWithErrors},
+		{[]string{models.StatusRejected, models.StatusCompleted, models.StatusQuarantined}, models.StatusCompletedWithErrors},
+		{[]string{models.StatusFailed, models.StatusRejected, models.StatusQuarantined}, models.StatusFailed},
+	} {
+		items := make([]models.BatchItem, len(test.statuses))
+		for i, status := range test.statuses {
+			items[i].Status = status
+		}
+		if got := batchResult(items); got != test.want {
+			t.Errorf("%v: got %s, want %s", test.statuses, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package reader
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/unicode/norm"
+)
+
+var byteOrderMarks = []struct {
+	mark    []byte
+	charset string
+}{
+	{[]byte("\xef\xbb\xbf"), "utf-8"},
+	{[]byte("\xff\xfe"), "utf-16le"},
+	{[]byte("\xfe\xff"), "utf-16be"},
+}
+
+// decodeText transcodes text to UTF-8 with LF line endings and NFC
+// normalization, so the processor always gets the same bytes for the same text.
+func decodeText(content []byte, contentType string) ([]byte, error) {
+	text, _, err := transcodeText(content, contentType)
+	return text, err
+}
+
+// transcodeText is decodeText that also reports the charset the content was
+// read as.
+func transcodeText(content []byte, contentType string) ([]byte, string, error) {
+	name, content := detectCharset(content, contentType)
+
+	encoding, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, "", fmt.Errorf("unsupported charset: %s", name)
+	}
+
+	text := content
+	if canonical, _ := htmlindex.Name(encoding); canonical != "utf-8" {
+		text, err = encoding.NewDecoder().Bytes(content)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decode %s text: %w", name, err)
+		}
+	} else if !utf8.Valid(text) {
+		return nil, "", fmt.Errorf("content is not valid utf-8")
+	}
+
+	return normalizeText(text), name, nil
+}
+
+// detectCharset picks the charset from a byte order mark, then the declared
+// charset, then an HTML meta tag, and finally guesses from the bytes. The
+// byte order mark is removed from the returned content.
+func detectCharset(content []byte, contentType string) (string, []byte) {
+	for _, bom := range byteOrderMarks {
+		if bytes.HasPrefix(content, bom.mark) {
+			return bom.charset, content[len(bom.mark):]
+		}
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	declared := strings.ToLower(strings.TrimSpace(params["charset"]))
+
+	// Detection on the head of a file can call it UTF-8 while the rest isn't
+	unicodeLabel := declared == "" || declared == "utf-8" || declared == "utf8" || declared == "us-ascii" || declared == "ascii"
+	if !unicodeLabel {
+		return declared, content
+	}
+
+	if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		if _, name, certain := charset.DetermineEncoding(content, mediaType); certain {
+			return name, content
+		}
+	}
+
+	// Zero bytes are valid UTF-8, so UTF-16 has to be ruled out first
+	if name := guessUTF16(content); name != "" {
+		return name, content
+	}
+	if utf8.Valid(content) {
+		return "utf-8", content
+	}
+
+	// Single byte text that isn't UTF-8 is almost always Windows-1252, which
+	// also decodes ISO-8859-1 correctly
+	return "windows-1252", content
+}
+
+// guessUTF16 recognises UTF-16 without a byte order mark by its zero bytes,
+// mostly ASCII text leaves one in every other position.
+func guessUTF16(content []byte) string {
+	sample := content[:min(len(content), 4096)]
+	if len(sample) < 4 {
+		return ""
+	}
+
+	var even, odd int
+	for i, b := range sample {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			even++
+		} else {
+			odd++
+		}
+	}
+
+	half := len(sample) / 2
+	switch {
+	case odd > half*3/10 && even <= half/20:
+		return "utf-16le"
+	case even > half*3/10 && odd <= half/20:
+		return "utf-16be"
+	}
+	return ""
+}
+
+func normalizeText(text []byte) []byte {
+	text = bytes.TrimPrefix(text, []byte("\ufeff"))
+	text = bytes.ReplaceAll(text, []byte("\r\n"), []byte("\n"))
+	text = bytes.ReplaceAll(text, []byte("\r"), []byte("\n"))
+	return norm.NFC.Bytes(text)
+}
@@ -0,0 +1,362 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"unicode"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
+)
+
+const (
+	maxMailboxMessages = 10000
+	maxAttachments     = 100
+)
+
+var mailHeaders = []string{"from:", "to:", "cc:", "subject:", "date:", "message-id:", "received:", "mime-version:", "return-path:", "delivered-to:"}
+
+var headerDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+type mailBody struct {
+	parent      *models.Document
+	plain       []string
+	html        []string
+	attachments []*models.Document
+	rejected    []models.Rejection
+}
+
+// sniffEmail only recognizes messages by fields that mail servers add, notes
+// starting with To: or Subject: lines stay text. Other messages are found by
+// their .eml extension.
+func sniffEmail(content []byte) bool {
+	seen := mailHeaderFields(content)
+	return len(seen) >= 2 && (seen["received:"] || seen["message-id:"])
+}
+
+func sniffMailbox(content []byte) bool {
+	if !bytes.HasPrefix(content, []byte("From ")) {
+		return false
+	}
+	_, rest, _ := bytes.Cut(content, []byte("\n"))
+	return len(mailHeaderFields(rest)) >= 2
+}
+
+// mailHeaderFields looks at the leading header block and returns the well known
+// RFC 822 fields it has, any line that isn't a header ends the block.
+func mailHeaderFields(content []byte) map[string]bool {
+	scanner := bufio.NewScanner(bytes.NewReader(content[:min(len(content), 8192)]))
+	seen := make(map[string]bool)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		name, _, ok := strings.Cut(line, ":")
+		if !ok || strings.ContainsAny(name, " \t") {
+			return nil
+		}
+		lower := strings.ToLower(name) + ":"
+		for _, h := range mailHeaders {
+			if lower == h {
+				seen[h] = true
+			}
+		}
+	}
+	return seen
+}
+
+// preprocessEmail turns an RFC 822 message into its text body with the headers
+// as metadata, attachments become child documents.
+func preprocessEmail(doc *models.Document) error {
+	content, err := doc.Content.Open()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	msg, err := mail.ReadMessage(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse email: %w", err)
+	}
+
+	for _, key := range []string{"From", "To", "Cc", "Subject", "Date", "Message-Id"} {
+		setMetadata(doc, strings.ToLower(strings.ReplaceAll(key, "-", "_")), decodeHeader(msg.Header.Get(key)))
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		setMetadata(doc, "date", date.UTC().Format("2006-01-02T15:04:05Z"))
+	}
+
+	body := &mailBody{parent: doc}
+	if err := walkMailPart(msg.Header, msg.Body, body); err != nil {
+		return err
+	}
+
+	text := strings.Join(body.plain, "\n\n")
+	if strings.TrimSpace(text) == "" && len(body.html) > 0 {
+		var converted []string
+		for _, page := range body.html {
+			if markdown, _, err := convertHTML([]byte(page)); err == nil {
+				converted = append(converted, markdown)
+			}
+		}
+		text = strings.Join(converted, "\n\n")
+	}
+
+	var out strings.Builder
+	for _, field := range []struct{ label, key string }{{"Subject", "subject"}, {"From", "from"}, {"To", "to"}, {"Date", "date"}} {
+		if value := doc.Metadata[field.key]; value != "" {
+			fmt.Fprintf(&out, "%s: %s\n", field.label, value)
+		}
+	}
+	out.WriteString("\n")
+	out.WriteString(strings.TrimSpace(text))
+
+	doc.ContentType = "text/plain; charset=utf-8"
+	doc.Children = append(doc.Children, body.attachments...)
+	doc.Rejected = append(doc.Rejected, body.rejected...)
+	return replaceContent(doc, []byte(out.String()))
+}
+
+// preprocessMailbox splits an mbox archive into one child document per message.
+func preprocessMailbox(doc *models.Document) error {
+	content, err := doc.Content.Bytes()
+	if err != nil {
+		return err
+	}
+
+	messages := splitMailbox(content)
+	if len(messages) == 0 {
+		return fmt.Errorf("no messages found in mailbox")
+	}
+	if len(messages) > maxMailboxMessages {
+		return fmt.Errorf("mailbox has %d messages, the limit is %d", len(messages), maxMailboxMessages)
+	}
+
+	// Messages are read like attachments, validators included, only their
+	// format is known
+	email := formatNamed("email")
+	if email == nil {
+		return fmt.Errorf("email format is not registered")
+	}
+	if doc.Depth >= maxNestingDepth {
+		return fmt.Errorf("nested more than %d levels deep", maxNestingDepth)
+	}
+
+	for i, raw := range messages {
+		file, err := spool.FromBytes(raw)
+		if err != nil {
+			return err
+		}
+
+		message := &models.Document{
+			FileName:    messageName(raw, fmt.Sprintf("%s#%d.eml", doc.FileName, i+1)),
+			Content:     file,
+			ContentType: "message/rfc822",
+			Format:      "email",
+			Kind:        models.KindDocument,
+			Size:        file.Size(),
+			SHA256:      file.SHA256(),
+			Status:      models.StatusReceived,
+			InArchive:   doc.InArchive,
+			Depth:       doc.Depth + 1,
+		}
+		if err := prepareDocument(message, email); err != nil {
+			discardContent(message)
+			doc.Rejected = append(doc.Rejected, rejectionsFor(message.FileName, err)...)
+			continue
+		}
+		doc.Children = append(doc.Children, message)
+	}
+
+	setMetadata(doc, "messages", fmt.Sprint(len(doc.Children)))
+	doc.Kind = models.KindCollection
+	return replaceContent(doc, nil)
+}
+
+// messageName names a message of a mailbox after its subject. Subjects
+// without a letter or digit would leave nothing once the filename validator
+// cleaned them up.
+func messageName(raw []byte, fallback string) string {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return fallback
+	}
+	subject := decodeHeader(msg.Header.Get("Subject"))
+	if !strings.ContainsFunc(subject, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+		return fallback
+	}
+	return subject
+}
+
+func splitMailbox(content []byte) [][]byte {
+	var (
+		messages [][]byte
+		current  bytes.Buffer
+		started  bool
+		blank    = true
+	)
+
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	for _, line := range lines {
+		if blank && bytes.HasPrefix(line, []byte("From ")) {
+			if started && current.Len() > 0 {
+				messages = append(messages, bytes.Clone(current.Bytes()))
+			}
+			current.Reset()
+			started = true
+			blank = false
+			continue
+		}
+
+		// mboxrd escapes body lines starting with "From " as ">From "
+		if bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From ")) && line[0] == '>' {
+			line = line[1:]
+		}
+		current.Write(line)
+		blank = len(bytes.TrimSpace(line)) == 0
+	}
+	if started && current.Len() > 0 {
+		messages = append(messages, bytes.Clone(current.Bytes()))
+	}
+
+	return messages
+}
+
+func walkMailPart(header mail.Header, body io.Reader, out *mailBody) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{"charset": "us-ascii"}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read email part: %w", err)
+			}
+			if err := walkMailPart(mail.Header(part.Header), part, out); err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := io.ReadAll(transferDecoder(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("failed to decode email part: %w", err)
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dispositionParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+
+	isAttachment := disposition == "attachment" || filename != ""
+	switch {
+	case isAttachment || mediaType == "message/rfc822":
+		if filename == "" {
+			filename = fmt.Sprintf("attachment-%d", len(out.attachments)+1)
+		}
+		addAttachment(out, content, filename)
+	case mediaType == "text/plain":
+		out.plain = append(out.plain, partText(content, params["charset"]))
+	case mediaType == "text/html":
+		out.html = append(out.html, partText(content, params["charset"]))
+	}
+
+	return nil
+}
+
+func addAttachment(out *mailBody, content []byte, filename string) {
+	if len(out.attachments) >= maxAttachments {
+		out.rejected = append(out.rejected, models.Rejection{FileName: filename, Reason: "attachment limit reached"})
+		return
+	}
+
+	child, err := FileReader(content, filename, out.parent)
+	if err != nil {
+		out.rejected = append(out.rejected, rejectionsFor(filename, err)...)
+		return
+	}
+	out.attachments = append(out.attachments, child)
+}
+
+func transferDecoder(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// partText decodes a text part, undecodable bytes are dropped rather than
+// failing the whole message
+func partText(content []byte, charset string) string {
+	contentType := "text/plain"
+	if charset != "" {
+		contentType += "; charset=" + charset
+	}
+
+	text, err := decodeText(content, contentType)
+	if err != nil {
+		return strings.ToValidUTF8(string(content), "")
+	}
+	return string(text)
+}
+
+func decodeHeader(value string) string {
+	decoded, err := headerDecoder.DecodeHeader(value)
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(decoded)
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(partText(content, charset)), nil
+}
+
+// newlineStripper drops line breaks so base64 bodies wrapped at 76 columns
+// can be decoded
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (n *newlineStripper) Read(p []byte) (int, error) {
+	for {
+		count, err := n.r.Read(p)
+		kept := 0
+		for _, b := range p[:count] {
+			if b != '\r' && b != '\n' {
+				p[kept] = b
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package reader
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
+)
+
+func message(subject, body string) string {
+	return fmt.Sprintf("From: a@example.com\r\nTo: b@example.com\r\nSubject: %s\r\nMessage-Id: <%d@example.com>\r\n\r\n%s\r\n", subject, len(body), body)
+}
+
+func TestMailboxMessages(t *testing.T) {
+	mailbox := "From a@example.com Mon Oct 5 10:00:00 2026\n" + message("Quarterly report", "The numbers are in.") +
+		"\nFrom a@example.com Mon Oct 5 10:01:00 2026\n" + message("../../etc/passwd\x07", "Path in the subject.") +
+		"\nFrom a@example.com Mon Oct 5 10:02:00 2026\nnot a header line\n\nbody\n"
+
+	file, err := spool.FromBytes([]byte(mailbox))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ReadSpooled(file, "archive.mbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer discardContent(doc)
+
+	var names []string
+	for _, child := range doc.Children {
+		names = append(names, child.FileName)
+	}
+	if strings.Join(names, "|") != "Quarterly report|passwd" {
+		t.Errorf("got messages %q", names)
+	}
+
+	if len(doc.Rejected) != 1 || doc.Rejected[0].FileName != "archive.mbox#3.eml" {
+		t.Fatalf("got rejections %+v, want the unparseable message", doc.Rejected)
+	}
+	if !strings.Contains(doc.Rejected[0].Reason, "failed to parse email") {
+		t.Errorf("got reason %q", doc.Rejected[0].Reason)
+	}
+}
+
+func TestNestedMessagesDepth(t *testing.T) {
+	// Every message carries the previous one as a message/rfc822 attachment
+	nested := message("level 0", "innermost")
+	for level := 1; level <= maxNestingDepth+2; level++ {
+		nested = fmt.Sprintf("From: a@example.com\r\nSubject: level %d\r\nMessage-Id: <%d@example.com>\r\n"+
+			"Content-Type: multipart/mixed; boundary=b%d\r\n\r\n--b%d\r\nContent-Type: text/plain\r\n\r\nlevel %d\r\n"+
+			"--b%d\r\nContent-Type: message/rfc822\r\nContent-Disposition: attachment; filename=level%d.eml\r\n\r\n%s\r\n--b%d--\r\n",
+			level, level, level, level, level, level, level-1, nested, level)
+	}
+
+	file, err := spool.FromBytes([]byte(nested))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ReadSpooled(file, "nested.eml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer discardContent(doc)
+
+	depth := 0
+	for current := doc; len(current.Children) > 0; current = current.Children[0] {
+		depth++
+		if len(current.Children[0].Children) == 0 {
+			rejected := current.Children[0].Rejected
+			if len(rejected) != 1 || !strings.Contains(rejected[0].Reason, "levels deep") {
+				t.Errorf("deepest message has rejections %+v", rejected)
+			}
+		}
+	}
+	if depth != maxNestingDepth {
+		t.Errorf("read %d levels of attachments, want %d", depth, maxNestingDepth)
+	}
+}
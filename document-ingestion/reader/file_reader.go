@@ -2,136 +2,291 @@ package reader
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/gabriel-vasile/mimetype"
-	"github.com/google/uuid"
 
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
 	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
 	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/processor"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
 	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/storage"
 )
 
+const maxUploadSize = 20 * 1024 * 1024 // 20MB
 
-func FileReader(content []byte, filename string, fileSize int64) (*models.Document, error) {
+// Documents found in documents, like messages attached to messages, are read
+// at most this deep
+const maxNestingDepth = 5
+
+
+// FileReader identifies content that is already in memory, such as email
+// attachments and archive entries. parent is the document the content was
+// found in.
+func FileReader(content []byte, filename string, parent *models.Document) (*models.Document, error) {
 	if len(content) == 0 {
 		return nil, fmt.Errorf("file content is empty")
 	}
+	if parent.Depth >= maxNestingDepth {
+		return nil, fmt.Errorf("nested more than %d levels deep", maxNestingDepth)
+	}
+
+	file, err := spool.FromBytes(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return readDocument(file, filename, parent)
+}
+
+// ReadSpooled identifies an upload that was streamed to a spool file. The
+// content is loaded for pre-processing under the upload memory budget.
+// Uploads are virus scanned first when a scanner is configured, infected ones
+// fail with a QuarantineError.
+func ReadSpooled(file *spool.File, filename string) (*models.Document, error) {
+	verdict, err := scanFile(file, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := spool.Reserve(file.Size())
+	if err != nil {
+		file.Remove()
+		return nil, err
+	}
+	defer release()
+
+	doc, err := readDocument(file, filename, nil)
+	if err != nil {
+		return nil, err
+	}
+	setMetadata(doc, "virus_scan", verdict)
+
+	return doc, nil
+}
+
+// retryable reports whether reading an upload failed for a reason that
+// passes, a scanner outage or a full memory budget, rather than because of
+// its content.
+func retryable(err error) bool {
+	return errors.Is(err, errScanUnavailable) || errors.Is(err, spool.ErrBudgetExhausted)
+}
+
+// readDocument takes ownership of file, the spooled content is removed when
+// the document is rejected. parent is nil for uploads. Archives are only
+// expanded outside of archives, so a nested one is never read no matter how
+// it is wrapped.
+func readDocument(file *spool.File, filename string, parent *models.Document) (*models.Document, error) {
+	inArchive := parent != nil && (parent.Format == "archive" || parent.InArchive)
+
+	if file.Size() == 0 {
+		file.Remove()
+		return nil, fmt.Errorf("file content is empty")
+	}
 	
-	mtype := mimetype.Detect(content)
+	mtype := mimetype.Detect(file.Head())
 	contentType := strings.TrimSpace(mtype.String())
 
-	if !isSupportedFileType(contentType) {
+	format := LookupFormat(contentType, filename, file.Head())
+	if format == nil {
+		file.Remove()
 		return nil, fmt.Errorf("unsupported file type: %s", contentType)
 	}
 
+	if inArchive && format.Name == "archive" {
+		file.Remove()
+		return nil, fmt.Errorf("nested archives are not supported")
+	}
+
+	if file.Size() > format.MaxSize {
+		file.Remove()
+		return nil, fmt.Errorf("file exceeds the %d byte limit for %s files", format.MaxSize, format.Name)
+	}
+
 	doc := &models.Document{
 		FileName: filename,
-		Content: content,
+		Content: file,
 		ContentType: contentType,
-		Size: fileSize,
+		Format: format.Name,
+		Kind: models.KindDocument,
+		Size: file.Size(),
+		SHA256: file.SHA256(),
 		Status: models.StatusReceived,
+		InArchive: inArchive,
+	}
+	if parent != nil {
+		doc.Depth = parent.Depth + 1
+	}
+
+	if err := prepareDocument(doc, format); err != nil {
+		discardContent(doc)
+		file.Remove()
+		return nil, err
 	}
 
 	return doc, nil
 }
 
-func isSupportedFileType(mimeType string) bool {
-	supportedTypes := map[string]bool{
-		"application/pdf": 				  true,
-		"text/plain; charset=utf-8":      true,
-		"text/rtf; charset=utf-8":        true,
+// prepareDocument validates the raw content, runs the format's pre-processing
+// and validates the resulting text. The caller discards the content when it
+// fails.
+func prepareDocument(doc *models.Document, format *Format) error {
+	if err := validateDocument(doc, StageRaw); err != nil {
+		return err
+	}
+
+	if format.PreProcess != nil {
+		if err := format.PreProcess(doc); err != nil {
+			return fmt.Errorf("failed to prepare %s file: %w", format.Name, err)
+		}
+	}
+
+	return validateDocument(doc, StageText)
+}
+
+// replaceContent swaps the document content for the pre-processed version
+// and drops the previous spool file. nil leaves the document without content.
+func replaceContent(doc *models.Document, content []byte) error {
+	previous := doc.Content
+	doc.Content = nil
+
+	if content != nil {
+		file, err := spool.FromBytes(content)
+		if err != nil {
+			return err
+		}
+		doc.Content = file
+	}
+
+	return previous.Remove()
+}
+
+// discardContent removes the spooled content of the document and all of its
+// children.
+func discardContent(doc *models.Document) {
+	if err := doc.Content.Remove(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	for _, child := range doc.Children {
+		discardContent(child)
 	}
-	return supportedTypes[mimeType]
 }
 
-func HandleUpload(w http.ResponseWriter, r *http.Request, client *processor.Client, mongodb *storage.MongoDB) {
+func HandleUpload(w http.ResponseWriter, r *http.Request, client processor.Processor, mongodb *storage.MongoDB) {
 	// Checks if the method is allowed
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
         return
 	}
 
-	// Cheks the the file size 
-	err := r.ParseMultipartForm(20 << 20)
+	options, err := processingOptions(r.URL.Query())
 	if err != nil {
-		http.Error(w, "Error file exceeds file limir" +err.Error(), http.StatusBadRequest)
-		return 
+		http.Error(w, "Invalid processing options: "+err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Reads file 
-	file, header, err := r.FormFile("document")
+	// Streams the file to a spool file instead of parsing the whole form in memory
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize+1<<20)
+	uploads, err := spoolFormFiles(r, "document", 1)
 	if err != nil {
-        http.Error(w, "Error retrieving file: "+err.Error(), http.StatusBadRequest)
-        return
-    }
-    defer file.Close()
-
-	if header.Size > 20*1024*1024 { // 20MB
-        http.Error(w, "File too large (max 20MB)", http.StatusBadRequest)
-        return
-    }
-
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		http.Error(w, "Error reading file content: "+err.Error(), http.StatusInternalServerError)
+		if errors.Is(err, spool.ErrTooLarge) {
+			http.Error(w, "File too large (max 20MB)", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Error retrieving file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(uploads) == 0 {
+		http.Error(w, "Error retrieving file: no document in request", http.StatusBadRequest)
 		return
 	}
 
-	doc, err := FileReader(fileContent, header.Filename, header.Size) 
+	doc, err := ReadSpooled(uploads[0].file, uploads[0].filename)
 	if err != nil {
+		if writeScanFailure(w, err, mongodb) || writeRejection(w, uploads[0].filename, err) {
+			return
+		}
         http.Error(w, "Error reading tempfile: "+err.Error(), http.StatusInternalServerError)
         return
     }
+	doc.Processing = options
+
+	if err := IngestDocument(doc, client, mongodb); err != nil {
+		http.Error(w, "Error ingesting document: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(documentSummary(doc))
+}
 
-	doc.ID = uuid.New().String()
-	doc.Status = models.StatusProcessing
-	doc.UploadedAt = time.Now()
+type spooledUpload struct {
+	filename string
+	file     *spool.File
+}
 
-	err = mongodb.InsertDocuments(doc)
+// spoolFormFiles streams the file parts of a multipart request to spool
+// files. An empty field accepts every file part.
+func spoolFormFiles(r *http.Request, field string, maxFiles int) ([]spooledUpload, error) {
+	reader, err := r.MultipartReader()
 	if err != nil {
-		http.Error(w, "Error saving document: "+err.Error(), http.StatusInternalServerError)
-        return
+		return nil, err
 	}
 
-	chunks,err := client.ProcessDocument(doc)
-	if err != nil {
-		http.Error(w, "Error at communications process: "+err.Error(), http.StatusInternalServerError)
-        return
+	var uploads []spooledUpload
+	fail := func(err error) ([]spooledUpload, error) {
+		for _, upload := range uploads {
+			upload.file.Remove()
+		}
+		return nil, err
 	}
 
-	err = mongodb.InsertChunks(doc.ID, chunks)
-    if err != nil {
-        http.Error(w, "Error saving chunks: "+err.Error(), http.StatusInternalServerError)
-        return
-    }
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return uploads, nil
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if part.FileName() == "" || (field != "" && part.FormName() != field) {
+			continue
+		}
+		if len(uploads) == maxFiles {
+			return fail(fmt.Errorf("request has more than %d files", maxFiles))
+		}
 
-	fmt.Printf("Successfully processed document %s with %d chunks\n", doc.ID, len(chunks))
-    
-    // Update document status
-    doc.Status = models.StatusCompleted
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-        "document_id": doc.ID,
-        "filename":    doc.FileName,
-        "status":      doc.Status,
-        "size":        doc.Size,
-    })
-    
+		file, err := spool.Write(part, maxUploadSize)
+		part.Close()
+		if err != nil {
+			return fail(err)
+		}
+
+		uploads = append(uploads, spooledUpload{filename: part.FileName(), file: file})
+	}
 }
 
-func HandleSearch(w http.ResponseWriter, r *http.Request, client *processor.Client, m *storage.MongoDB) {
+const (
+	searchVector  = "vector"
+	searchKeyword = "keyword"
+)
+
+func HandleSearch(w http.ResponseWriter, r *http.Request, embedder processor.Embedder, m *storage.MongoDB) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
         return
 	}
 
 	var request struct {
-		Query string `json:"query"`
+		Query    string   `json:"query"`
+		Queries  []string `json:"queries"`
+		Language string   `json:"language"`
+		Mode     string   `json:"mode"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -139,21 +294,77 @@ func HandleSearch(w http.ResponseWriter, r *http.Request, client *processor.Clie
         return
 	}
 
-	if request.Query == "" {
+	// Several phrasings of a question can be searched at once, the results
+	// are merged
+	queries := request.Queries
+	if request.Query != "" {
+		queries = append([]string{request.Query}, queries...)
+	}
+	for _, query := range queries {
+		if strings.TrimSpace(query) == "" {
+			http.Error(w, "Query can not be empty", http.StatusBadRequest)
+			return
+		}
+	}
+	if len(queries) == 0 {
 		http.Error(w, "Query can not be empty", http.StatusBadRequest)
         return
 	}
+	if limit := config.Int("SEARCH_MAX_QUERIES", 10); len(queries) > limit {
+		http.Error(w, fmt.Sprintf("Too many queries (max %d)", limit), http.StatusBadRequest)
+		return
+	}
+	if request.Language != "" && !languagePattern.MatchString(request.Language) {
+		http.Error(w, "Language must be a two letter ISO 639-1 code", http.StatusBadRequest)
+		return
+	}
 
-	queryVector, err := client.CreateInputEmbeddings(request.Query)
-	if err != nil {
-		http.Error(w, "Error creating embedding: "+err.Error(), http.StatusInternalServerError)
-        return
+	// Vector search compares meaning, keyword search matches the stemmed
+	// words of the query
+	var search func(i int) ([]string, error)
+	switch request.Mode {
+	case searchKeyword:
+		search = func(i int) ([]string, error) {
+			return m.KeywordSearch(queries[i], request.Language)
+		}
+	case "", searchVector:
+		request.Mode = searchVector
+
+		var queryVectors [][]float32
+		var err error
+		if len(queries) == 1 {
+			var queryVector []float32
+			queryVector, err = embedder.Embed(queries[0])
+			queryVectors = [][]float32{queryVector}
+		} else {
+			queryVectors, err = embedder.EmbedBatch(queries)
+		}
+		if err != nil {
+			http.Error(w, "Error creating embedding: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		search = func(i int) ([]string, error) {
+			return m.SearchDocumetns(queryVectors[i], embedder.Model(), request.Language)
+		}
+	default:
+		http.Error(w, "Mode must be vector or keyword", http.StatusBadRequest)
+		return
 	}
 
-	documentNames, err := m.SearchDocumetns(queryVector)
-	if err != nil {
-		http.Error(w, "Search failed: "+err.Error(), http.StatusInternalServerError)
-        return
+	documentNames := []string{}
+	found := make(map[string]bool)
+	for i := range queries {
+		names, err := search(i)
+		if err != nil {
+			http.Error(w, "Search failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, name := range names {
+			if !found[name] {
+				found[name] = true
+				documentNames = append(documentNames, name)
+			}
+		}
 	}
 
 	var response map[string]interface{}
@@ -168,12 +379,47 @@ func HandleSearch(w http.ResponseWriter, r *http.Request, client *processor.Clie
             "message":  "Similar documents returned",
         }
 	}
+	response["mode"] = request.Mode
+	if request.Mode == searchVector {
+		response["model"] = embedder.Model()
+	}
+	if request.Language != "" {
+		response["language"] = request.Language
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 
 }
 
+// HandleQueryCache reports query cache statistics on GET and empties the
+// cache on DELETE.
+func HandleQueryCache(w http.ResponseWriter, r *http.Request, embedder processor.Embedder) {
+	cache, ok := embedder.(*processor.QueryCache)
+	if !ok {
+		http.Error(w, "Query cache is disabled", http.StatusNotFound)
+		return
+	}
+
+	var response interface{}
+	switch r.Method {
+	case http.MethodGet:
+		response = cache.Stats()
+	case http.MethodDelete:
+		dropped := cache.Invalidate()
+		if err := cache.Save(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		response = map[string]interface{}{"invalidated": dropped}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 func HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
     fmt.Fprintf(w, "Service is healthy!")
 }
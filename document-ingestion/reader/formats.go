@@ -0,0 +1,266 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+)
+
+// Format describes a document type the ingestion service accepts. Formats are
+// matched by sniffing the content first, then by extension for generic text or
+// binary content, and finally by the detected MIME type.
+type Format struct {
+	Name       string   `json:"name"`
+	MimeTypes  []string `json:"mime_types"`
+	Extensions []string `json:"extensions"`
+	MaxSize    int64    `json:"max_size"`
+
+	// Sniff reports whether the content belongs to this format regardless of
+	// what the generic MIME detection says.
+	Sniff func(content []byte) bool `json:"-"`
+
+	// PreProcess runs before the document is sent to the processing service and
+	// may rewrite its content and content type.
+	PreProcess func(doc *models.Document) error `json:"-"`
+}
+
+const defaultMaxFormatSize = 20 * 1024 * 1024 // 20MB
+
+var (
+	formatsMu sync.RWMutex
+	formats   []*Format
+)
+
+func init() {
+	for _, f := range builtinFormats() {
+		if err := RegisterFormat(f); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func builtinFormats() []*Format {
+	return []*Format{
+		{
+			Name:       "pdf",
+			MimeTypes:  []string{"application/pdf"},
+			Extensions: []string{".pdf"},
+			Sniff: func(content []byte) bool {
+				return bytes.HasPrefix(content, []byte("%PDF-"))
+			},
+		},
+		{
+			Name:       "mbox",
+			MimeTypes:  []string{"application/mbox"},
+			Extensions: []string{".mbox", ".mbx"},
+			Sniff:      sniffMailbox,
+			PreProcess: preprocessMailbox,
+		},
+		{
+			Name:       "email",
+			MimeTypes:  []string{"message/rfc822"},
+			Extensions: []string{".eml"},
+			Sniff:      sniffEmail,
+			PreProcess: preprocessEmail,
+		},
+		{
+			Name:       "docx",
+			MimeTypes:  []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+			Extensions: []string{".docx"},
+			PreProcess: extractOOXML(extractDOCX),
+		},
+		{
+			Name:       "xlsx",
+			MimeTypes:  []string{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+			Extensions: []string{".xlsx"},
+			PreProcess: extractOOXML(extractXLSX),
+		},
+		{
+			Name:       "pptx",
+			MimeTypes:  []string{"application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+			Extensions: []string{".pptx"},
+			PreProcess: extractOOXML(extractPPTX),
+		},
+		{
+			Name:       "archive",
+			MimeTypes:  []string{"application/zip", "application/x-tar", "application/gzip", "application/x-gzip"},
+			Extensions: []string{".zip", ".tar", ".tgz", ".gz"},
+			PreProcess: preprocessArchive,
+		},
+		{
+			Name:       "rtf",
+			MimeTypes:  []string{"text/rtf", "application/rtf"},
+			Extensions: []string{".rtf"},
+			PreProcess: preprocessRTF,
+		},
+		{
+			Name:       "markdown",
+			MimeTypes:  []string{"text/markdown", "text/x-markdown"},
+			Extensions: []string{".md", ".markdown"},
+			PreProcess: preprocessMarkdown,
+		},
+		{
+			Name:       "html",
+			MimeTypes:  []string{"text/html", "application/xhtml+xml"},
+			Extensions: []string{".html", ".htm", ".xhtml"},
+			PreProcess: preprocessHTML,
+		},
+		{
+			Name:       "csv",
+			MimeTypes:  []string{"text/csv", "text/tab-separated-values"},
+			Extensions: []string{".csv", ".tsv"},
+			PreProcess: toUTF8Text("text/plain; charset=utf-8"),
+		},
+		{
+			Name:       "text",
+			MimeTypes:  []string{"text/plain"},
+			Extensions: []string{".txt", ".text", ".log"},
+			PreProcess: toUTF8Text("text/plain; charset=utf-8"),
+		},
+	}
+}
+
+func RegisterFormat(f *Format) error {
+	if f == nil || f.Name == "" {
+		return fmt.Errorf("format name can not be empty")
+	}
+	if len(f.MimeTypes) == 0 && len(f.Extensions) == 0 && f.Sniff == nil {
+		return fmt.Errorf("format %s has no way to be matched", f.Name)
+	}
+	if f.MaxSize <= 0 {
+		f.MaxSize = defaultMaxFormatSize
+	}
+
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	for _, existing := range formats {
+		if existing.Name == f.Name {
+			return fmt.Errorf("format %s is already registered", f.Name)
+		}
+	}
+
+	formats = append(formats, f)
+	return nil
+}
+
+func Formats() []*Format {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	list := make([]*Format, len(formats))
+	copy(list, formats)
+	return list
+}
+
+// formatNamed returns the registered format called name, or nil.
+func formatNamed(name string) *Format {
+	for _, f := range Formats() {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func LookupFormat(contentType, filename string, content []byte) *Format {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	registered := Formats()
+
+	for _, f := range registered {
+		if f.Sniff != nil && f.Sniff(content) {
+			return f
+		}
+	}
+
+	// Generic detections say little about the document, so the extension
+	// decides between e.g. Markdown and plain text
+	if mediaType == "text/plain" || mediaType == "application/octet-stream" || mediaType == "application/zip" {
+		for _, f := range registered {
+			if containsString(f.Extensions, ext) {
+				return f
+			}
+		}
+	}
+
+	for _, f := range registered {
+		if containsString(f.MimeTypes, mediaType) {
+			return f
+		}
+	}
+
+	return nil
+}
+
+func containsString(list []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// toUTF8Text transcodes text in any supported charset to UTF-8 and hands it
+// to the processor with the given content type.
+func toUTF8Text(targetType string) func(doc *models.Document) error {
+	return func(doc *models.Document) error {
+		content, err := doc.Content.Bytes()
+		if err != nil {
+			return err
+		}
+		text, encoding, err := transcodeText(content, doc.ContentType)
+		if err != nil {
+			return err
+		}
+
+		doc.Encoding = encoding
+		doc.ContentType = targetType
+		if bytes.Equal(text, content) {
+			return nil
+		}
+		return replaceContent(doc, text)
+	}
+}
+
+// documentText loads the document content as UTF-8 text and records the
+// charset it was written in.
+func documentText(doc *models.Document) ([]byte, error) {
+	content, err := doc.Content.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	text, encoding, err := transcodeText(content, doc.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	doc.Encoding = encoding
+	return text, nil
+}
+
+func HandleFormats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"formats": Formats(),
+	})
+}
@@ -0,0 +1,354 @@
+package reader
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+)
+
+// Elements that only carry page chrome or code, never document content
+var boilerplateElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Nav:      true,
+	atom.Aside:    true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Iframe:   true,
+	atom.Svg:      true,
+	atom.Canvas:   true,
+}
+
+var boilerplateHints = []string{"nav", "menu", "footer", "sidebar", "cookie", "banner", "breadcrumb", "advert", "social", "share"}
+
+// preprocessHTML converts a saved web page to Markdown, dropping scripts,
+// styles and navigation so only the readable content reaches the processor.
+func preprocessHTML(doc *models.Document) error {
+	page, err := documentText(doc)
+	if err != nil {
+		return err
+	}
+
+	text, metadata, err := convertHTML(page)
+	if err != nil {
+		return err
+	}
+	for key, value := range metadata {
+		setMetadata(doc, key, value)
+	}
+
+	doc.ContentType = markdownContentType
+	return replaceContent(doc, []byte(text))
+}
+
+func convertHTML(page []byte) (string, map[string]string, error) {
+	root, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	metadata := make(map[string]string)
+	if title := findElement(root, atom.Title); title != nil {
+		metadata["title"] = strings.TrimSpace(textContent(title))
+	}
+	for _, meta := range findElements(root, atom.Meta) {
+		name := strings.ToLower(htmlAttr(meta, "name"))
+		if name == "" {
+			name = strings.ToLower(htmlAttr(meta, "property"))
+		}
+		switch name {
+		case "description", "author", "keywords", "og:title", "og:description":
+			metadata[strings.TrimPrefix(name, "og:")] = strings.TrimSpace(htmlAttr(meta, "content"))
+		}
+	}
+	if lang := htmlAttr(findElement(root, atom.Html), "lang"); lang != "" {
+		metadata["lang"] = lang
+	}
+
+	// Pages that mark up their main content let us skip everything around it
+	content := findElement(root, atom.Main)
+	if content == nil {
+		content = findElement(root, atom.Article)
+	}
+	if content == nil {
+		content = findElement(root, atom.Body)
+	}
+	if content == nil {
+		content = root
+	}
+
+	converter := &markdownWriter{}
+	converter.block(content)
+	text := converter.String()
+	if strings.TrimSpace(text) == "" {
+		return "", nil, fmt.Errorf("no readable content found in page")
+	}
+
+	return text, metadata, nil
+}
+
+type markdownWriter struct {
+	blocks []string
+	inline strings.Builder
+}
+
+func (m *markdownWriter) String() string {
+	m.flush()
+	return strings.Join(m.blocks, "\n\n")
+}
+
+func (m *markdownWriter) flush() {
+	text := collapseSpaces(m.inline.String())
+	m.inline.Reset()
+	if text != "" {
+		m.blocks = append(m.blocks, text)
+	}
+}
+
+func (m *markdownWriter) emit(block string) {
+	m.flush()
+	if strings.TrimSpace(block) != "" {
+		m.blocks = append(m.blocks, block)
+	}
+}
+
+func (m *markdownWriter) block(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		m.node(c)
+	}
+}
+
+func (m *markdownWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		m.inline.WriteString(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		m.block(n)
+		return
+	}
+
+	if isBoilerplate(n) {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level, _ := strconv.Atoi(n.Data[1:])
+		m.emit(strings.Repeat("#", level) + " " + collapseSpaces(inlineText(n)))
+	case atom.P, atom.Blockquote, atom.Figcaption, atom.Dt, atom.Dd:
+		text := collapseSpaces(inlineText(n))
+		if n.DataAtom == atom.Blockquote && text != "" {
+			text = "> " + text
+		}
+		m.emit(text)
+	case atom.Ul, atom.Ol:
+		m.emit(m.list(n, 0))
+	case atom.Table:
+		m.emit(tableMarkdown(n))
+	case atom.Pre:
+		m.emit("```\n" + strings.Trim(textContent(n), "\n") + "\n```")
+	case atom.Br:
+		m.inline.WriteString("\n")
+	case atom.Hr:
+		m.flush()
+	case atom.Img:
+		if alt := strings.TrimSpace(htmlAttr(n, "alt")); alt != "" {
+			m.inline.WriteString(" " + alt + " ")
+		}
+	case atom.Div, atom.Section, atom.Article, atom.Main, atom.Body, atom.Li, atom.Dl, atom.Figure:
+		m.flush()
+		m.block(n)
+		m.flush()
+	default:
+		m.block(n)
+	}
+}
+
+func (m *markdownWriter) list(n *html.Node, depth int) string {
+	var lines []string
+	index := 1
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+
+		marker := "-"
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(index) + "."
+			index++
+		}
+
+		var text strings.Builder
+		var nested []string
+		for c := li.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.DataAtom == atom.Ul || c.DataAtom == atom.Ol) {
+				nested = append(nested, m.list(c, depth+1))
+				continue
+			}
+			text.WriteString(inlineText(c))
+		}
+
+		lines = append(lines, strings.Repeat("  ", depth)+marker+" "+collapseSpaces(text.String()))
+		lines = append(lines, nested...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func tableMarkdown(table *html.Node) string {
+	var rows [][]string
+	for _, tr := range findElements(table, atom.Tr) {
+		var cells []string
+		for c := tr.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.DataAtom == atom.Td || c.DataAtom == atom.Th) {
+				cell := collapseSpaces(inlineText(c))
+				cells = append(cells, strings.ReplaceAll(cell, "|", "\\|"))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", width))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isBoilerplate(n *html.Node) bool {
+	if boilerplateElements[n.DataAtom] {
+		return true
+	}
+	if hasHTMLAttr(n, "hidden") || strings.EqualFold(htmlAttr(n, "aria-hidden"), "true") {
+		return true
+	}
+
+	// Page level headers and footers are chrome, an article's own header
+	// usually holds its title
+	if (n.DataAtom == atom.Header || n.DataAtom == atom.Footer) && n.Parent != nil && n.Parent.DataAtom == atom.Body {
+		return true
+	}
+
+	role := strings.ToLower(htmlAttr(n, "role"))
+	if role == "navigation" || role == "banner" || role == "contentinfo" || role == "complementary" {
+		return true
+	}
+
+	// Only layout containers are judged by their class or id, so a paragraph
+	// that happens to mention "share" is kept
+	if n.DataAtom != atom.Div && n.DataAtom != atom.Section && n.DataAtom != atom.Ul {
+		return false
+	}
+	hints := strings.ToLower(htmlAttr(n, "class") + " " + htmlAttr(n, "id"))
+	for _, hint := range boilerplateHints {
+		if strings.Contains(hints, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+func inlineText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	if n.Type == html.ElementNode && isBoilerplate(n) {
+		return ""
+	}
+	if n.Type == html.ElementNode && n.DataAtom == atom.Br {
+		return " "
+	}
+	if n.Type == html.ElementNode && n.DataAtom == atom.Img {
+		return htmlAttr(n, "alt")
+	}
+
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(inlineText(c))
+	}
+	return b.String()
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func collapseSpaces(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func findElements(n *html.Node, a atom.Atom) []*html.Node {
+	var found []*html.Node
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		found = append(found, n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		found = append(found, findElements(c, a)...)
+	}
+	return found
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	if n == nil {
+		return ""
+	}
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasHTMLAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package reader
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/processor"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/storage"
+)
+
+// IngestDocument saves the document, sends it for processing and stores the
+// resulting chunks. Child documents (attachments, archive entries) are ingested
+// afterwards and linked to it, a failing child is recorded but doesn't fail
+// the parent.
+func IngestDocument(doc *models.Document, client processor.Processor, mongodb *storage.MongoDB) error {
+	// Children that never got ingested still hold spooled content
+	defer discardContent(doc)
+
+	if doc.ID == "" {
+		doc.ID = uuid.New().String()
+	}
+	doc.Status = models.StatusProcessing
+	doc.UploadedAt = time.Now()
+	redactMetadata(doc)
+
+	if err := mongodb.InsertDocuments(doc); err != nil {
+		return fmt.Errorf("error saving document: %w", err)
+	}
+
+	if doc.Kind != models.KindCollection {
+		release, err := spool.Reserve(doc.Content.Size())
+		if err != nil {
+			return failDocument(doc, mongodb, err)
+		}
+		chunks, err := client.ProcessDocument(doc)
+		release()
+		if err != nil {
+			return failDocument(doc, mongodb, fmt.Errorf("error at communications process: %w", err))
+		}
+
+		redactChunks(doc, chunks)
+		detectLanguages(doc, chunks)
+
+		if err := mongodb.InsertChunks(doc.ID, chunks); err != nil {
+			return failDocument(doc, mongodb, fmt.Errorf("error saving chunks: %w", err))
+		}
+
+		fmt.Printf("Successfully processed document %s with %d chunks\n", doc.ID, len(chunks))
+	}
+
+	for _, child := range doc.Children {
+		child.ParentID = doc.ID
+		if child.Processing == nil {
+			child.Processing = doc.Processing
+		}
+		if err := IngestDocument(child, client, mongodb); err != nil {
+			log.Printf("Warning: failed to ingest %s of document %s: %v", child.FileName, doc.ID, err)
+		}
+	}
+
+	doc.Status = models.StatusCompleted
+	if err := mongodb.InsertDocuments(doc); err != nil {
+		return fmt.Errorf("error updating document status: %w", err)
+	}
+
+	return nil
+}
+
+func failDocument(doc *models.Document, mongodb *storage.MongoDB, cause error) error {
+	doc.Status = models.StatusFailed
+	doc.Error = cause.Error()
+
+	if err := mongodb.InsertDocuments(doc); err != nil {
+		log.Printf("Warning: failed to record failure of document %s: %v", doc.ID, err)
+	}
+
+	return cause
+}
+
+func documentSummary(doc *models.Document) map[string]interface{} {
+	summary := map[string]interface{}{
+		"document_id": doc.ID,
+		"filename":    doc.FileName,
+		"status":      doc.Status,
+		"size":        doc.Size,
+	}
+	if doc.Error != "" {
+		summary["error"] = doc.Error
+	}
+	if doc.Encoding != "" {
+		summary["encoding"] = doc.Encoding
+	}
+	if doc.Language != "" {
+		summary["language"] = doc.Language
+	}
+	if len(doc.PII) > 0 {
+		summary["pii"] = doc.PII
+	}
+	if doc.Processing != nil {
+		summary["processing"] = doc.Processing
+	}
+	if len(doc.OCRPages) > 0 {
+		summary["ocr_pages"] = doc.OCRPages
+	}
+
+	if doc.Kind == models.KindCollection {
+		summary["accepted"] = len(doc.Children)
+	}
+	if len(doc.Rejected) > 0 {
+		summary["rejected"] = doc.Rejected
+	}
+
+	if len(doc.Children) > 0 {
+		children := make([]map[string]interface{}, 0, len(doc.Children))
+		for _, child := range doc.Children {
+			children = append(children, documentSummary(child))
+		}
+		summary["children"] = children
+	}
+
+	return summary
+}
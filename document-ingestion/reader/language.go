@@ -0,0 +1,36 @@
+package reader
+
+import (
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/language"
+)
+
+// detectLanguages sets the language of the chunks and of the document. The
+// document is in the language most of its chunk text is in, or the language
+// it was processed with when none is detected. Chunks whose language isn't
+// clear, such as short headings or tables, take the document's.
+func detectLanguages(doc *models.Document, chunks []*models.DocumentChunk) {
+	lengths := make(map[string]int)
+	for _, chunk := range chunks {
+		chunk.Language = language.Detect(chunk.Text)
+		if chunk.Language != "" {
+			lengths[chunk.Language] += len(chunk.Text)
+		}
+	}
+
+	doc.Language = ""
+	for _, code := range language.Supported() {
+		if lengths[code] > lengths[doc.Language] {
+			doc.Language = code
+		}
+	}
+	if doc.Language == "" && doc.Processing != nil {
+		doc.Language = doc.Processing.Language
+	}
+
+	for _, chunk := range chunks {
+		if chunk.Language == "" {
+			chunk.Language = doc.Language
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package reader
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
+)
+
+// Names and other small strings of a manifest are read into memory, contents
+// never are
+const maxManifestString = 64 * 1024
+
+var errInvalidManifest = errors.New("invalid manifest")
+
+// manifestReader streams a JSON batch manifest of the form
+//
+//	{"documents": [{"filename": "a.pdf", "content": "<base64>"}, ...]}
+//
+// Contents are decoded straight into spool files while the body is read, so
+// a batch never has to fit in memory. encoding/json can't do this, it holds
+// every string value in full.
+type manifestReader struct {
+	r *bufio.Reader
+}
+
+func readManifest(body io.Reader, maxFiles int) (uploads []spooledUpload, err error) {
+	m := &manifestReader{r: bufio.NewReader(body)}
+	defer func() {
+		if err != nil {
+			for _, upload := range uploads {
+				upload.file.Remove()
+			}
+			uploads = nil
+		}
+	}()
+
+	err = m.object(func(key string) error {
+		if key != "documents" {
+			return m.skip()
+		}
+		return m.array(func() error {
+			if len(uploads) == maxFiles {
+				return fmt.Errorf("batch has more than %d files", maxFiles)
+			}
+			upload, err := m.document(len(uploads))
+			if err != nil {
+				return err
+			}
+			uploads = append(uploads, upload)
+			return nil
+		})
+	})
+	return uploads, err
+}
+
+// document reads one manifest entry, the content may come before the name.
+func (m *manifestReader) document(index int) (spooledUpload, error) {
+	var upload spooledUpload
+	err := m.object(func(key string) error {
+		switch key {
+		case "filename":
+			name, err := m.string()
+			upload.filename = name
+			return err
+		case "content":
+			if upload.file != nil {
+				return fmt.Errorf("%w: document %d has two contents", errInvalidManifest, index)
+			}
+			// encoding/json writes an empty content as null
+			if c, err := m.peek(); err != nil || c == 'n' {
+				return m.skip()
+			}
+			if err := m.expect('"'); err != nil {
+				return err
+			}
+			content := &jsonStringReader{r: m.r}
+			file, err := spool.Write(base64.NewDecoder(base64.StdEncoding, content), maxUploadSize)
+			if err != nil {
+				return fmt.Errorf("document %d: %w", index, err)
+			}
+			upload.file = file
+			return nil
+		}
+		return m.skip()
+	})
+
+	if err == nil && strings.TrimSpace(upload.filename) == "" {
+		err = fmt.Errorf("manifest entry without filename")
+	}
+	if err == nil && upload.file == nil {
+		upload.file, err = spool.FromBytes(nil)
+	}
+	if err != nil {
+		upload.file.Remove()
+		return spooledUpload{}, err
+	}
+	return upload, nil
+}
+
+// object reads the members of a JSON object, value reads the value of a key.
+func (m *manifestReader) object(value func(key string) error) error {
+	if err := m.expect('{'); err != nil {
+		return err
+	}
+	return m.members('}', func() error {
+		key, err := m.string()
+		if err != nil {
+			return err
+		}
+		if err := m.expect(':'); err != nil {
+			return err
+		}
+		return value(key)
+	})
+}
+
+func (m *manifestReader) array(element func() error) error {
+	if err := m.expect('['); err != nil {
+		return err
+	}
+	return m.members(']', element)
+}
+
+// members reads comma separated members up to the closing bracket.
+func (m *manifestReader) members(closing byte, member func() error) error {
+	if c, err := m.peek(); err != nil {
+		return err
+	} else if c == closing {
+		m.r.ReadByte()
+		return nil
+	}
+	for {
+		if err := member(); err != nil {
+			return err
+		}
+		c, err := m.next()
+		if err != nil {
+			return err
+		}
+		if c == closing {
+			return nil
+		}
+		if c != ',' {
+			return fmt.Errorf("%w: unexpected %q", errInvalidManifest, c)
+		}
+	}
+}
+
+// string reads a small string value.
+func (m *manifestReader) string() (string, error) {
+	if err := m.expect('"'); err != nil {
+		return "", err
+	}
+	raw := []byte{'"'}
+	err := m.scanString(func(c byte) error {
+		if raw = append(raw, c); len(raw) > maxManifestString {
+			return fmt.Errorf("%w: string longer than %d bytes", errInvalidManifest, maxManifestString)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	raw = append(raw, '"')
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", fmt.Errorf("%w: %v", errInvalidManifest, err)
+	}
+	return s, nil
+}
+
+// skip reads past a value of a key the manifest doesn't use.
+func (m *manifestReader) skip() error {
+	c, err := m.peek()
+	if err != nil {
+		return err
+	}
+	switch c {
+	case '"':
+		m.r.ReadByte()
+		return m.scanString(func(byte) error { return nil })
+	case '{':
+		return m.object(func(string) error { return m.skip() })
+	case '[':
+		return m.array(m.skip)
+	}
+	for {
+		c, err := m.r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidManifest, io.ErrUnexpectedEOF)
+		}
+		if c == ',' || c == '}' || c == ']' || isJSONSpace(c) {
+			return m.r.UnreadByte()
+		}
+	}
+}
+
+// scanString passes the raw bytes of a string, escapes included, up to its
+// closing quote.
+func (m *manifestReader) scanString(each func(c byte) error) error {
+	for escaped := false; ; {
+		c, err := m.r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidManifest, io.ErrUnexpectedEOF)
+		}
+		if c == '"' && !escaped {
+			return nil
+		}
+		if err := each(c); err != nil {
+			return err
+		}
+		escaped = c == '\\' && !escaped
+	}
+}
+
+// next returns the next byte that isn't whitespace.
+func (m *manifestReader) next() (byte, error) {
+	for {
+		c, err := m.r.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", errInvalidManifest, io.ErrUnexpectedEOF)
+		}
+		if !isJSONSpace(c) {
+			return c, nil
+		}
+	}
+}
+
+func (m *manifestReader) peek() (byte, error) {
+	c, err := m.next()
+	if err == nil {
+		m.r.UnreadByte()
+	}
+	return c, err
+}
+
+func (m *manifestReader) expect(want byte) error {
+	c, err := m.next()
+	if err != nil {
+		return err
+	}
+	if c != want {
+		return fmt.Errorf("%w: expected %q, found %q", errInvalidManifest, want, c)
+	}
+	return nil
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// jsonStringReader reads the rest of a JSON string after its opening quote
+// and ends at the closing one. Only the escapes base64 text can contain are
+// understood: an escaped slash and line breaks.
+type jsonStringReader struct {
+	r    *bufio.Reader
+	done bool
+}
+
+func (s *jsonStringReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) && !s.done {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return n, fmt.Errorf("%w: %v", errInvalidManifest, io.ErrUnexpectedEOF)
+		}
+		switch c {
+		case '"':
+			s.done = true
+			continue
+		case '\\':
+			escape, err := s.r.ReadByte()
+			if err != nil {
+				return n, fmt.Errorf("%w: %v", errInvalidManifest, io.ErrUnexpectedEOF)
+			}
+			switch escape {
+			case '/', '\\', '"':
+				c = escape
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			default:
+				return n, fmt.Errorf("%w: unsupported escape \\%c in content", errInvalidManifest, escape)
+			}
+		}
+		p[n] = c
+		n++
+	}
+	if n == 0 && s.done {
+		return 0, io.EOF
+	}
+	return n, nil
+}
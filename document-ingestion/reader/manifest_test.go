@@ -0,0 +1,88 @@
+package reader
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
+)
+
+func TestReadManifest(t *testing.T) {
+	type entry struct {
+		FileName string `json:"filename"`
+		Content  []byte `json:"content"`
+	}
+	large := strings.Repeat("spooled to disk ", 64*1024)
+	body, _ := json.Marshal(map[string]any{
+		"comment": map[string]any{"nested": []any{"aé\"", 1.5, nil, true}},
+		"documents": []entry{
+			{FileName: "notes.txt", Content: []byte("small notes")},
+			{FileName: "large.txt", Content: []byte(large)},
+			{FileName: "empty.txt"},
+		},
+	})
+
+	uploads, err := readManifest(strings.NewReader(string(body)), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"notes.txt": "small notes", "large.txt": large, "empty.txt": ""}
+	if len(uploads) != len(want) {
+		t.Fatalf("got %d uploads, want %d", len(uploads), len(want))
+	}
+	for _, upload := range uploads {
+		content, err := upload.file.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != want[upload.filename] {
+			t.Errorf("%s: got %d bytes, want %d", upload.filename, len(content), len(want[upload.filename]))
+		}
+		upload.file.Remove()
+	}
+}
+
+func TestReadManifestFormatting(t *testing.T) {
+	// Content before the name, an escaped slash and a line broken encoding
+	encoded := base64.StdEncoding.EncodeToString([]byte("?>?>?>?>?>?>"))
+	encoded = strings.ReplaceAll(encoded[:8], "/", `\/`) + `\n` + encoded[8:]
+	body := ` { "documents" : [ { "content" : "` + encoded + `" , "filename" : "café.txt" } ] } `
+
+	uploads, err := readManifest(strings.NewReader(body), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer uploads[0].file.Remove()
+	content, _ := uploads[0].file.Bytes()
+	if uploads[0].filename != "café.txt" || string(content) != "?>?>?>?>?>?>" {
+		t.Errorf("got %s with %q", uploads[0].filename, content)
+	}
+}
+
+func TestReadManifestErrors(t *testing.T) {
+	tooLarge := base64.StdEncoding.EncodeToString(make([]byte, maxUploadSize+1))
+	for name, test := range map[string]struct {
+		body string
+		want error
+	}{
+		"too many files":   {`{"documents": [{"filename": "a"}, {"filename": "b"}, {"filename": "c"}]}`, nil},
+		"too large":        {`{"documents": [{"filename": "a", "content": "` + tooLarge + `"}]}`, spool.ErrTooLarge},
+		"invalid base64":   {`{"documents": [{"filename": "a", "content": "not base64!"}]}`, nil},
+		"missing filename": {`{"documents": [{"content": "YQ=="}]}`, nil},
+		"truncated":        {`{"documents": [{"filename": "a", "content": "YQ==`, errInvalidManifest},
+		"not an object":    {`["a"]`, errInvalidManifest},
+		"unknown escape":   {`{"documents": [{"filename": "a", "content": "Y\u0051=="}]}`, errInvalidManifest},
+	} {
+		uploads, err := readManifest(strings.NewReader(test.body), 2)
+		if err == nil {
+			t.Errorf("%s: accepted with %d uploads", name, len(uploads))
+			continue
+		}
+		if test.want != nil && !errors.Is(err, test.want) {
+			t.Errorf("%s: got %v, want %v", name, err, test.want)
+		}
+	}
+}
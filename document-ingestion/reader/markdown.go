@@ -0,0 +1,131 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+)
+
+const markdownContentType = "text/markdown; charset=utf-8"
+
+// preprocessMarkdown keeps the Markdown body as is so the processor's header
+// splitter can section it, and moves YAML or TOML front matter into metadata.
+func preprocessMarkdown(doc *models.Document) error {
+	text, err := documentText(doc)
+	if err != nil {
+		return err
+	}
+
+	body, frontMatter := splitFrontMatter(text)
+	for key, value := range frontMatter {
+		setMetadata(doc, key, value)
+	}
+
+	doc.ContentType = markdownContentType
+	return replaceContent(doc, body)
+}
+
+func splitFrontMatter(content []byte) ([]byte, map[string]string) {
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+
+	var separator string
+	switch strings.TrimSpace(lines[0]) {
+	case "---":
+		separator = ":"
+	case "+++":
+		separator = "="
+	default:
+		return content, nil
+	}
+
+	opening := strings.TrimSpace(lines[0])
+	for i := 1; i < len(lines); i++ {
+		closing := strings.TrimSpace(lines[i])
+		if closing == opening || (opening == "---" && closing == "...") {
+			block := strings.Join(lines[1:i], "\n")
+			body := strings.TrimLeft(strings.Join(lines[i+1:], "\n"), "\n")
+			return []byte(body), parseFrontMatter([]byte(block), separator)
+		}
+	}
+
+	return content, nil
+}
+
+// parseFrontMatter understands flat "key: value" (YAML) and "key = value"
+// (TOML) pairs plus YAML block lists, which covers what docs tooling emits.
+func parseFrontMatter(block []byte, separator string) map[string]string {
+	values := make(map[string]string)
+	var listKey string
+	var listItems []string
+
+	flush := func() {
+		if listKey != "" && len(listItems) > 0 {
+			values[listKey] = strings.Join(listItems, ", ")
+		}
+		listKey, listItems = "", nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(block))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if listKey != "" && strings.HasPrefix(trimmed, "- ") {
+			listItems = append(listItems, unquote(strings.TrimSpace(trimmed[2:])))
+			continue
+		}
+
+		// Nested YAML mappings are not flattened
+		if line != strings.TrimLeft(line, " \t") {
+			continue
+		}
+
+		flush()
+		key, value, ok := strings.Cut(trimmed, separator)
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if value == "" {
+			listKey = key
+			continue
+		}
+		if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			items := strings.Split(value[1:len(value)-1], ",")
+			for i, item := range items {
+				items[i] = unquote(strings.TrimSpace(item))
+			}
+			value = strings.Join(items, ", ")
+		}
+		values[key] = unquote(value)
+	}
+	flush()
+
+	return values
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 {
+		if (value[0] == '"' && value[len(value)-1] == '"') || (value[0] == '\'' && value[len(value)-1] == '\'') {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
+
+func setMetadata(doc *models.Document, key, value string) {
+	if value == "" {
+		return
+	}
+	if doc.Metadata == nil {
+		doc.Metadata = make(map[string]string)
+	}
+	doc.Metadata[key] = value
+}
@@ -0,0 +1,546 @@
+package reader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/processor"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/storage"
+)
+
+const migrationHistoryLimit = 20
+
+var (
+	errMigrationInvalid = errors.New("invalid migration")
+	errMigrationState   = errors.New("migration state does not allow this")
+	errMigrationStopped = errors.New("migration stopped")
+
+	errNoServiceEmbedder = fmt.Errorf("%w: migrations need EMBEDDER=grpc", errMigrationInvalid)
+)
+
+// Migrator re-embeds every chunk with a new model in the background. Vectors
+// go to the slot search doesn't read, so search keeps answering with the old
+// model until the switch. The checkpoint is the last chunk embedded, a
+// migration interrupted by a restart continues from there.
+type Migrator struct {
+	// client is nil when vectors don't come from the processing service,
+	// only its models can be migrated to
+	client    *processor.Client
+	embedder  processor.Embedder
+	mongodb   *storage.MongoDB
+	batchSize int
+
+	// mu serializes the operations, only one migration runs at a time
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewMigrator points the client at the model of the slot search reads, which
+// differs from EMBEDDING_MODEL after a switch.
+func NewMigrator(p processor.Processor, mongodb *storage.MongoDB) (*Migrator, error) {
+	slot, err := mongodb.SearchSlot()
+	if err != nil {
+		return nil, err
+	}
+
+	client := processor.ServiceClient(p)
+	if slot.Model != "" && slot.Model != p.Embedder().Model() {
+		if client != nil {
+			client.SetModel(slot.Model)
+			fmt.Printf("Searching %s with model %s\n", slot.Path, slot.Model)
+		} else {
+			log.Printf("Warning: search reads %s vectors but the embedder is %s", slot.Model, p.Embedder().Model())
+		}
+	}
+
+	return &Migrator{
+		client:    client,
+		embedder:  p.Embedder(),
+		mongodb:   mongodb,
+		batchSize: max(config.Int("MIGRATION_BATCH_SIZE", 256), 1),
+	}, nil
+}
+
+// Run continues a migration that was running when the service stopped.
+func (m *Migrator) Run() {
+	migrations, err := m.mongodb.ListMigrations(1)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	if len(migrations) == 0 || migrations[0].Status != models.MigrationRunning {
+		return
+	}
+	if m.client == nil {
+		log.Printf("Warning: migration %s can't continue without EMBEDDER=grpc", migrations[0].ID)
+		return
+	}
+
+	migration := migrations[0]
+	fmt.Printf("Resuming migration %s to %s at %d of %d chunks\n", migration.ID, migration.ToModel, migration.Processed, migration.Total)
+
+	m.mu.Lock()
+	m.startLoop(&migration)
+	m.mu.Unlock()
+}
+
+// Start begins a migration to model. With autoSwitch search moves to the new
+// model as soon as every chunk has its vector.
+func (m *Migrator) Start(model string, autoSwitch bool) (*models.Migration, error) {
+	if m.client == nil {
+		return nil, errNoServiceEmbedder
+	}
+	if model == "" {
+		return nil, fmt.Errorf("%w: a model is required", errMigrationInvalid)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	latest, err := m.mongodb.ListMigrations(1)
+	if err != nil {
+		return nil, err
+	}
+	if len(latest) > 0 && !migrationFinished(latest[0].Status) {
+		return nil, fmt.Errorf("%w: migration %s is %s", errMigrationState, latest[0].ID, latest[0].Status)
+	}
+
+	slot, err := m.mongodb.SearchSlot()
+	if err != nil {
+		return nil, err
+	}
+	from := slot.Model
+	if from == "" {
+		from = m.embedder.Model()
+	}
+	if model == from {
+		return nil, fmt.Errorf("%w: search already uses %s", errMigrationInvalid, model)
+	}
+
+	// The other slot still holds the vectors a rollback of the previous
+	// migration would return to
+	toPath := storage.OtherPath(slot.Path)
+	if err := m.mongodb.ResetSlot(toPath); err != nil {
+		return nil, err
+	}
+
+	total, err := m.mongodb.CountChunks()
+	if err != nil {
+		return nil, err
+	}
+
+	migration := &models.Migration{
+		ID:         uuid.New().String(),
+		FromModel:  from,
+		ToModel:    model,
+		FromPath:   slot.Path,
+		ToPath:     toPath,
+		Status:     models.MigrationRunning,
+		AutoSwitch: autoSwitch,
+		Total:      total,
+		StartedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	if err := m.mongodb.SaveMigration(migration); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Migrating %d chunks from %s to %s\n", total, from, model)
+	m.startLoop(migration)
+	return migration, nil
+}
+
+// Pause stops a running migration after its current batch.
+func (m *Migrator) Pause(id string) (*models.Migration, error) {
+	return m.update(id, func(migration *models.Migration) error {
+		if migration.Status != models.MigrationRunning {
+			return fmt.Errorf("%w: migration is %s", errMigrationState, migration.Status)
+		}
+		migration.Status = models.MigrationPaused
+		return nil
+	})
+}
+
+// Resume continues a paused or failed migration from its checkpoint.
+func (m *Migrator) Resume(id string) (*models.Migration, error) {
+	migration, err := m.update(id, func(migration *models.Migration) error {
+		if migration.Status != models.MigrationPaused && migration.Status != models.MigrationFailed {
+			return fmt.Errorf("%w: migration is %s", errMigrationState, migration.Status)
+		}
+		migration.Status = models.MigrationRunning
+		migration.Error = ""
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.startLoop(migration)
+	m.mu.Unlock()
+	return migration, nil
+}
+
+// Cancel gives up on a migration that wasn't switched to. Its vectors stay
+// until the next migration reuses the slot.
+func (m *Migrator) Cancel(id string) (*models.Migration, error) {
+	return m.update(id, func(migration *models.Migration) error {
+		if migrationFinished(migration.Status) {
+			return fmt.Errorf("%w: migration is %s", errMigrationState, migration.Status)
+		}
+		migration.Status = models.MigrationCancelled
+		return nil
+	})
+}
+
+// Switch moves search to the new model of a migration that is ready.
+func (m *Migrator) Switch(id string) (*models.Migration, error) {
+	return m.update(id, func(migration *models.Migration) error {
+		if migration.Status != models.MigrationReady {
+			return fmt.Errorf("%w: migration is %s", errMigrationState, migration.Status)
+		}
+		return m.switchTo(migration)
+	})
+}
+
+// Rollback moves search back to the model a migration switched from. Chunks
+// stored since the switch only have vectors of the new model, they are
+// embedded with the old one before returning.
+func (m *Migrator) Rollback(id string) (*models.Migration, error) {
+	return m.update(id, func(migration *models.Migration) error {
+		if migration.Status != models.MigrationSwitched {
+			return fmt.Errorf("%w: migration is %s", errMigrationState, migration.Status)
+		}
+
+		// A later migration reused the slot this one switched from
+		latest, err := m.mongodb.ListMigrations(1)
+		if err != nil {
+			return err
+		}
+		if len(latest) == 0 || latest[0].ID != migration.ID {
+			return fmt.Errorf("%w: a later migration replaced the old vectors", errMigrationState)
+		}
+
+		if err := m.mongodb.SwitchSlot(models.VectorSlot{Path: migration.FromPath, Model: migration.FromModel}); err != nil {
+			return err
+		}
+		m.client.SetModel(migration.FromModel)
+		migration.Status = models.MigrationRolledBack
+		fmt.Printf("Rolled back search to model %s\n", migration.FromModel)
+
+		if err := m.embedPending(migration.FromPath, migration.FromModel); err != nil {
+			log.Printf("Warning: chunks stored since the switch are missing from search: %v", err)
+		}
+		return nil
+	})
+}
+
+// update stops the loop so it doesn't overwrite the migration, applies change
+// and saves the result.
+func (m *Migrator) update(id string, change func(*models.Migration) error) (*models.Migration, error) {
+	if m.client == nil {
+		return nil, errNoServiceEmbedder
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stopLoop()
+
+	migration, err := m.mongodb.GetMigration(id)
+	if err != nil {
+		return nil, err
+	}
+	if migration == nil {
+		return nil, nil
+	}
+
+	if err := change(migration); err != nil {
+		// A migration that was running continues
+		if migration.Status == models.MigrationRunning {
+			m.startLoop(migration)
+		}
+		return nil, err
+	}
+
+	migration.UpdatedAt = time.Now()
+	if err := m.mongodb.SaveMigration(migration); err != nil {
+		return nil, err
+	}
+	return migration, nil
+}
+
+func (m *Migrator) startLoop(migration *models.Migration) {
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go m.run(migration, m.stop, m.done)
+}
+
+func (m *Migrator) stopLoop() {
+	if m.stop == nil {
+		return
+	}
+	close(m.stop)
+	<-m.done
+	m.stop, m.done = nil, nil
+}
+
+func (m *Migrator) run(migration *models.Migration, stop, done chan struct{}) {
+	defer close(done)
+
+	err := m.fill(migration, stop)
+	if errors.Is(err, errMigrationStopped) {
+		return
+	}
+
+	if err != nil {
+		migration.Status = models.MigrationFailed
+		migration.Error = err.Error()
+		log.Printf("Warning: migration %s failed: %v", migration.ID, err)
+	} else {
+		migration.Status = models.MigrationReady
+		migration.Total = max(migration.Total, migration.Processed)
+		fmt.Printf("Migration %s embedded %d chunks with %s\n", migration.ID, migration.Processed, migration.ToModel)
+
+		if migration.AutoSwitch {
+			if err := m.switchTo(migration); err != nil {
+				migration.Error = err.Error()
+				log.Printf("Warning: migration %s could not switch: %v", migration.ID, err)
+			}
+		}
+	}
+
+	migration.UpdatedAt = time.Now()
+	if err := m.mongodb.SaveMigration(migration); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// fill embeds the chunks after the checkpoint, saving progress after every
+// batch.
+func (m *Migrator) fill(migration *models.Migration, stop chan struct{}) error {
+	for {
+		last, count, err := m.embedBatch(migration.ToPath, migration.ToModel, migration.Checkpoint, stop)
+		if err != nil || count == 0 {
+			return err
+		}
+
+		migration.Checkpoint = last
+		migration.Processed += int64(count)
+		migration.UpdatedAt = time.Now()
+		if err := m.mongodb.SaveMigration(migration); err != nil {
+			return err
+		}
+	}
+}
+
+// switchTo embeds what was stored since the migration got ready, then points
+// search at the new slot. Chunks stored during the switch are embedded right
+// after it.
+func (m *Migrator) switchTo(migration *models.Migration) error {
+	if err := m.fill(migration, nil); err != nil {
+		return err
+	}
+
+	if err := m.mongodb.SwitchSlot(models.VectorSlot{Path: migration.ToPath, Model: migration.ToModel}); err != nil {
+		return err
+	}
+	m.client.SetModel(migration.ToModel)
+	migration.Status = models.MigrationSwitched
+	migration.SwitchedAt = time.Now()
+	migration.Total = max(migration.Total, migration.Processed)
+	fmt.Printf("Switched search to model %s\n", migration.ToModel)
+
+	if err := m.fill(migration, nil); err != nil {
+		log.Printf("Warning: chunks stored during the switch are missing from search: %v", err)
+	}
+	return nil
+}
+
+// embedPending embeds every chunk without a vector of model at path.
+func (m *Migrator) embedPending(path, model string) error {
+	after := ""
+	for {
+		last, count, err := m.embedBatch(path, model, after, nil)
+		if err != nil || count == 0 {
+			return err
+		}
+		after = last
+	}
+}
+
+// embedBatch embeds the next batch and returns its last chunk and size.
+func (m *Migrator) embedBatch(path, model, after string, stop chan struct{}) (string, int, error) {
+	select {
+	case <-stop:
+		return after, 0, errMigrationStopped
+	default:
+	}
+
+	chunks, err := m.mongodb.PendingChunks(path, model, after, m.batchSize)
+	if err != nil || len(chunks) == 0 {
+		return after, 0, err
+	}
+
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+	vectors, err := m.client.CreateEmbeddings(texts, model)
+	if err != nil {
+		return after, 0, err
+	}
+	if err := m.mongodb.SetChunkVectors(path, model, chunks, vectors); err != nil {
+		return after, 0, err
+	}
+
+	return chunks[len(chunks)-1].ID.Hex(), len(chunks), nil
+}
+
+func migrationFinished(status string) bool {
+	return status == models.MigrationSwitched || status == models.MigrationRolledBack || status == models.MigrationCancelled
+}
+
+type migrationRequest struct {
+	Model  string `json:"model"`
+	Switch bool   `json:"switch"`
+}
+
+// HandleMigrations lists migrations and starts new ones.
+func HandleMigrations(w http.ResponseWriter, r *http.Request, migrator *Migrator, mongodb *storage.MongoDB) {
+	switch r.Method {
+	case http.MethodGet:
+		migrations, err := mongodb.ListMigrations(migrationHistoryLimit)
+		if err != nil {
+			http.Error(w, "Error listing migrations: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		slot, err := mongodb.SearchSlot()
+		if err != nil {
+			http.Error(w, "Error reading search slot: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"search": map[string]interface{}{
+				"path":  slot.Path,
+				"model": migrator.embedder.Model(),
+			},
+			"migrations": migrations,
+		})
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request migrationRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	migration, err := migrator.Start(strings.TrimSpace(request.Model), request.Switch)
+	if err != nil {
+		writeMigrationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(migrationResponse(migration))
+}
+
+// HandleMigration serves /migrations/{id} and the actions
+// POST /migrations/{id}/pause|resume|switch|rollback|cancel.
+func HandleMigration(w http.ResponseWriter, r *http.Request, migrator *Migrator, mongodb *storage.MongoDB) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/migrations/"), "/")
+	migrationID, action, _ := strings.Cut(path, "/")
+	if migrationID == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if action == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		migration, err := mongodb.GetMigration(migrationID)
+		if err != nil {
+			http.Error(w, "Error reading migration: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if migration == nil {
+			http.Error(w, "Migration not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(migrationResponse(migration))
+		return
+	}
+
+	actions := map[string]func(string) (*models.Migration, error){
+		"pause":    migrator.Pause,
+		"resume":   migrator.Resume,
+		"switch":   migrator.Switch,
+		"rollback": migrator.Rollback,
+		"cancel":   migrator.Cancel,
+	}
+	run, ok := actions[action]
+	if !ok {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	migration, err := run(migrationID)
+	if err != nil {
+		writeMigrationError(w, err)
+		return
+	}
+	if migration == nil {
+		http.Error(w, "Migration not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(migrationResponse(migration))
+}
+
+func migrationResponse(migration *models.Migration) map[string]interface{} {
+	progress := 0.0
+	if migration.Total > 0 {
+		progress = min(float64(migration.Processed)/float64(migration.Total), 1)
+	}
+	return map[string]interface{}{
+		"migration": migration,
+		"progress":  progress,
+	}
+}
+
+func writeMigrationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errMigrationInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errMigrationState):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Migration error: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,570 @@
+package reader
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+)
+
+// Office files are zip archives, a single part is never allowed to inflate
+// past maxOOXMLPartSize and all parts together not past maxOOXMLTotalSize
+const (
+	maxOOXMLPartSize  = 50 * 1024 * 1024
+	maxOOXMLTotalSize = 100 * 1024 * 1024
+)
+
+var errOOXMLBudget = errors.New("office document exceeds the total uncompressed size limit")
+
+var (
+	headingStyle = regexp.MustCompile(`(?i)^heading\s*([1-6])$`)
+	slidePart    = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+)
+
+// textBuilder collects extracted text as paragraphs, marking headings with
+// Markdown hashes so the processor's splitters keep the document structure.
+type textBuilder struct {
+	buf bytes.Buffer
+}
+
+func (b *textBuilder) heading(level int, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	b.paragraph(strings.Repeat("#", level) + " " + text)
+}
+
+func (b *textBuilder) paragraph(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if b.buf.Len() > 0 {
+		b.buf.WriteString("\n\n")
+	}
+	b.buf.WriteString(text)
+}
+
+func (b *textBuilder) bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// ooxmlPackage holds the parts of an office document and the inflated size
+// they share, like archiveWalker does for archives. Every part can be read
+// once, so relationships pointing many times at one bomb part don't multiply
+// it.
+type ooxmlPackage struct {
+	files  map[string]*zip.File
+	opened map[string]bool
+	total  int64
+}
+
+func extractOOXML(extract func(pkg *ooxmlPackage) ([]byte, error)) func(doc *models.Document) error {
+	return func(doc *models.Document) error {
+		content, err := doc.Content.Open()
+		if err != nil {
+			return err
+		}
+		defer content.Close()
+
+		archive, err := zip.NewReader(content, doc.Content.Size())
+		if err != nil {
+			return fmt.Errorf("failed to open office document: %w", err)
+		}
+
+		pkg := &ooxmlPackage{
+			files:  make(map[string]*zip.File, len(archive.File)),
+			opened: make(map[string]bool),
+		}
+		for _, f := range archive.File {
+			pkg.files[f.Name] = f
+		}
+
+		text, err := extract(pkg)
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(text)) == 0 {
+			return fmt.Errorf("no text found in document")
+		}
+
+		doc.ContentType = "text/plain; charset=utf-8"
+		return replaceContent(doc, text)
+	}
+}
+
+func (p *ooxmlPackage) open(name string) (*xml.Decoder, func() error, error) {
+	f, ok := p.files[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("missing part %s", name)
+	}
+	if p.opened[name] {
+		return nil, nil, fmt.Errorf("part %s is referenced more than once", name)
+	}
+	p.opened[name] = true
+	if f.UncompressedSize64 > maxOOXMLPartSize {
+		return nil, nil, fmt.Errorf("part %s is too large", name)
+	}
+	if p.total+int64(f.UncompressedSize64) > maxOOXMLTotalSize {
+		return nil, nil, errOOXMLBudget
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open part %s: %w", name, err)
+	}
+
+	return xml.NewDecoder(&partReader{r: rc, pkg: p, name: name}), rc.Close, nil
+}
+
+// partReader charges what a part inflates to against the package, declared
+// sizes can lie.
+type partReader struct {
+	r    io.Reader
+	pkg  *ooxmlPackage
+	name string
+	read int64
+}
+
+func (r *partReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.read += int64(n)
+	r.pkg.total += int64(n)
+	if r.read > maxOOXMLPartSize {
+		return n, fmt.Errorf("part %s is too large", r.name)
+	}
+	if r.pkg.total > maxOOXMLTotalSize {
+		return n, errOOXMLBudget
+	}
+	return n, err
+}
+
+func extractDOCX(pkg *ooxmlPackage) ([]byte, error) {
+	decoder, closePart, err := pkg.open("word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer closePart()
+
+	var (
+		out        textBuilder
+		para       strings.Builder
+		level      int
+		inText     bool
+		inProps    bool
+		cells      []string
+		row        []string
+		tableRows  [][]string
+		tableDepth int
+	)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse document.xml: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para.Reset()
+				level = 0
+			case "pPr":
+				inProps = true
+			case "pStyle":
+				level = headingLevel(attr(t, "val"))
+			case "t":
+				inText = true
+			case "tab":
+				if !inProps {
+					para.WriteString("\t")
+				}
+			case "br", "cr":
+				para.WriteString("\n")
+			case "tbl":
+				tableDepth++
+			case "tr":
+				row = nil
+			case "tc":
+				cells = nil
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "pPr":
+				inProps = false
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(para.String())
+				switch {
+				case tableDepth > 0:
+					cells = append(cells, text)
+				case level > 0:
+					out.heading(level, text)
+				default:
+					out.paragraph(text)
+				}
+			case "tc":
+				row = append(row, strings.Join(cells, " "))
+			case "tr":
+				tableRows = append(tableRows, row)
+			case "tbl":
+				tableDepth--
+				if tableDepth == 0 {
+					out.paragraph(formatTable(tableRows))
+					tableRows = nil
+				}
+			}
+		}
+	}
+
+	return out.bytes(), nil
+}
+
+func headingLevel(style string) int {
+	if strings.EqualFold(style, "Title") {
+		return 1
+	}
+	if m := headingStyle.FindStringSubmatch(style); m != nil {
+		level, _ := strconv.Atoi(m[1])
+		return level
+	}
+	return 0
+}
+
+func extractXLSX(pkg *ooxmlPackage) ([]byte, error) {
+	shared, err := readSharedStrings(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	sheets, err := workbookSheets(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	var out textBuilder
+	for _, sheet := range sheets {
+		rows, err := readSheetRows(pkg, sheet.part, shared)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		out.heading(1, sheet.name)
+		out.paragraph(formatTable(rows))
+	}
+
+	return out.bytes(), nil
+}
+
+func readSharedStrings(pkg *ooxmlPackage) ([]string, error) {
+	if _, ok := pkg.files["xl/sharedStrings.xml"]; !ok {
+		return nil, nil
+	}
+
+	decoder, closePart, err := pkg.open("xl/sharedStrings.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer closePart()
+
+	var (
+		strs    []string
+		current strings.Builder
+		inText  bool
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse shared strings: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				current.Reset()
+			case "t":
+				inText = true
+			}
+		case xml.CharData:
+			if inText {
+				current.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "si":
+				strs = append(strs, current.String())
+			}
+		}
+	}
+
+	return strs, nil
+}
+
+type sheetRef struct {
+	name string
+	part string
+}
+
+func workbookSheets(pkg *ooxmlPackage) ([]sheetRef, error) {
+	rels, err := readRelationships(pkg, "xl/_rels/workbook.xml.rels", "xl")
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, closePart, err := pkg.open("xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer closePart()
+
+	var sheets []sheetRef
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse workbook.xml: %w", err)
+		}
+
+		if t, ok := token.(xml.StartElement); ok && t.Name.Local == "sheet" {
+			if part, ok := rels[attr(t, "id")]; ok {
+				sheets = append(sheets, sheetRef{name: attr(t, "name"), part: part})
+			}
+		}
+	}
+
+	return sheets, nil
+}
+
+func readSheetRows(pkg *ooxmlPackage, part string, shared []string) ([][]string, error) {
+	decoder, closePart, err := pkg.open(part)
+	if err != nil {
+		return nil, err
+	}
+	defer closePart()
+
+	var (
+		rows     [][]string
+		row      []string
+		cellType string
+		value    strings.Builder
+		inValue  bool
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", part, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				row = nil
+			case "c":
+				cellType = attr(t, "t")
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				text := value.String()
+				if cellType == "s" {
+					if i, err := strconv.Atoi(text); err == nil && i >= 0 && i < len(shared) {
+						text = shared[i]
+					}
+				}
+				row = append(row, strings.TrimSpace(text))
+			case "row":
+				if strings.TrimSpace(strings.Join(row, "")) != "" {
+					rows = append(rows, row)
+				}
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+func extractPPTX(pkg *ooxmlPackage) ([]byte, error) {
+	type slide struct {
+		number int
+		part   string
+	}
+
+	var slides []slide
+	for name := range pkg.files {
+		if m := slidePart.FindStringSubmatch(name); m != nil {
+			number, _ := strconv.Atoi(m[1])
+			slides = append(slides, slide{number: number, part: name})
+		}
+	}
+	sort.Slice(slides, func(i, j int) bool { return slides[i].number < slides[j].number })
+
+	var out textBuilder
+	for _, s := range slides {
+		title, paragraphs, err := readSlide(pkg, s.part)
+		if err != nil {
+			return nil, err
+		}
+		if title == "" {
+			title = fmt.Sprintf("Slide %d", s.number)
+		}
+		out.heading(1, title)
+		for _, p := range paragraphs {
+			out.paragraph(p)
+		}
+	}
+
+	return out.bytes(), nil
+}
+
+func readSlide(pkg *ooxmlPackage, part string) (string, []string, error) {
+	decoder, closePart, err := pkg.open(part)
+	if err != nil {
+		return "", nil, err
+	}
+	defer closePart()
+
+	var (
+		title      string
+		paragraphs []string
+		para       strings.Builder
+		isTitle    bool
+		inText     bool
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to parse %s: %w", part, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sp":
+				isTitle = false
+			case "ph":
+				phType := attr(t, "type")
+				isTitle = phType == "title" || phType == "ctrTitle"
+			case "p":
+				para.Reset()
+			case "t":
+				inText = true
+			case "br":
+				para.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(para.String())
+				if text == "" {
+					continue
+				}
+				if isTitle && title == "" {
+					title = text
+				} else {
+					paragraphs = append(paragraphs, text)
+				}
+			}
+		}
+	}
+
+	return title, paragraphs, nil
+}
+
+func readRelationships(pkg *ooxmlPackage, name, base string) (map[string]string, error) {
+	decoder, closePart, err := pkg.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer closePart()
+
+	rels := make(map[string]string)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		if t, ok := token.(xml.StartElement); ok && t.Name.Local == "Relationship" {
+			target := attr(t, "Target")
+			if strings.HasPrefix(target, "/") {
+				target = strings.TrimPrefix(target, "/")
+			} else {
+				target = path.Join(base, target)
+			}
+			rels[attr(t, "Id")] = target
+		}
+	}
+
+	return rels, nil
+}
+
+func attr(element xml.StartElement, local string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func formatTable(rows [][]string) string {
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, strings.Join(row, " | "))
+	}
+	return strings.Join(lines, "\n")
+}
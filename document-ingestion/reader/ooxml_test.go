@@ -0,0 +1,104 @@
+package reader
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// workbook builds an XLSX whose sheets point at the given parts, each part
+// written from its reader.
+func workbook(t *testing.T, sheets []string, parts map[string]io.Reader) *ooxmlPackage {
+	t.Helper()
+
+	var rels, entries strings.Builder
+	for i, part := range sheets {
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Target="%s"/>`, i, strings.TrimPrefix(part, "xl/"))
+		fmt.Fprintf(&entries, `<sheet name="Sheet%d" r:id="rId%d"/>`, i+1, i)
+	}
+	files := map[string]io.Reader{
+		"xl/workbook.xml":            strings.NewReader(`<workbook><sheets>` + entries.String() + `</sheets></workbook>`),
+		"xl/_rels/workbook.xml.rels": strings.NewReader(`<Relationships>` + rels.String() + `</Relationships>`),
+	}
+	for name, r := range parts {
+		files[name] = r
+	}
+
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for name, r := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(f, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := &ooxmlPackage{files: make(map[string]*zip.File), opened: make(map[string]bool)}
+	for _, f := range archive.File {
+		pkg.files[f.Name] = f
+	}
+	return pkg
+}
+
+func sheet(text string) io.Reader {
+	return strings.NewReader(`<worksheet><sheetData><row><c t="inlineStr"><is><t>` + text + `</t></is></c></row></sheetData></worksheet>`)
+}
+
+func TestExtractXLSX(t *testing.T) {
+	pkg := workbook(t, []string{"xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"}, map[string]io.Reader{
+		"xl/worksheets/sheet1.xml": sheet("first"),
+		"xl/worksheets/sheet2.xml": sheet("second"),
+	})
+
+	text, err := extractXLSX(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(text), "first") || !strings.Contains(string(text), "second") {
+		t.Errorf("got %q", text)
+	}
+}
+
+func TestExtractXLSXPartReferencedTwice(t *testing.T) {
+	pkg := workbook(t, []string{"xl/worksheets/sheet1.xml", "xl/worksheets/sheet1.xml"}, map[string]io.Reader{
+		"xl/worksheets/sheet1.xml": sheet("repeated"),
+	})
+
+	if _, err := extractXLSX(pkg); err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Errorf("got %v, want the repeated part refused", err)
+	}
+}
+
+func TestExtractXLSXTotalBudget(t *testing.T) {
+	// Every sheet is below the part limit, together they are over the total
+	part := func() io.Reader {
+		filler := strings.NewReader(`<worksheet><sheetData>` + strings.Repeat(" ", maxOOXMLPartSize-64))
+		return io.MultiReader(filler, strings.NewReader(`</sheetData></worksheet>`))
+	}
+	var sheets []string
+	parts := make(map[string]io.Reader)
+	for i := range maxOOXMLTotalSize/maxOOXMLPartSize + 1 {
+		name := fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)
+		sheets = append(sheets, name)
+		parts[name] = part()
+	}
+	pkg := workbook(t, sheets, parts)
+
+	if _, err := extractXLSX(pkg); !errors.Is(err, errOOXMLBudget) {
+		t.Errorf("got %v, want %v", err, errOOXMLBudget)
+	}
+}
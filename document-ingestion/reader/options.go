@@ -0,0 +1,127 @@
+package reader
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/chunker"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+)
+
+// chunkingService leaves chunking to the processing service's defaults, it
+// overrides a CHUNK_STRATEGY for one upload.
+const chunkingService = "service"
+
+var languagePattern = regexp.MustCompile(`^[a-z]{2}$`)
+
+// processingOptions reads how an upload is processed from the chunking,
+// chunk_size, chunk_overlap, language and ocr parameters. Missing values fall
+// back to CHUNK_STRATEGY, CHUNK_SIZE, CHUNK_OVERLAP, DOCUMENT_LANGUAGE and
+// OCR. Chunk sizes must lie within CHUNK_MIN_SIZE and CHUNK_MAX_SIZE, and
+// OCR can only be asked for when OCR_ALLOWED.
+func processingOptions(values url.Values) (*models.ProcessingOptions, error) {
+	options := &models.ProcessingOptions{
+		ChunkStrategy: values.Get("chunking"),
+		Language:      values.Get("language"),
+	}
+	if options.ChunkStrategy == "" {
+		options.ChunkStrategy = config.String("CHUNK_STRATEGY", "")
+	}
+	if options.ChunkStrategy == chunkingService {
+		options.ChunkStrategy = ""
+	}
+	if options.Language == "" {
+		options.Language = config.String("DOCUMENT_LANGUAGE", "")
+	}
+
+	ocr, err := boolParameter(values, "ocr", config.Bool("OCR", false))
+	if err != nil {
+		return nil, err
+	}
+	if ocr && !config.Bool("OCR_ALLOWED", true) {
+		return nil, fmt.Errorf("ocr is not enabled on this deployment")
+	}
+	options.OCR = ocr
+
+	if options.Language != "" && !languagePattern.MatchString(options.Language) {
+		return nil, fmt.Errorf("language must be a two letter ISO 639-1 code")
+	}
+
+	// The processing service's defaults apply without a strategy, unless a
+	// size is given
+	defaultSize := 0
+	switch options.ChunkStrategy {
+	case "":
+	case chunker.Token:
+		defaultSize = 256
+	default:
+		defaultSize = 1000
+	}
+	size, err := intParameter(values, "chunk_size", config.Int("CHUNK_SIZE", defaultSize))
+	if err != nil {
+		return nil, err
+	}
+	overlap, err := intParameter(values, "chunk_overlap", config.Int("CHUNK_OVERLAP", size/10))
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 && options.ChunkStrategy == "" {
+		return options, nil
+	}
+
+	minSize, maxSize := config.Int("CHUNK_MIN_SIZE", 50), config.Int("CHUNK_MAX_SIZE", 8000)
+	if size < minSize || size > maxSize {
+		return nil, fmt.Errorf("chunk_size must be between %d and %d", minSize, maxSize)
+	}
+
+	strategy := options.ChunkStrategy
+	if strategy == "" {
+		strategy = chunker.Recursive
+	}
+	if _, err := chunker.New(chunker.Options{Strategy: strategy, Size: size, Overlap: overlap}); err != nil {
+		return nil, err
+	}
+
+	options.ChunkSize = size
+	options.ChunkOverlap = overlap
+	return options, nil
+}
+
+// defaultProcessing are the options of documents that don't arrive with a
+// request, such as watched files.
+func defaultProcessing() *models.ProcessingOptions {
+	options, err := processingOptions(nil)
+	if err != nil {
+		log.Printf("Warning: invalid processing settings, using the processing service's defaults: %v", err)
+		return &models.ProcessingOptions{}
+	}
+	return options
+}
+
+func intParameter(values url.Values, name string, fallback int) (int, error) {
+	value := values.Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	return n, nil
+}
+
+func boolParameter(values url.Values, name string, fallback bool) (bool, error) {
+	value := values.Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", name)
+	}
+	return b, nil
+}
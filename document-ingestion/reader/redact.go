@@ -0,0 +1,122 @@
+package reader
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/redact"
+)
+
+// PII_MODE values. Masking replaces personal data in the stored chunk text and
+// document metadata, tagging keeps the text and only flags the chunks.
+const (
+	piiOff  = "off"
+	piiMask = "mask"
+	piiTag  = "tag"
+)
+
+var (
+	piiMode  string
+	redactor *redact.Redactor
+)
+
+// LoadRedaction reads the PII settings and rules. It runs at startup so a bad
+// rules file stops the server before it accepts uploads.
+func LoadRedaction() error {
+	piiMode = config.String("PII_MODE", piiOff)
+	if piiMode == piiOff {
+		return nil
+	}
+	if piiMode != piiMask && piiMode != piiTag {
+		log.Printf("Warning: unknown PII_MODE %s, masking personal data", piiMode)
+		piiMode = piiMask
+	}
+
+	rules := redact.Builtin()
+	if path := config.String("PII_RULES_FILE", ""); path != "" {
+		custom, err := redact.LoadRules(path)
+		if err != nil {
+			return fmt.Errorf("failed to load PII rules: %w", err)
+		}
+		rules = append(custom, rules...)
+	}
+	if names := config.List("PII_RULES"); len(names) > 0 {
+		selected, err := redact.Select(rules, names)
+		if err != nil {
+			return fmt.Errorf("failed to select PII rules: %w", err)
+		}
+		rules = selected
+	}
+
+	redactor = redact.New(rules)
+	fmt.Printf("PII detection in %s mode with rules %v\n", piiMode, redactor.Rules())
+	return nil
+}
+
+// redactChunks runs between processing and storing the chunks. It flags the
+// chunks that contain personal data, masks it when configured and records how
+// much of each kind the document had.
+//
+// The vectors were computed from the original text. They don't reveal the
+// masked values but may still place a chunk near queries for them.
+func redactChunks(doc *models.Document, chunks []*models.DocumentChunk) {
+	if redactor == nil {
+		return
+	}
+
+	// The counts start from those redactMetadata found
+	counts := make(map[string]int)
+	for rule, count := range doc.PII {
+		counts[rule] = count
+	}
+	for _, chunk := range chunks {
+		var findings []redact.Finding
+		if piiMode == piiMask {
+			chunk.Text, findings = redactor.Mask(chunk.Text)
+		} else {
+			findings = redactor.Find(chunk.Text)
+		}
+
+		chunk.PII = nil
+		for _, finding := range findings {
+			if !containsString(chunk.PII, finding.Rule) {
+				chunk.PII = append(chunk.PII, finding.Rule)
+			}
+			counts[finding.Rule]++
+		}
+	}
+
+	doc.PII = counts
+	if len(counts) > 0 {
+		fmt.Printf("Found personal data in document %s: %v\n", doc.ID, counts)
+	}
+}
+
+// redactMetadata runs before the document is first stored. Metadata such as
+// the sender and recipients of an email is shown and searched as the chunk
+// text is, so it is masked the same way.
+func redactMetadata(doc *models.Document) {
+	if redactor == nil {
+		return
+	}
+
+	counts := make(map[string]int)
+	for key, value := range doc.Metadata {
+		var findings []redact.Finding
+		if piiMode == piiMask {
+			doc.Metadata[key], findings = redactor.Mask(value)
+		} else {
+			findings = redactor.Find(value)
+		}
+		for _, finding := range findings {
+			counts[finding.Rule]++
+		}
+	}
+
+	doc.PII = nil
+	if len(counts) > 0 {
+		doc.PII = counts
+	}
+}
@@ -0,0 +1,31 @@
+package reader
+
+import (
+	"testing"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/redact"
+)
+
+func TestRedactMetadata(t *testing.T) {
+	previousMode, previousRedactor := piiMode, redactor
+	t.Cleanup(func() { piiMode, redactor = previousMode, previousRedactor })
+	piiMode, redactor = piiMask, redact.New(redact.Builtin())
+
+	doc := &models.Document{Metadata: map[string]string{
+		"from":    "Jane Doe <jane@example.com>",
+		"to":      "bob@example.com, carol@example.com",
+		"subject": "Invoice",
+	}}
+	redactMetadata(doc)
+
+	if doc.Metadata["from"] != "Jane Doe <[EMAIL]>" || doc.Metadata["to"] != "[EMAIL], [EMAIL]" || doc.Metadata["subject"] != "Invoice" {
+		t.Errorf("got metadata %v", doc.Metadata)
+	}
+
+	chunks := []*models.DocumentChunk{{Text: "Call jane@example.com"}}
+	redactChunks(doc, chunks)
+	if doc.PII["email"] != 4 || chunks[0].Text != "Call [EMAIL]" {
+		t.Errorf("got counts %v and chunk %q", doc.PII, chunks[0].Text)
+	}
+}
@@ -0,0 +1,411 @@
+package reader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/processor"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/schedule"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/storage"
+)
+
+const refreshHistoryLimit = 20
+
+var errRefreshRunning = errors.New("refresh already running")
+
+// RefreshScheduler re-fetches URL sources when their schedule is due. Only
+// content that actually changed is processed again, as a new version of the
+// document.
+type RefreshScheduler struct {
+	fetcher     *URLFetcher
+	client      processor.Processor
+	mongodb     *storage.MongoDB
+	poll        time.Duration
+	minInterval time.Duration
+	slots       chan struct{}
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+func NewRefreshScheduler(fetcher *URLFetcher, client processor.Processor, mongodb *storage.MongoDB) *RefreshScheduler {
+	return &RefreshScheduler{
+		fetcher:     fetcher,
+		client:      client,
+		mongodb:     mongodb,
+		poll:        max(config.Duration("REFRESH_POLL_INTERVAL", time.Minute), time.Second),
+		minInterval: config.Duration("REFRESH_MIN_INTERVAL", 5*time.Minute),
+		slots:       make(chan struct{}, max(config.Int("REFRESH_WORKERS", 2), 1)),
+		running:     make(map[string]bool),
+	}
+}
+
+func (s *RefreshScheduler) Run() {
+	ticker := time.NewTicker(s.poll)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.runDue()
+	}
+}
+
+func (s *RefreshScheduler) runDue() {
+	now := time.Now()
+	sources, err := s.mongodb.DueSources(now)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+
+	for _, source := range sources {
+		next := time.Time{}
+		if sched, err := schedule.Parse(source.Schedule); err == nil {
+			next = sched.Next(now)
+		}
+		if next.IsZero() {
+			// Never due again, so it is switched off instead of polled forever
+			source.Enabled = false
+			log.Printf("Warning: disabling source %s, schedule %q has no next run", source.ID, source.Schedule)
+			if err := s.mongodb.UpdateSourceSettings(&source); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			continue
+		}
+
+		claimed, err := s.mongodb.ClaimSource(source.ID, source.NextRun, next)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		s.slots <- struct{}{}
+		go func(source models.RefreshSource) {
+			defer func() { <-s.slots }()
+			if _, err := s.refresh(source); err != nil {
+				log.Printf("Warning: refresh of source %s: %v", source.ID, err)
+			}
+		}(source)
+	}
+}
+
+// refresh fetches a source once and records the outcome in its history. A
+// conditional request is sent with the validators of the current version and
+// unchanged content is detected by its hash when the server ignores them.
+func (s *RefreshScheduler) refresh(source models.RefreshSource) (*models.Refresh, error) {
+	s.mu.Lock()
+	if s.running[source.ID] {
+		s.mu.Unlock()
+		return nil, errRefreshRunning
+	}
+	s.running[source.ID] = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, source.ID)
+		s.mu.Unlock()
+	}()
+
+	refresh := &models.Refresh{SourceID: source.ID, StartedAt: time.Now()}
+	newDocumentID, err := s.fetchVersion(source, refresh)
+	if err != nil {
+		refresh.Result = models.RefreshFailed
+		refresh.Error = err.Error()
+	}
+	refresh.FinishedAt = time.Now()
+
+	fmt.Printf("Refreshed source %s: %s\n", source.ID, refresh.Result)
+
+	if err := s.mongodb.RecordRefresh(refresh, newDocumentID); err != nil {
+		return refresh, err
+	}
+	return refresh, nil
+}
+
+func (s *RefreshScheduler) fetchVersion(source models.RefreshSource, refresh *models.Refresh) (string, error) {
+	var current *models.Document
+	if source.DocumentID != "" {
+		doc, err := s.mongodb.GetDocument(source.DocumentID)
+		if err != nil {
+			return "", err
+		}
+		current = doc
+	}
+
+	var etag, lastModified string
+	if current != nil && current.Source != nil {
+		etag, lastModified = current.Source.ETag, current.Source.LastModified
+	}
+
+	fetched, err := s.fetcher.fetch(context.Background(), source.URL, etag, lastModified)
+	if err != nil {
+		return "", err
+	}
+
+	if current != nil && (fetched.notModified || fetched.file.SHA256() == current.SHA256) {
+		refresh.Result = models.RefreshUnchanged
+		if fetched.notModified {
+			refresh.Result = models.RefreshNotModified
+		} else {
+			fetched.file.Remove()
+		}
+		refresh.DocumentID = current.ID
+		refresh.Version = current.Version
+		refresh.SHA256 = current.SHA256
+
+		updated := &models.Source{URL: source.URL, FetchedAt: time.Now()}
+		if current.Source != nil {
+			*updated = *current.Source
+			updated.FetchedAt = time.Now()
+		}
+		if fetched.etag != "" {
+			updated.ETag = fetched.etag
+		}
+		if fetched.lastModified != "" {
+			updated.LastModified = fetched.lastModified
+		}
+		return "", s.mongodb.UpdateDocumentSource(current.ID, updated)
+	}
+	if fetched.notModified {
+		return "", fmt.Errorf("remote answered not modified without a stored version")
+	}
+
+	doc, err := readFetched(fetched, source.URL)
+	if err != nil {
+		if quarantined := recordQuarantine(err, s.mongodb); quarantined != nil {
+			refresh.DocumentID = quarantined.ID
+		}
+		return "", err
+	}
+	doc.Version = 1
+	doc.Processing = defaultProcessing()
+	if current != nil {
+		doc.Version = max(current.Version, 1) + 1
+		// The new version is processed like the current one
+		if current.Processing != nil {
+			doc.Processing = current.Processing
+		}
+		refresh.PreviousID = current.ID
+	}
+
+	if err := IngestDocument(doc, s.client, s.mongodb); err != nil {
+		// The current version stays in place until a refresh succeeds
+		refresh.DocumentID = doc.ID
+		return "", err
+	}
+
+	if current != nil {
+		if err := s.mongodb.SupersedeDocument(current.ID, doc.ID); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	refresh.Result = models.RefreshUpdated
+	refresh.DocumentID = doc.ID
+	refresh.Version = doc.Version
+	refresh.SHA256 = doc.SHA256
+	return doc.ID, nil
+}
+
+// parseSchedule validates a schedule and refuses ones that would fetch a
+// source more often than REFRESH_MIN_INTERVAL.
+func (s *RefreshScheduler) parseSchedule(spec string) (schedule.Schedule, error) {
+	sched, err := schedule.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if sched.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("schedule %q never runs", spec)
+	}
+	if sched.MinInterval() < s.minInterval {
+		return nil, fmt.Errorf("schedule runs more often than every %s", s.minInterval)
+	}
+	return sched, nil
+}
+
+type sourceRequest struct {
+	URL        string `json:"url"`
+	DocumentID string `json:"document_id"`
+	Schedule   string `json:"schedule"`
+	Enabled    *bool  `json:"enabled"`
+}
+
+// HandleSources lists the refresh sources and registers new ones, either for
+// a document that was ingested from a URL or for a URL that is fetched on the
+// first run.
+func HandleSources(w http.ResponseWriter, r *http.Request, scheduler *RefreshScheduler, mongodb *storage.MongoDB) {
+	switch r.Method {
+	case http.MethodGet:
+		sources, err := mongodb.ListSources()
+		if err != nil {
+			http.Error(w, "Error listing sources: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"sources": sources})
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request sourceRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sched, err := scheduler.parseSchedule(request.Schedule)
+	if err != nil {
+		http.Error(w, "Invalid schedule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	source := &models.RefreshSource{
+		ID:         uuid.New().String(),
+		URL:        strings.TrimSpace(request.URL),
+		Schedule:   request.Schedule,
+		Enabled:    request.Enabled == nil || *request.Enabled,
+		DocumentID: request.DocumentID,
+		CreatedAt:  time.Now(),
+		NextRun:    time.Now(),
+	}
+
+	if source.DocumentID != "" {
+		doc, err := mongodb.GetDocument(source.DocumentID)
+		if err != nil {
+			http.Error(w, "Error reading document: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if doc == nil {
+			http.Error(w, "Document not found", http.StatusNotFound)
+			return
+		}
+		if doc.Source == nil {
+			http.Error(w, "Document was not ingested from a URL", http.StatusBadRequest)
+			return
+		}
+		source.URL = doc.Source.URL
+		source.NextRun = sched.Next(time.Now())
+	}
+
+	target, err := url.Parse(source.URL)
+	if err != nil || target.Host == "" || checkScheme(target) != nil {
+		http.Error(w, "A valid http or https url is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := mongodb.SaveSource(source); err != nil {
+		http.Error(w, "Error saving source: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(source)
+}
+
+// HandleSource serves /sources/{id} and triggers an immediate refresh on
+// POST /sources/{id}/refresh.
+func HandleSource(w http.ResponseWriter, r *http.Request, scheduler *RefreshScheduler, mongodb *storage.MongoDB) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/sources/"), "/")
+	sourceID, action, _ := strings.Cut(path, "/")
+	if sourceID == "" || (action != "" && action != "refresh") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	source, err := mongodb.GetSource(sourceID)
+	if err != nil {
+		http.Error(w, "Error reading source: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if source == nil {
+		http.Error(w, "Source not found", http.StatusNotFound)
+		return
+	}
+
+	if action == "refresh" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		refresh, err := scheduler.refresh(*source)
+		if err == errRefreshRunning {
+			http.Error(w, "A refresh of this source is already running", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(refresh)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		history, err := mongodb.RefreshHistory(source.ID, refreshHistoryLimit)
+		if err != nil {
+			http.Error(w, "Error reading history: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"source":  source,
+			"history": history,
+		})
+
+	case http.MethodPut:
+		var request sourceRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if request.Schedule != "" {
+			sched, err := scheduler.parseSchedule(request.Schedule)
+			if err != nil {
+				http.Error(w, "Invalid schedule: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			source.Schedule = request.Schedule
+			source.NextRun = sched.Next(time.Now())
+		}
+		if request.Enabled != nil {
+			source.Enabled = *request.Enabled
+		}
+		if err := mongodb.UpdateSourceSettings(source); err != nil {
+			http.Error(w, "Error saving source: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(source)
+
+	case http.MethodDelete:
+		if _, err := mongodb.DeleteSource(source.ID); err != nil {
+			http.Error(w, "Error deleting source: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
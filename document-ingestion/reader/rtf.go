@@ -0,0 +1,302 @@
+package reader
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const maxRTFNesting = 256
+
+// Code pages RTF files declare with \ansicpg, bytes written as \'xx are in
+// the code page of the document
+var rtfCodePages = map[int]struct {
+	name     string
+	encoding encoding.Encoding
+}{
+	437:   {"ibm437", charmap.CodePage437},
+	850:   {"ibm850", charmap.CodePage850},
+	866:   {"ibm866", charmap.CodePage866},
+	874:   {"windows-874", charmap.Windows874},
+	1250:  {"windows-1250", charmap.Windows1250},
+	1251:  {"windows-1251", charmap.Windows1251},
+	1252:  {"windows-1252", charmap.Windows1252},
+	1253:  {"windows-1253", charmap.Windows1253},
+	1254:  {"windows-1254", charmap.Windows1254},
+	1255:  {"windows-1255", charmap.Windows1255},
+	1256:  {"windows-1256", charmap.Windows1256},
+	1257:  {"windows-1257", charmap.Windows1257},
+	1258:  {"windows-1258", charmap.Windows1258},
+	10000: {"macintosh", charmap.Macintosh},
+}
+
+// Groups that hold no document text, such as font tables and pictures
+var rtfSkippedGroups = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "listtable": true,
+	"listoverridetable": true, "revtbl": true, "rsidtbl": true, "generator": true,
+	"pict": true, "object": true, "themedata": true, "colorschememapping": true,
+	"datastore": true, "latentstyles": true, "xmlnstbl": true, "filetbl": true,
+	"header": true, "headerl": true, "headerr": true, "headerf": true,
+	"footer": true, "footerl": true, "footerr": true, "footerf": true,
+}
+
+// Document properties of the info group kept as metadata
+var rtfInfoFields = map[string]string{
+	"title":    "title",
+	"author":   "author",
+	"subject":  "subject",
+	"keywords": "keywords",
+}
+
+var rtfSymbols = map[string]string{
+	"par": "\n", "line": "\n", "sect": "\n\n", "page": "\n\n", "row": "\n",
+	"tab": "\t", "cell": "\t",
+	"emdash": "—", "endash": "–", "bullet": "•",
+	"lquote": "‘", "rquote": "’", "ldblquote": "“", "rdblquote": "”",
+	"emspace": " ", "enspace": " ", "qmspace": " ",
+}
+
+// preprocessRTF replaces RTF markup with the plain text of the document, so
+// every processor can read it.
+func preprocessRTF(doc *models.Document) error {
+	content, err := doc.Content.Bytes()
+	if err != nil {
+		return err
+	}
+
+	text, metadata, codePage, err := convertRTF(content)
+	if err != nil {
+		return err
+	}
+	for key, value := range metadata {
+		setMetadata(doc, key, value)
+	}
+
+	doc.Encoding = codePage
+	doc.ContentType = "text/plain; charset=utf-8"
+	return replaceContent(doc, normalizeText([]byte(text)))
+}
+
+type rtfGroup struct {
+	skip bool
+	// field is the info field the group's text belongs to
+	field string
+	// fallback is how many characters follow a \u character for readers
+	// that don't understand it
+	fallback int
+}
+
+// convertRTF returns the text of an RTF document, its document properties
+// and the code page its bytes were written in.
+func convertRTF(content []byte) (string, map[string]string, string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(content, " \t\r\n"), []byte(`{\rtf`)) {
+		return "", nil, "", fmt.Errorf("failed to parse rtf: missing {\\rtf header")
+	}
+
+	codePage := rtfCodePages[1252]
+	var text strings.Builder
+	info := make(map[string]*strings.Builder)
+	// pending holds code page bytes until the text they are part of ends
+	var pending []byte
+	// skipFallback counts the fallback characters still to drop after \u
+	skipFallback := 0
+	var highSurrogate rune
+	overflow := 0
+
+	stack := []rtfGroup{{fallback: 1}}
+	current := func() *rtfGroup { return &stack[len(stack)-1] }
+	out := func() *strings.Builder {
+		if g := current(); g.field != "" {
+			if info[g.field] == nil {
+				info[g.field] = &strings.Builder{}
+			}
+			return info[g.field]
+		}
+		return &text
+	}
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		decoded, _ := codePage.encoding.NewDecoder().Bytes(pending)
+		if !current().skip {
+			out().Write(decoded)
+		}
+		pending = pending[:0]
+	}
+	write := func(s string) {
+		flush()
+		if !current().skip {
+			out().WriteString(s)
+		}
+	}
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch c {
+		case '{':
+			flush()
+			if len(stack) == maxRTFNesting {
+				// Groups nested deeper share the state of the deepest one
+				overflow++
+			} else {
+				stack = append(stack, *current())
+			}
+			skipFallback = 0
+			continue
+		case '}':
+			flush()
+			if overflow > 0 {
+				overflow--
+			} else if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+			skipFallback = 0
+			continue
+		case '\r', '\n':
+			continue
+		case '\\':
+		default:
+			if skipFallback > 0 {
+				skipFallback--
+				continue
+			}
+			if c < utf8.RuneSelf {
+				write(string(c))
+			} else {
+				pending = append(pending, c)
+			}
+			continue
+		}
+
+		// Control words are letters with an optional number, control
+		// symbols are a single other character
+		i++
+		if i >= len(content) {
+			break
+		}
+		start := i
+		for i < len(content) && isASCIILetter(content[i]) {
+			i++
+		}
+		if i == start {
+			symbol := content[i]
+			if symbol == '\'' && i+2 < len(content) {
+				if b, err := strconv.ParseUint(string(content[i+1:i+3]), 16, 8); err == nil {
+					i += 2
+					if skipFallback > 0 {
+						skipFallback--
+					} else {
+						pending = append(pending, byte(b))
+					}
+					continue
+				}
+			}
+			if skipFallback > 0 {
+				skipFallback--
+				continue
+			}
+			switch symbol {
+			case '\\', '{', '}':
+				write(string(symbol))
+			case '~':
+				write("\u00a0")
+			case '_':
+				write("\u2011")
+			case '*':
+				current().skip = true
+			case '\r', '\n':
+				write("\n")
+			}
+			continue
+		}
+
+		word := string(content[start:i])
+		numberStart := i
+		if i < len(content) && content[i] == '-' {
+			i++
+		}
+		for i < len(content) && content[i] >= '0' && content[i] <= '9' && i-numberStart < 10 {
+			i++
+		}
+		param, hasParam := 0, i > numberStart
+		if hasParam {
+			param, _ = strconv.Atoi(string(content[numberStart:i]))
+		}
+		// One space ends the control word and belongs to it
+		if i >= len(content) || content[i] != ' ' {
+			i--
+		}
+
+		switch {
+		case word == "bin" && hasParam:
+			// Binary data follows, it is never text
+			flush()
+			i = min(i+max(param, 0), len(content))
+		case word == "u" && hasParam:
+			if param < 0 {
+				param += 0x10000
+			}
+			// Characters outside the BMP are written as surrogate pairs
+			r := rune(param)
+			switch {
+			case utf16.IsSurrogate(r) && r < 0xdc00:
+				highSurrogate = r
+			case utf16.IsSurrogate(r):
+				write(string(utf16.DecodeRune(highSurrogate, r)))
+				highSurrogate = 0
+			default:
+				write(string(r))
+			}
+			skipFallback = current().fallback
+		case word == "uc" && hasParam:
+			current().fallback = max(param, 0)
+		case word == "ansicpg" && hasParam:
+			if page, ok := rtfCodePages[param]; ok {
+				flush()
+				codePage = page
+			}
+		case word == "mac":
+			codePage = rtfCodePages[10000]
+		case word == "pc":
+			codePage = rtfCodePages[437]
+		case word == "pca":
+			codePage = rtfCodePages[850]
+		case rtfSkippedGroups[word]:
+			flush()
+			current().skip = true
+		case word == "info":
+			// Only the fields kept as metadata are read from the info group
+			flush()
+			current().skip = true
+		case rtfInfoFields[word] != "":
+			flush()
+			current().skip = false
+			current().field = rtfInfoFields[word]
+		case rtfSymbols[word] != "":
+			if skipFallback > 0 {
+				skipFallback--
+				continue
+			}
+			write(rtfSymbols[word])
+		}
+	}
+	flush()
+
+	metadata := make(map[string]string)
+	for field, value := range info {
+		metadata[field] = strings.TrimSpace(value.String())
+	}
+	return text.String(), metadata, codePage.name, nil
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,84 @@
+package reader
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
+)
+
+func TestConvertRTF(t *testing.T) {
+	tests := []struct {
+		name, rtf, text string
+	}{
+		{"plain", `{\rtf1\ansi Hello \b world\b0 .\par Next line}`, "Hello world.\nNext line"},
+		{"code page", `{\rtf1\ansi\ansicpg1251 \'cf\'f0\'e8\'e2\'e5\'f2}`, "Привет"},
+		{"default code page", `{\rtf1\ansi caf\'e9}`, "café"},
+		{"unicode with fallback", `{\rtf1\uc1 na\u239?ve \u-10180?\u-8960?}`, "naïve 🄀"},
+		{"longer fallback", `{\rtf1\uc2 \u8364\'80\'80 price}`, "€ price"},
+		{"escapes", `{\rtf1 \{braces\} and \\ back\~slash}`, "{braces} and \\ back\u00a0slash"},
+		{"skipped groups", `{\rtf1{\fonttbl{\f0 Arial;}}{\colortbl;\red0;}{\*\generator Writer;}{\pict 89504e47}Body}`, "Body"},
+		{"binary data", `{\rtf1 a{\bin4 }{}\}b}`, "ab"},
+		{"line breaks ignored", "{\\rtf1 one\r\ntwo}", "onetwo"},
+	}
+	for _, test := range tests {
+		text, _, _, err := convertRTF([]byte(test.rtf))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if text != test.text {
+			t.Errorf("%s: got %q, want %q", test.name, text, test.text)
+		}
+	}
+}
+
+func TestConvertRTFInfo(t *testing.T) {
+	text, metadata, codePage, err := convertRTF([]byte(`{\rtf1\ansi\ansicpg1252{\info{\title Annual report}{\author J\'f6rg}{\company Acme}{\creatim\yr2026}}Text}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Text" || codePage != "windows-1252" {
+		t.Errorf("got text %q in %s", text, codePage)
+	}
+	if metadata["title"] != "Annual report" || metadata["author"] != "Jörg" || len(metadata) != 2 {
+		t.Errorf("got metadata %v", metadata)
+	}
+}
+
+func TestConvertRTFDeepNesting(t *testing.T) {
+	depth := maxRTFNesting * 4
+	rtf := `{\rtf1 ` + strings.Repeat("{", depth) + "deep" + strings.Repeat("}", depth) + " end}"
+	text, _, _, err := convertRTF([]byte(rtf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "deep end" {
+		t.Errorf("got %q", text)
+	}
+
+	if _, _, _, err := convertRTF([]byte("plain text")); err == nil {
+		t.Error("text without the rtf header was converted")
+	}
+}
+
+// RTF reaches the processor as plain text, which every processor reads
+func TestReadRTF(t *testing.T) {
+	file, err := spool.FromBytes([]byte(`{\rtf1\ansi{\info{\title Memo}}Meeting moved to Friday.\par}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ReadSpooled(file, "memo.rtf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer discardContent(doc)
+
+	content, err := doc.Content.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ContentType != "text/plain; charset=utf-8" || string(content) != "Meeting moved to Friday.\n" || doc.Metadata["title"] != "Memo" {
+		t.Errorf("got %s %q %v", doc.ContentType, content, doc.Metadata)
+	}
+}
@@ -0,0 +1,214 @@
+package reader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/scanner"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/storage"
+)
+
+// Values of the virus_scan metadata entry
+const (
+	scanClean   = "clean"
+	scanSkipped = "skipped"
+)
+
+// errScanUnavailable is returned when the scanner can't be reached and the
+// scan is configured to fail closed.
+var errScanUnavailable = errors.New("virus scan unavailable")
+
+var (
+	scanOnce      sync.Once
+	scanAddress   string
+	virusScanner  *scanner.Clamd
+	scanFailOpen  bool
+	quarantineDir string
+)
+
+func scanSettings() {
+	scanOnce.Do(func() {
+		scanAddress = config.String("CLAMD_ADDRESS", "")
+		if scanAddress == "" {
+			return
+		}
+
+		clamd, err := scanner.NewClamd(scanAddress, config.Duration("CLAMD_TIMEOUT", 60*time.Second))
+		if err != nil {
+			// A broken address must not silently disable scanning, every
+			// upload fails the scan instead
+			log.Printf("Warning: %v", err)
+		}
+
+		virusScanner = clamd
+		scanFailOpen = config.Bool("SCAN_FAIL_OPEN", false)
+		quarantineDir = config.String("QUARANTINE_DIR", "quarantine")
+		if clamd != nil {
+			fmt.Printf("Scanning uploads with clamd at %s\n", clamd)
+		}
+	})
+}
+
+// scanEnabled reports whether uploads go through the virus scanner.
+func scanEnabled() bool {
+	scanSettings()
+	return scanAddress != ""
+}
+
+// QuarantineError is returned for infected uploads. The document records the
+// quarantined file and still has to be saved.
+type QuarantineError struct {
+	Document  *models.Document
+	Signature string
+}
+
+func (e *QuarantineError) Error() string {
+	return "file is infected: " + e.Signature
+}
+
+// scanFile sends the upload to the virus scanner and returns the verdict for
+// the virus_scan metadata entry, or nothing when scanning is disabled.
+// Infected files are moved to the quarantine directory and no longer belong
+// to the caller.
+func scanFile(file *spool.File, filename string) (string, error) {
+	if !scanEnabled() {
+		return "", nil
+	}
+
+	result, err := scanContent(file)
+	if err != nil {
+		if scanFailOpen {
+			log.Printf("Warning: accepting %s without virus scan: %v", filename, err)
+			return scanSkipped, nil
+		}
+		file.Remove()
+		return "", fmt.Errorf("%w: %v", errScanUnavailable, err)
+	}
+	if !result.Infected {
+		return scanClean, nil
+	}
+
+	doc := &models.Document{
+		ID:         uuid.New().String(),
+		FileName:   filename,
+		Size:       file.Size(),
+		SHA256:     file.SHA256(),
+		UploadedAt: time.Now(),
+		Status:     models.StatusQuarantined,
+		Error:      "infected: " + result.Signature,
+		Kind:       models.KindDocument,
+		Metadata:   map[string]string{"virus_signature": result.Signature},
+	}
+
+	path, err := quarantineFile(file, doc.ID)
+	if err != nil {
+		log.Printf("Warning: failed to quarantine %s, the file is dropped: %v", filename, err)
+	}
+	setMetadata(doc, "quarantine_path", path)
+
+	fmt.Printf("Quarantined %s as %s: %s\n", filename, doc.ID, result.Signature)
+	return "", &QuarantineError{Document: doc, Signature: result.Signature}
+}
+
+func scanContent(file *spool.File) (*scanner.Result, error) {
+	if virusScanner == nil {
+		return nil, fmt.Errorf("invalid CLAMD_ADDRESS")
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	return virusScanner.Scan(context.Background(), content)
+}
+
+// quarantineFile moves the spooled content out of the way of the pipeline.
+// The spool file is removed either way.
+func quarantineFile(file *spool.File, id string) (string, error) {
+	defer file.Remove()
+
+	if err := os.MkdirAll(quarantineDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+
+	out, err := os.CreateTemp(quarantineDir, ".quarantine-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create quarantine file: %w", err)
+	}
+	_, err = io.Copy(out, content)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("failed to write quarantine file: %w", err)
+	}
+
+	path := filepath.Join(quarantineDir, id)
+	if err := os.Rename(out.Name(), path); err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("failed to move quarantine file: %w", err)
+	}
+	return path, nil
+}
+
+// recordQuarantine saves the document of an infected upload and returns it,
+// or nil when err is not a quarantine.
+func recordQuarantine(err error, mongodb *storage.MongoDB) *models.Document {
+	var infected *QuarantineError
+	if !errors.As(err, &infected) {
+		return nil
+	}
+
+	if err := mongodb.InsertDocuments(infected.Document); err != nil {
+		log.Printf("Warning: failed to record quarantined document %s: %v", infected.Document.ID, err)
+	}
+	return infected.Document
+}
+
+// writeScanFailure answers for uploads that were quarantined or couldn't be
+// scanned and reports whether it did.
+func writeScanFailure(w http.ResponseWriter, err error, mongodb *storage.MongoDB) bool {
+	if errors.Is(err, errScanUnavailable) {
+		http.Error(w, "Virus scan unavailable, try again later", http.StatusServiceUnavailable)
+		return true
+	}
+
+	doc := recordQuarantine(err, mongodb)
+	if doc == nil {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"document_id": doc.ID,
+		"filename":    doc.FileName,
+		"status":      doc.Status,
+		"error":       doc.Error,
+		"signature":   doc.Metadata["virus_signature"],
+	})
+	return true
+}
@@ -0,0 +1,152 @@
+package reader
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/scanner"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
+)
+
+// fakeClamd answers every INSTREAM with reply.
+func fakeClamd(t *testing.T, reply string) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				if _, err := r.ReadString(0); err != nil {
+					return
+				}
+				for {
+					var size [4]byte
+					if _, err := io.ReadFull(r, size[:]); err != nil {
+						return
+					}
+					n := binary.BigEndian.Uint32(size[:])
+					if n == 0 {
+						break
+					}
+					if _, err := io.CopyN(io.Discard, r, int64(n)); err != nil {
+						return
+					}
+				}
+				conn.Write([]byte(reply + "\x00"))
+			}()
+		}
+	}()
+
+	return "tcp://" + listener.Addr().String()
+}
+
+// closedAddress is a local port nothing listens on.
+func closedAddress(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	return "tcp://" + address
+}
+
+// useScanner configures scanning for one test.
+func useScanner(t *testing.T, address string, failOpen bool) {
+	t.Helper()
+	scanSettings()
+
+	clamd, err := scanner.NewClamd(address, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address0, scanner0, failOpen0, dir0 := scanAddress, virusScanner, scanFailOpen, quarantineDir
+	scanAddress, virusScanner, scanFailOpen, quarantineDir = address, clamd, failOpen, t.TempDir()
+	t.Cleanup(func() {
+		scanAddress, virusScanner, scanFailOpen, quarantineDir = address0, scanner0, failOpen0, dir0
+	})
+}
+
+func spooled(t *testing.T, content string) *spool.File {
+	t.Helper()
+	file, err := spool.FromBytes([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Remove() })
+	return file
+}
+
+func TestScanFileClean(t *testing.T) {
+	useScanner(t, fakeClamd(t, "stream: OK"), false)
+
+	verdict, err := scanFile(spooled(t, "plain text"), "notes.txt")
+	if err != nil {
+		t.Fatalf("clean file failed the scan: %v", err)
+	}
+	if verdict != scanClean {
+		t.Errorf("got verdict %q, want %q", verdict, scanClean)
+	}
+}
+
+func TestScanFileQuarantines(t *testing.T) {
+	useScanner(t, fakeClamd(t, "stream: Eicar-Test-Signature FOUND"), false)
+
+	_, err := scanFile(spooled(t, "X5O!P%@AP"), "eicar.txt")
+	var infected *QuarantineError
+	if !errors.As(err, &infected) {
+		t.Fatalf("got %v, want a QuarantineError", err)
+	}
+
+	doc := infected.Document
+	if doc.Status != models.StatusQuarantined || infected.Signature != "Eicar-Test-Signature" {
+		t.Errorf("got status %s and signature %s", doc.Status, infected.Signature)
+	}
+	content, err := os.ReadFile(doc.Metadata["quarantine_path"])
+	if err != nil {
+		t.Fatalf("quarantined file missing: %v", err)
+	}
+	if string(content) != "X5O!P%@AP" {
+		t.Errorf("quarantined file holds %q", content)
+	}
+}
+
+func TestScanFileFailsClosed(t *testing.T) {
+	useScanner(t, closedAddress(t), false)
+
+	_, err := scanFile(spooled(t, "plain text"), "notes.txt")
+	if !errors.Is(err, errScanUnavailable) {
+		t.Errorf("got %v, want %v", err, errScanUnavailable)
+	}
+}
+
+func TestScanFileFailsOpen(t *testing.T) {
+	useScanner(t, closedAddress(t), true)
+
+	verdict, err := scanFile(spooled(t, "plain text"), "notes.txt")
+	if err != nil {
+		t.Fatalf("unreachable scanner refused the file: %v", err)
+	}
+	if verdict != scanSkipped {
+		t.Errorf("got verdict %q, want %q", verdict, scanSkipped)
+	}
+}
@@ -0,0 +1,675 @@
+package reader
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/processor"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/storage"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,expiration"
+	tusPath       = "/files/"
+)
+
+var (
+	errUploadNotFound = errors.New("upload not found")
+	errUploadTooLarge = errors.New("upload exceeds its declared length")
+)
+
+// TusStore keeps partial tus uploads on local disk. Every upload is a data
+// file that PATCH requests append to and a small JSON info file, so an upload
+// can be resumed after the service restarts.
+type TusStore struct {
+	dir     string
+	maxSize int64
+	ttl     time.Duration
+
+	mu   sync.Mutex
+	busy map[string]bool
+}
+
+type tusUpload struct {
+	ID          string                    `json:"id"`
+	FileName    string                    `json:"filename"`
+	Length      int64                     `json:"length"`
+	Offset      int64                     `json:"offset"`
+	Metadata    map[string]string         `json:"metadata,omitempty"`
+	RawMetadata string                    `json:"raw_metadata,omitempty"`
+	CreatedAt   time.Time                 `json:"created_at"`
+	ExpiresAt   time.Time                 `json:"expires_at"`
+	Completed   bool                      `json:"completed"`
+	Retry       bool                      `json:"retry,omitempty"`
+	Status      string                    `json:"status"`
+	DocumentID  string                    `json:"document_id,omitempty"`
+	Error       string                    `json:"error,omitempty"`
+	Rejections  []models.Rejection        `json:"rejections,omitempty"`
+	Processing  *models.ProcessingOptions `json:"processing,omitempty"`
+}
+
+func NewTusStore() (*TusStore, error) {
+	store := &TusStore{
+		dir:     config.String("TUS_DIR", filepath.Join(os.TempDir(), "tus-uploads")),
+		maxSize: config.Int64("TUS_MAX_SIZE", maxUploadSize),
+		ttl:     config.Duration("TUS_UPLOAD_TTL", 24*time.Hour),
+		busy:    make(map[string]bool),
+	}
+
+	if err := os.MkdirAll(store.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory %s: %w", store.dir, err)
+	}
+
+	go store.expireLoop(config.Duration("TUS_CLEANUP_INTERVAL", 10*time.Minute))
+
+	return store, nil
+}
+
+// HandleTus implements the tus 1.0 core protocol with the creation,
+// termination and expiration extensions. GET on an upload is not part of tus,
+// it reports what happened to the document once the upload completed.
+func HandleTus(w http.ResponseWriter, r *http.Request, store *TusStore, client processor.Processor, mongodb *storage.MongoDB) {
+	method := r.Method
+	if override := r.Header.Get("X-HTTP-Method-Override"); override != "" && method == http.MethodPost {
+		method = strings.ToUpper(override)
+	}
+
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	if method == http.MethodOptions {
+		w.Header().Set("Tus-Version", tusVersion)
+		w.Header().Set("Tus-Extension", tusExtensions)
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(store.maxSize, 10))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if method != http.MethodGet && r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		http.Error(w, "Unsupported tus version", http.StatusPreconditionFailed)
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(tusPath, "/")), "/")
+	if id == "" {
+		if method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		store.create(w, r)
+		return
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return
+	}
+
+	switch method {
+	case http.MethodHead, http.MethodPatch, http.MethodGet:
+		store.retry(id, client, mongodb)
+	}
+
+	switch method {
+	case http.MethodHead:
+		store.head(w, id)
+	case http.MethodPatch:
+		store.patch(w, r, id, client, mongodb)
+	case http.MethodDelete:
+		store.terminate(w, id)
+	case http.MethodGet:
+		store.status(w, id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *TusStore) create(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		http.Error(w, "Upload-Defer-Length is not supported", http.StatusBadRequest)
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		http.Error(w, "Upload-Length must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	if length > s.maxSize {
+		http.Error(w, fmt.Sprintf("Upload exceeds the %d byte limit", s.maxSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	rawMetadata := r.Header.Get("Upload-Metadata")
+	metadata, err := parseUploadMetadata(rawMetadata)
+	if err != nil {
+		http.Error(w, "Invalid Upload-Metadata: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Processing options travel in the metadata under the names of the
+	// /upload parameters
+	values := url.Values{}
+	for _, key := range []string{"chunking", "chunk_size", "chunk_overlap", "language", "ocr"} {
+		if value, ok := metadata[key]; ok {
+			values.Set(key, value)
+		}
+	}
+	options, err := processingOptions(values)
+	if err != nil {
+		http.Error(w, "Invalid processing options: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	upload := &tusUpload{
+		ID:          uuid.New().String(),
+		Length:      length,
+		Metadata:    metadata,
+		RawMetadata: rawMetadata,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   time.Now().Add(s.ttl),
+		Status:      models.StatusReceived,
+		Processing:  options,
+	}
+
+	// tus clients send the name as either "filename" or "name"
+	upload.FileName = filepath.Base(strings.ReplaceAll(metadata["filename"], "\\", "/"))
+	if metadata["filename"] == "" {
+		upload.FileName = filepath.Base(strings.ReplaceAll(metadata["name"], "\\", "/"))
+	}
+	if upload.FileName == "." || upload.FileName == "/" {
+		upload.FileName = "upload-" + upload.ID
+	}
+
+	data, err := os.OpenFile(s.dataPath(upload.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		http.Error(w, "Error creating upload: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data.Close()
+
+	if err := s.save(upload); err != nil {
+		os.Remove(s.dataPath(upload.ID))
+		http.Error(w, "Error creating upload: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Printf("Created upload %s for %s (%d bytes)\n", upload.ID, upload.FileName, upload.Length)
+
+	w.Header().Set("Location", tusPath+upload.ID)
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *TusStore) head(w http.ResponseWriter, id string) {
+	upload, ok := s.lookup(w, id)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if upload.RawMetadata != "" {
+		w.Header().Set("Upload-Metadata", upload.RawMetadata)
+	}
+	if !upload.Completed || upload.Retry {
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *TusStore) patch(w http.ResponseWriter, r *http.Request, id string, client processor.Processor, mongodb *storage.MongoDB) {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		http.Error(w, "Content-Type must be application/offset+octet-stream", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Upload-Offset must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+
+	// A client that retries while its old request is still being read would
+	// otherwise interleave both bodies
+	if !s.acquire(id) {
+		http.Error(w, "Upload is locked by another request", http.StatusLocked)
+		return
+	}
+	defer s.release(id)
+
+	upload, ok := s.lookup(w, id)
+	if !ok {
+		return
+	}
+	if upload.Completed {
+		http.Error(w, "Upload is already complete", http.StatusForbidden)
+		return
+	}
+	if offset != upload.Offset {
+		http.Error(w, fmt.Sprintf("Upload-Offset %d does not match the current offset %d", offset, upload.Offset), http.StatusConflict)
+		return
+	}
+
+	written, copyErr := s.appendChunk(upload, r.Body)
+	if copyErr == errUploadTooLarge {
+		http.Error(w, "Upload exceeds its declared Upload-Length", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Whatever arrived before a dropped connection is kept so the client can
+	// resume from there
+	upload.Offset += written
+	upload.ExpiresAt = time.Now().Add(s.ttl)
+	if err := s.save(upload); err != nil {
+		http.Error(w, "Error saving upload: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if copyErr != nil {
+		log.Printf("Warning: upload %s interrupted at offset %d: %v", upload.ID, upload.Offset, copyErr)
+		http.Error(w, "Error reading upload data: "+copyErr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if upload.Offset == upload.Length {
+		s.complete(upload, client, mongodb)
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if !upload.Completed {
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *TusStore) appendChunk(upload *tusUpload, body io.Reader) (int64, error) {
+	data, err := os.OpenFile(s.dataPath(upload.ID), os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open upload data: %w", err)
+	}
+	defer data.Close()
+
+	// The recorded offset is authoritative, bytes written after it by a
+	// request that crashed before saving are dropped
+	if err := data.Truncate(upload.Offset); err != nil {
+		return 0, fmt.Errorf("failed to prepare upload data: %w", err)
+	}
+	if _, err := data.Seek(upload.Offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to prepare upload data: %w", err)
+	}
+
+	remaining := upload.Length - upload.Offset
+	written, err := io.Copy(data, io.LimitReader(body, remaining))
+	if err == nil && written == remaining {
+		var extra [1]byte
+		if n, _ := body.Read(extra[:]); n > 0 {
+			// Nothing of an oversized chunk is kept
+			if err := data.Truncate(upload.Offset); err != nil {
+				log.Printf("Warning: failed to truncate upload %s: %v", upload.ID, err)
+			}
+			return 0, errUploadTooLarge
+		}
+	}
+	if syncErr := data.Sync(); err == nil && syncErr != nil {
+		err = fmt.Errorf("failed to sync upload data: %w", syncErr)
+	}
+
+	return written, err
+}
+
+// complete hands the finished upload to the regular pipeline. The data file is
+// adopted by the spool and removed once the document has been ingested. When
+// reading fails for a reason that passes the data is kept and the upload is
+// read again by retry.
+func (s *TusStore) complete(upload *tusUpload, client processor.Processor, mongodb *storage.MongoDB) {
+	upload.Completed = true
+	upload.Retry = false
+
+	// The spool removes what it was given when reading fails, so it gets a
+	// link to the data
+	path := s.dataPath(upload.ID)
+	link := path + ".read"
+	os.Remove(link)
+	if err := os.Link(path, link); err != nil {
+		log.Printf("Warning: upload %s can't be retried if reading it fails: %v", upload.ID, err)
+		link = path
+	}
+
+	file, err := spool.Adopt(link)
+	var doc *models.Document
+	if err == nil {
+		doc, err = ReadSpooled(file, upload.FileName)
+	}
+	if err != nil {
+		os.Remove(link)
+		if retryable(err) && link != path {
+			log.Printf("Warning: upload %s will be read again: %v", upload.ID, err)
+			upload.Retry = true
+			upload.Status = models.StatusReceived
+			upload.Error = err.Error()
+			if err := s.save(upload); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			return
+		}
+
+		upload.Status = models.StatusRejected
+		upload.Error = err.Error()
+		var invalid *ValidationError
+		if errors.As(err, &invalid) {
+			upload.Rejections = invalid.Rejections
+		}
+		if quarantined := recordQuarantine(err, mongodb); quarantined != nil {
+			upload.Status = quarantined.Status
+			upload.DocumentID = quarantined.ID
+		}
+		os.Remove(path)
+		if err := s.save(upload); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		return
+	}
+	if link != path {
+		os.Remove(path)
+	}
+	upload.Error = ""
+
+	doc.ID = uuid.New().String()
+	doc.Processing = upload.Processing
+	if doc.Processing == nil {
+		// Uploads created before options were stored with them
+		doc.Processing = defaultProcessing()
+	}
+	upload.DocumentID = doc.ID
+	upload.Status = models.StatusProcessing
+	if err := s.save(upload); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	fmt.Printf("Upload %s complete, processing as document %s\n", upload.ID, doc.ID)
+
+	go func() {
+		if err := IngestDocument(doc, client, mongodb); err != nil {
+			upload.Error = err.Error()
+		}
+		upload.Status = doc.Status
+
+		// The upload may have been terminated while it was processed
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, err := os.Stat(s.infoPath(upload.ID)); err != nil {
+			return
+		}
+		if err := s.writeInfo(upload); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}()
+}
+
+// retry reads a completed upload again whose reading failed for a reason that
+// passes. Clients look at the upload again with HEAD or GET, so that is when
+// it happens.
+func (s *TusStore) retry(id string, client processor.Processor, mongodb *storage.MongoDB) {
+	if !s.acquire(id) {
+		return
+	}
+	defer s.release(id)
+
+	upload, err := s.load(id)
+	if err != nil || !upload.Retry || time.Now().After(upload.ExpiresAt) {
+		return
+	}
+	s.complete(upload, client, mongodb)
+}
+
+// terminate removes an upload. A completed upload has already been handed to
+// ingestion, so only its record is removed and the document is kept.
+func (s *TusStore) terminate(w http.ResponseWriter, id string) {
+	if !s.acquire(id) {
+		http.Error(w, "Upload is locked by another request", http.StatusLocked)
+		return
+	}
+	defer s.release(id)
+
+	upload, ok := s.lookup(w, id)
+	if !ok {
+		return
+	}
+
+	if err := s.remove(upload); err != nil {
+		http.Error(w, "Error terminating upload: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *TusStore) status(w http.ResponseWriter, id string) {
+	upload, ok := s.lookup(w, id)
+	if !ok {
+		return
+	}
+
+	summary := map[string]interface{}{
+		"upload_id": upload.ID,
+		"filename":  upload.FileName,
+		"offset":    upload.Offset,
+		"length":    upload.Length,
+		"status":    upload.Status,
+	}
+	if upload.DocumentID != "" {
+		summary["document_id"] = upload.DocumentID
+	}
+	if upload.Error != "" {
+		summary["error"] = upload.Error
+	}
+	if len(upload.Rejections) > 0 {
+		summary["rejections"] = upload.Rejections
+	}
+	if !upload.Completed || upload.Retry {
+		summary["expires_at"] = upload.ExpiresAt
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// lookup loads an upload and writes the error response when it is missing or
+// has expired.
+func (s *TusStore) lookup(w http.ResponseWriter, id string) (*tusUpload, bool) {
+	upload, err := s.load(id)
+	if err == errUploadNotFound {
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Error reading upload: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if time.Now().After(upload.ExpiresAt) {
+		if err := s.remove(upload); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		http.Error(w, "Upload has expired", http.StatusGone)
+		return nil, false
+	}
+
+	return upload, true
+}
+
+func (s *TusStore) load(id string) (*tusUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := os.ReadFile(s.infoPath(id))
+	if os.IsNotExist(err) {
+		return nil, errUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload %s: %w", id, err)
+	}
+
+	var upload tusUpload
+	if err := json.Unmarshal(raw, &upload); err != nil {
+		return nil, fmt.Errorf("failed to decode upload %s: %w", id, err)
+	}
+	return &upload, nil
+}
+
+func (s *TusStore) save(upload *tusUpload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeInfo(upload)
+}
+
+// writeInfo replaces the info file through a rename so a crash never leaves a
+// half written record behind. Callers hold s.mu.
+func (s *TusStore) writeInfo(upload *tusUpload) error {
+	raw, err := json.Marshal(upload)
+	if err != nil {
+		return fmt.Errorf("failed to encode upload %s: %w", upload.ID, err)
+	}
+
+	tmp := s.infoPath(upload.ID) + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return fmt.Errorf("failed to save upload %s: %w", upload.ID, err)
+	}
+	if err := os.Rename(tmp, s.infoPath(upload.ID)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save upload %s: %w", upload.ID, err)
+	}
+	return nil
+}
+
+func (s *TusStore) remove(upload *tusUpload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// After completion the data file belongs to the spool
+	if !upload.Completed {
+		if err := os.Remove(s.dataPath(upload.ID)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove upload %s: %w", upload.ID, err)
+		}
+	}
+	if err := os.Remove(s.infoPath(upload.ID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove upload %s: %w", upload.ID, err)
+	}
+	return nil
+}
+
+func (s *TusStore) expireLoop(interval time.Duration) {
+	ticker := time.NewTicker(max(interval, time.Minute))
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.expire()
+	}
+}
+
+// expire removes uploads that weren't touched within the TTL, including the
+// records of completed uploads.
+func (s *TusStore) expire() {
+	infos, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		log.Printf("Warning: failed to list uploads: %v", err)
+		return
+	}
+
+	for _, info := range infos {
+		id := strings.TrimSuffix(filepath.Base(info), ".json")
+		if !s.acquire(id) {
+			continue
+		}
+
+		upload, err := s.load(id)
+		if err == nil && time.Now().After(upload.ExpiresAt) {
+			if err := s.remove(upload); err != nil {
+				log.Printf("Warning: %v", err)
+			} else {
+				fmt.Printf("Expired upload %s\n", id)
+			}
+		} else if err != nil {
+			log.Printf("Warning: %v", err)
+		}
+
+		s.release(id)
+	}
+}
+
+func (s *TusStore) acquire(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.busy[id] {
+		return false
+	}
+	s.busy[id] = true
+	return true
+}
+
+func (s *TusStore) release(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.busy, id)
+}
+
+func (s *TusStore) dataPath(id string) string {
+	return filepath.Join(s.dir, id+".bin")
+}
+
+func (s *TusStore) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// parseUploadMetadata decodes the Upload-Metadata header, a comma separated
+// list of keys each followed by an optional base64 encoded value.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("malformed pair %q", strings.TrimSpace(pair))
+		}
+
+		key := fields[0]
+		if _, exists := metadata[key]; exists {
+			return nil, fmt.Errorf("duplicate key %q", key)
+		}
+
+		value := ""
+		if len(fields) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("value of %q is not base64", key)
+			}
+			value = string(decoded)
+		}
+		metadata[key] = value
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,91 @@
+package reader
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+)
+
+func tusRequest(t *testing.T, store *TusStore, method, target string, body string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Tus-Resumable", tusVersion)
+	for key, value := range header {
+		r.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	HandleTus(w, r, store, nil, nil)
+	return w
+}
+
+func tusStatus(t *testing.T, store *TusStore, location string) map[string]interface{} {
+	t.Helper()
+	var status map[string]interface{}
+	w := tusRequest(t, store, http.MethodGet, location, "", nil)
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("invalid status response %q: %v", w.Body, err)
+	}
+	return status
+}
+
+// Uploads that can't be scanned keep their data and are read again later
+func TestTusRetriesAfterScanOutage(t *testing.T) {
+	t.Setenv("TUS_DIR", t.TempDir())
+	store, err := NewTusStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	useScanner(t, closedAddress(t), false)
+
+	// Content of no supported format, so the retry ends in a rejection that
+	// doesn't need a processor or database
+	content := "\x00\x01\x02\x03 binary"
+	w := tusRequest(t, store, http.MethodPost, tusPath, "", map[string]string{
+		"Upload-Length":   strconv.Itoa(len(content)),
+		"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("data.bin")),
+	})
+	location := w.Header().Get("Location")
+	if w.Code != http.StatusCreated || location == "" {
+		t.Fatalf("creation failed with %d: %s", w.Code, w.Body)
+	}
+	id := location[strings.LastIndex(location, "/")+1:]
+
+	w = tusRequest(t, store, http.MethodPatch, location, content, map[string]string{
+		"Content-Type":  "application/offset+octet-stream",
+		"Upload-Offset": "0",
+	})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("patch failed with %d: %s", w.Code, w.Body)
+	}
+
+	// GET would retry as well, the scanner is still down
+	status := tusStatus(t, store, location)
+	if status["status"] != models.StatusReceived || !strings.Contains(status["error"].(string), "virus scan unavailable") {
+		t.Errorf("got %v after the scanner outage", status)
+	}
+	if _, err := os.Stat(store.dataPath(id)); err != nil {
+		t.Fatalf("upload data removed after the scanner outage: %v", err)
+	}
+
+	useScanner(t, fakeClamd(t, "stream: OK"), false)
+	if w := tusRequest(t, store, http.MethodHead, location, "", nil); w.Code != http.StatusOK {
+		t.Fatalf("head failed with %d", w.Code)
+	}
+
+	status = tusStatus(t, store, location)
+	if status["status"] != models.StatusRejected || !strings.Contains(status["error"].(string), "unsupported file type") {
+		t.Errorf("got %v after the retry", status)
+	}
+	for _, path := range []string{store.dataPath(id), store.dataPath(id) + ".read"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("rejected upload data kept at %s: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package reader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/processor"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/storage"
+)
+
+var (
+	errBlockedAddress = errors.New("address is not allowed")
+	errFetchFailed    = errors.New("fetch failed")
+)
+
+// Ranges that are not covered by the net.IP helpers but must never be
+// reachable from a user supplied URL
+var blockedNetworks = parseCIDRs(
+	"0.0.0.0/8",     // "this" network
+	"100.64.0.0/10", // carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"240.0.0.0/4",   // reserved
+	"64:ff9b::/96",  // NAT64, can embed a private IPv4 address
+)
+
+// URLFetcher downloads remote documents. Every connection, including the ones
+// made for redirects, is checked against the resolved address so a hostname
+// can't be used to reach the internal network.
+type URLFetcher struct {
+	client       *http.Client
+	maxSize      int64
+	maxRedirects int
+	allowHosts   map[string]bool
+	allowNets    []*net.IPNet
+}
+
+type fetchedDocument struct {
+	file         *spool.File
+	filename     string
+	finalURL     string
+	etag         string
+	lastModified string
+	notModified  bool
+}
+
+func NewURLFetcher() *URLFetcher {
+	fetcher := &URLFetcher{
+		maxSize:      config.Int64("URL_MAX_SIZE", maxUploadSize),
+		maxRedirects: config.Int("URL_MAX_REDIRECTS", 5),
+		allowHosts:   make(map[string]bool),
+	}
+
+	// URL_ALLOWLIST takes hostnames and CIDR ranges that may be fetched even
+	// though they resolve to a private address
+	for _, entry := range config.List("URL_ALLOWLIST") {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			fetcher.allowNets = append(fetcher.allowNets, network)
+		} else if ip := net.ParseIP(entry); ip != nil {
+			fetcher.allowNets = append(fetcher.allowNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+		} else {
+			fetcher.allowHosts[strings.ToLower(entry)] = true
+		}
+	}
+
+	timeout := config.Duration("URL_FETCH_TIMEOUT", 30*time.Second)
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	fetcher.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// Proxies from the environment would bypass the address checks
+			Proxy: nil,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return fetcher.dial(ctx, dialer, network, addr)
+			},
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > fetcher.maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", fetcher.maxRedirects)
+			}
+			return checkScheme(req.URL)
+		},
+	}
+
+	return fetcher
+}
+
+// dial resolves the host itself and connects to the checked address, so a
+// DNS answer that changes between the check and the connection is harmless.
+func (f *URLFetcher) dial(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error = fmt.Errorf("no addresses found for %s", host)
+	for _, ip := range addrs {
+		if !f.allowed(host, ip.IP) {
+			return nil, fmt.Errorf("%w: %s resolves to %s", errBlockedAddress, host, ip.IP)
+		}
+	}
+	for _, ip := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (f *URLFetcher) allowed(host string, ip net.IP) bool {
+	if f.allowHosts[strings.ToLower(strings.TrimSuffix(host, "."))] {
+		return true
+	}
+	for _, network := range f.allowNets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return isPublicIP(ip)
+}
+
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if u.User != nil {
+		return fmt.Errorf("URLs with credentials are not supported")
+	}
+	return nil
+}
+
+// fetch downloads rawURL into a spool file. When etag or lastModified are set
+// the request is conditional and an unchanged document comes back with
+// notModified and no content.
+func (f *URLFetcher) fetch(ctx context.Context, rawURL, etag, lastModified string) (*fetchedDocument, error) {
+	target, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q", rawURL)
+	}
+	if err := checkScheme(target); err != nil {
+		return nil, err
+	}
+	if target.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	req.Header.Set("User-Agent", "document-ingestion/1.0")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if errors.Is(err, errBlockedAddress) {
+			return nil, errBlockedAddress
+		}
+		return nil, fmt.Errorf("%w: %v", errFetchFailed, err)
+	}
+	defer resp.Body.Close()
+
+	fetched := &fetchedDocument{
+		finalURL:     resp.Request.URL.String(),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		fetched.notModified = true
+		return fetched, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: remote returned %s", errFetchFailed, resp.Status)
+	}
+	if resp.ContentLength > f.maxSize {
+		return nil, spool.ErrTooLarge
+	}
+
+	file, err := spool.Write(resp.Body, f.maxSize)
+	if err != nil {
+		if errors.Is(err, spool.ErrTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", errFetchFailed, err)
+	}
+
+	fetched.file = file
+	fetched.filename = remoteFilename(resp)
+	return fetched, nil
+}
+
+// remoteFilename prefers the name the server suggests. URLs without an
+// extension get one from the declared content type so formats that can't be
+// sniffed, like Markdown, are still recognised.
+func remoteFilename(resp *http.Response) string {
+	var name string
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		name = path.Base(strings.ReplaceAll(params["filename"], "\\", "/"))
+	}
+	if name == "" || name == "." || name == "/" {
+		name = path.Base(resp.Request.URL.Path)
+	}
+	if name == "" || name == "." || name == "/" {
+		name = resp.Request.URL.Hostname()
+	}
+
+	if path.Ext(name) == "" {
+		declared, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		for _, format := range Formats() {
+			if containsString(format.MimeTypes, declared) && len(format.Extensions) > 0 {
+				return name + format.Extensions[0]
+			}
+		}
+	}
+	return name
+}
+
+// readFetched identifies fetched content and records where it came from.
+func readFetched(fetched *fetchedDocument, sourceURL string) (*models.Document, error) {
+	doc, err := ReadSpooled(fetched.file, fetched.filename)
+	if err != nil {
+		return nil, err
+	}
+
+	doc.Source = &models.Source{
+		URL:          sourceURL,
+		ETag:         fetched.etag,
+		LastModified: fetched.lastModified,
+		FetchedAt:    time.Now(),
+	}
+	if fetched.finalURL != sourceURL {
+		doc.Source.FinalURL = fetched.finalURL
+	}
+	return doc, nil
+}
+
+func HandleFromURL(w http.ResponseWriter, r *http.Request, fetcher *URLFetcher, client processor.Processor, mongodb *storage.MongoDB) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	options, err := processingOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, "Invalid processing options: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(request.URL) == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+
+	fetched, err := fetcher.fetch(r.Context(), request.URL, "", "")
+	switch {
+	case errors.Is(err, errBlockedAddress):
+		http.Error(w, "URL points to a blocked address", http.StatusForbidden)
+		return
+	case errors.Is(err, spool.ErrTooLarge):
+		http.Error(w, fmt.Sprintf("Remote document too large (max %d bytes)", fetcher.maxSize), http.StatusBadRequest)
+		return
+	case errors.Is(err, errFetchFailed):
+		http.Error(w, "Error fetching document: "+err.Error(), http.StatusBadGateway)
+		return
+	case err != nil:
+		http.Error(w, "Error fetching document: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	doc, err := readFetched(fetched, strings.TrimSpace(request.URL))
+	if err != nil {
+		if writeScanFailure(w, err, mongodb) || writeRejection(w, fetched.filename, err) {
+			return
+		}
+		http.Error(w, "Error reading document: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	doc.Processing = options
+
+	if err := IngestDocument(doc, client, mongodb); err != nil {
+		http.Error(w, "Error ingesting document: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	summary := documentSummary(doc)
+	summary["source"] = doc.Source
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
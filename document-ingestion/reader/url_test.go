@@ -0,0 +1,220 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
+)
+
+// localFetcher builds a fetcher that may reach the httptest servers on the
+// loopback address.
+func localFetcher(t *testing.T) *URLFetcher {
+	t.Helper()
+	t.Setenv("URL_ALLOWLIST", "127.0.0.1")
+	return NewURLFetcher()
+}
+
+func fetchURL(t *testing.T, fetcher *URLFetcher, rawURL, etag string) (*fetchedDocument, error) {
+	t.Helper()
+	fetched, err := fetcher.fetch(context.Background(), rawURL, etag, "")
+	if err == nil && fetched.file != nil {
+		t.Cleanup(func() { fetched.file.Remove() })
+	}
+	return fetched, err
+}
+
+func TestFetchBlocksPrivateAddresses(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, "internal")
+	}))
+	defer server.Close()
+
+	t.Setenv("URL_ALLOWLIST", "")
+	fetcher := NewURLFetcher()
+
+	localhost := fmt.Sprintf("http://localhost:%d", server.Listener.Addr().(*net.TCPAddr).Port)
+	for _, target := range []string{server.URL, localhost} {
+		if _, err := fetchURL(t, fetcher, target, ""); !errors.Is(err, errBlockedAddress) {
+			t.Errorf("%s: got %v, want %v", target, err, errBlockedAddress)
+		}
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("blocked address received %d requests", n)
+	}
+}
+
+func TestFetchBlocksRedirectToPrivateAddress(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "internal")
+	}))
+	defer internal.Close()
+
+	// Only the first server's name is allowed, the redirect goes to an address
+	// that isn't
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	defer public.Close()
+
+	t.Setenv("URL_ALLOWLIST", "localhost")
+	fetcher := NewURLFetcher()
+
+	target := strings.Replace(public.URL, "127.0.0.1", "localhost", 1)
+	if _, err := fetchURL(t, fetcher, target, ""); !errors.Is(err, errBlockedAddress) {
+		t.Errorf("got %v, want %v", err, errBlockedAddress)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for address, want := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+		"::ffff:10.0.0.1": false,
+		"64:ff9b::a00:1":  false,
+	} {
+		if got := isPublicIP(net.ParseIP(address)); got != want {
+			t.Errorf("%s: got %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestFetchAllowlist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "allowed")
+	}))
+	defer server.Close()
+
+	for _, allowlist := range []string{"127.0.0.1", "127.0.0.0/8", "LOCALHOST"} {
+		t.Setenv("URL_ALLOWLIST", allowlist)
+		fetcher := NewURLFetcher()
+
+		target := server.URL
+		if allowlist == "LOCALHOST" {
+			target = strings.Replace(target, "127.0.0.1", "localhost", 1)
+		}
+		fetched, err := fetchURL(t, fetcher, target, "")
+		if err != nil {
+			t.Errorf("%s: %v", allowlist, err)
+			continue
+		}
+		content, err := fetched.file.Bytes()
+		if err != nil || string(content) != "allowed" {
+			t.Errorf("%s: got %q, %v", allowlist, content, err)
+		}
+	}
+}
+
+func TestFetchRedirectLimit(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var hop int
+		fmt.Sscanf(r.URL.Path, "/hop/%d", &hop)
+		if hop < 3 {
+			http.Redirect(w, r, fmt.Sprintf("%s/hop/%d", server.URL, hop+1), http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "arrived")
+	}))
+	defer server.Close()
+
+	t.Setenv("URL_MAX_REDIRECTS", "3")
+	fetched, err := fetchURL(t, localFetcher(t), server.URL+"/hop/0", "")
+	if err != nil {
+		t.Fatalf("three redirects failed: %v", err)
+	}
+	if fetched.finalURL != server.URL+"/hop/3" {
+		t.Errorf("got final URL %s", fetched.finalURL)
+	}
+
+	t.Setenv("URL_MAX_REDIRECTS", "2")
+	if _, err := fetchURL(t, localFetcher(t), server.URL+"/hop/0", ""); !errors.Is(err, errFetchFailed) {
+		t.Errorf("got %v, want %v", err, errFetchFailed)
+	}
+}
+
+func TestFetchSizeLimit(t *testing.T) {
+	body := strings.Repeat("x", 1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			// Without a Content-Length the limit has to hold while copying
+			w.Write([]byte(body[:512]))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(body[512:]))
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	t.Setenv("URL_MAX_SIZE", "100")
+	fetcher := localFetcher(t)
+	for _, target := range []string{server.URL + "/sized", server.URL + "/chunked"} {
+		if _, err := fetchURL(t, fetcher, target, ""); !errors.Is(err, spool.ErrTooLarge) {
+			t.Errorf("%s: got %v, want %v", target, err, spool.ErrTooLarge)
+		}
+	}
+
+	t.Setenv("URL_MAX_SIZE", "1024")
+	if _, err := fetchURL(t, localFetcher(t), server.URL+"/chunked", ""); err != nil {
+		t.Errorf("document at the limit failed: %v", err)
+	}
+}
+
+func TestFetchRecordsETag(t *testing.T) {
+	const etag = `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Mon, 05 Oct 2026 10:00:00 GMT")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "remote notes")
+	}))
+	defer server.Close()
+
+	fetcher := localFetcher(t)
+	target := server.URL + "/notes.txt"
+
+	fetched, err := fetchURL(t, fetcher, target, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := readFetched(fetched, target)
+	if err != nil {
+		t.Fatalf("failed to read fetched document: %v", err)
+	}
+	if doc.Source == nil || doc.Source.ETag != etag || doc.Source.LastModified == "" {
+		t.Errorf("got source %+v, want ETag %s and Last-Modified", doc.Source, etag)
+	}
+
+	fetched, err = fetchURL(t, fetcher, target, doc.Source.ETag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fetched.notModified || fetched.file != nil {
+		t.Errorf("conditional fetch of an unchanged document returned content")
+	}
+}
@@ -0,0 +1,411 @@
+package reader
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/pdftext"
+)
+
+// Validators run at one of two stages. Raw validators see the upload as it was
+// received, text validators see the content after pre-processing.
+const (
+	StageRaw  = "raw"
+	StageText = "text"
+)
+
+const maxFileNameLength = 255
+
+// Validator checks a document before it is processed. Check returns the
+// reasons the document is rejected, or nothing when it passes. A validator
+// may also fix the document, as the filename validator does.
+type Validator struct {
+	Name  string
+	Stage string
+	Check func(doc *models.Document) []models.Rejection
+}
+
+// ValidationError is returned for documents that failed validation and
+// carries every reason so clients can show all of them at once.
+type ValidationError struct {
+	Rejections []models.Rejection
+}
+
+func (e *ValidationError) Error() string {
+	reasons := make([]string, len(e.Rejections))
+	for i, rejection := range e.Rejections {
+		reasons[i] = rejection.Reason
+	}
+	return "document rejected: " + strings.Join(reasons, "; ")
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   []*Validator
+
+	validatorOrderOnce sync.Once
+	validatorOrder     []string
+)
+
+var (
+	officePages     = regexp.MustCompile(`<(?:Pages|Slides)>(\d+)</(?:Pages|Slides)>`)
+	reservedInNames = regexp.MustCompile(`[<>:"/\\|?*]`)
+)
+
+// PDF names that make a document do something when it is opened
+var pdfActiveContent = []struct {
+	name, code, reason string
+}{
+	{"JavaScript", "pdf_javascript", "PDF contains JavaScript"},
+	{"JS", "pdf_javascript", "PDF contains JavaScript"},
+	{"Launch", "pdf_launch_action", "PDF launches external programs"},
+	{"EmbeddedFile", "pdf_embedded_file", "PDF contains embedded files"},
+	{"RichMedia", "pdf_embedded_file", "PDF contains embedded media"},
+}
+
+// PDFs are inspected once per validation, every PDF validator of the stage
+// shares the result
+var pdfInspections sync.Map
+
+type pdfInspection struct {
+	once sync.Once
+	info *pdftext.Info
+	err  error
+}
+
+func inspectPDF(doc *models.Document) (*pdftext.Info, error) {
+	value, _ := pdfInspections.LoadOrStore(doc, &pdfInspection{})
+	inspection := value.(*pdfInspection)
+	inspection.once.Do(func() {
+		content, err := doc.Content.Bytes()
+		if err != nil {
+			inspection.err = err
+			return
+		}
+		inspection.info, inspection.err = pdftext.Inspect(content)
+	})
+	return inspection.info, inspection.err
+}
+
+func init() {
+	for _, v := range builtinValidators() {
+		if err := RegisterValidator(v); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func builtinValidators() []*Validator {
+	return []*Validator{
+		{Name: "filename", Stage: StageRaw, Check: checkFileName},
+		{Name: "pdf_encryption", Stage: StageRaw, Check: checkPDFEncryption},
+		{Name: "pdf_active_content", Stage: StageRaw, Check: checkPDFActiveContent},
+		{Name: "max_pages", Stage: StageRaw, Check: checkMaxPages},
+		{Name: "min_text", Stage: StageText, Check: checkMinText},
+	}
+}
+
+// RegisterValidator adds a validator to the end of the chain. Deployments
+// that set VALIDATORS pick which validators run and in which order.
+func RegisterValidator(v *Validator) error {
+	if v == nil || v.Name == "" || v.Check == nil {
+		return fmt.Errorf("validator needs a name and a check")
+	}
+	if v.Stage != StageRaw && v.Stage != StageText {
+		return fmt.Errorf("validator %s has unknown stage %q", v.Name, v.Stage)
+	}
+
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+
+	for _, existing := range validators {
+		if existing.Name == v.Name {
+			return fmt.Errorf("validator %s is already registered", v.Name)
+		}
+	}
+
+	validators = append(validators, v)
+	return nil
+}
+
+// activeValidators returns the validators of a stage in chain order.
+func activeValidators(stage string) []*Validator {
+	validatorOrderOnce.Do(func() {
+		validatorOrder = config.List("VALIDATORS")
+	})
+
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+
+	var chain []*Validator
+	if len(validatorOrder) == 0 {
+		chain = validators
+	} else {
+		for _, name := range validatorOrder {
+			found := false
+			for _, v := range validators {
+				if v.Name == name {
+					chain = append(chain, v)
+					found = true
+				}
+			}
+			if !found {
+				log.Printf("Warning: unknown validator %s in VALIDATORS", name)
+			}
+		}
+	}
+
+	var active []*Validator
+	for _, v := range chain {
+		if v.Stage == stage {
+			active = append(active, v)
+		}
+	}
+	return active
+}
+
+// validateDocument runs every validator of the stage so all problems are
+// reported together.
+func validateDocument(doc *models.Document, stage string) error {
+	var rejections []models.Rejection
+	filename := doc.FileName
+	defer pdfInspections.Delete(doc)
+
+	for _, v := range activeValidators(stage) {
+		for _, rejection := range v.Check(doc) {
+			rejection.FileName = filename
+			rejection.Validator = v.Name
+			rejections = append(rejections, rejection)
+		}
+	}
+
+	if len(rejections) > 0 {
+		return &ValidationError{Rejections: rejections}
+	}
+	return nil
+}
+
+// rejectionsFor turns a failed read into rejections, keeping the structured
+// reasons of validation errors.
+func rejectionsFor(filename string, err error) []models.Rejection {
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		return invalid.Rejections
+	}
+	return []models.Rejection{{FileName: filename, Reason: err.Error()}}
+}
+
+// writeRejection answers with the structured reasons when err is a validation
+// failure and reports whether it did.
+func writeRejection(w http.ResponseWriter, filename string, err error) bool {
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"filename":   filename,
+		"status":     models.StatusRejected,
+		"error":      invalid.Error(),
+		"rejections": invalid.Rejections,
+	})
+	return true
+}
+
+// checkFileName replaces the name with one that is safe to store and show:
+// no directories, control or reserved characters and at most 255 bytes.
+func checkFileName(doc *models.Document) []models.Rejection {
+	original := doc.FileName
+	name := path.Base(strings.ReplaceAll(original, "\\", "/"))
+
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = reservedInNames.ReplaceAllString(name, "_")
+	name = strings.Join(strings.Fields(name), " ")
+	name = strings.Trim(name, " .")
+
+	if len(name) > maxFileNameLength {
+		ext := path.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		base := name[:maxFileNameLength-len(ext)]
+		for !utf8.ValidString(base) {
+			base = base[:len(base)-1]
+		}
+		name = base + ext
+	}
+
+	if name == "" || strings.Trim(name, "_") == "" {
+		return []models.Rejection{{Code: "invalid_filename", Reason: "filename has no usable characters"}}
+	}
+
+	if name != original {
+		setMetadata(doc, "original_filename", original)
+		doc.FileName = name
+	}
+	return nil
+}
+
+func checkPDFEncryption(doc *models.Document) []models.Rejection {
+	if doc.Format != "pdf" {
+		return nil
+	}
+
+	info, err := inspectPDF(doc)
+	if err != nil {
+		return []models.Rejection{{Code: "unreadable", Reason: err.Error()}}
+	}
+
+	if info.Encrypted || info.HasName("Encrypt") {
+		return []models.Rejection{{Code: "pdf_encrypted", Reason: "PDF is encrypted or password protected"}}
+	}
+	return nil
+}
+
+// checkPDFActiveContent refuses PDFs that run code or carry other files,
+// objects compressed into object streams included. Object streams that can't
+// be decoded could hide anything and are refused as well.
+func checkPDFActiveContent(doc *models.Document) []models.Rejection {
+	if doc.Format != "pdf" {
+		return nil
+	}
+
+	info, err := inspectPDF(doc)
+	if err != nil {
+		return []models.Rejection{{Code: "unreadable", Reason: err.Error()}}
+	}
+
+	var rejections []models.Rejection
+	if info.UnreadStreams > 0 {
+		rejections = append(rejections, models.Rejection{
+			Code:   "pdf_uninspectable",
+			Reason: fmt.Sprintf("PDF has %d object streams that can't be read", info.UnreadStreams),
+		})
+	}
+	seen := make(map[string]bool)
+	for _, active := range pdfActiveContent {
+		if seen[active.code] || !info.HasName(active.name) {
+			continue
+		}
+		seen[active.code] = true
+		rejections = append(rejections, models.Rejection{Code: active.code, Reason: active.reason})
+	}
+	return rejections
+}
+
+func checkMaxPages(doc *models.Document) []models.Rejection {
+	limit := config.Int("VALIDATE_MAX_PAGES", 500)
+	if limit <= 0 {
+		return nil
+	}
+
+	pages, err := countPages(doc)
+	if err != nil || pages == 0 {
+		// Unknown page counts are left to the other limits
+		return nil
+	}
+
+	setMetadata(doc, "pages", strconv.Itoa(pages))
+	if pages > limit {
+		return []models.Rejection{{
+			Code:   "too_many_pages",
+			Reason: fmt.Sprintf("document has %d pages, the limit is %d", pages, limit),
+		}}
+	}
+	return nil
+}
+
+// countPages reads the page count of PDFs from their page tree and of
+// Word and PowerPoint files from their document properties. Zero means the
+// count is unknown.
+func countPages(doc *models.Document) (int, error) {
+	switch doc.Format {
+	case "pdf":
+		info, err := inspectPDF(doc)
+		if err != nil {
+			return 0, err
+		}
+		return info.Pages, nil
+
+	case "docx", "pptx":
+		content, err := doc.Content.Open()
+		if err != nil {
+			return 0, err
+		}
+		defer content.Close()
+
+		archive, err := zip.NewReader(content, doc.Content.Size())
+		if err != nil {
+			return 0, err
+		}
+		for _, f := range archive.File {
+			if f.Name != "docProps/app.xml" {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return 0, err
+			}
+			properties, err := io.ReadAll(io.LimitReader(rc, 1<<20))
+			rc.Close()
+			if err != nil {
+				return 0, err
+			}
+			if match := officePages.FindSubmatch(properties); match != nil {
+				return strconv.Atoi(string(match[1]))
+			}
+		}
+	}
+	return 0, nil
+}
+
+// checkMinText rejects text documents that have almost nothing to index,
+// such as scanned pages saved as HTML or empty spreadsheets. PDFs are
+// extracted by the processing service and not checked here.
+func checkMinText(doc *models.Document) []models.Rejection {
+	minimum := config.Int("VALIDATE_MIN_TEXT", 10)
+	if minimum <= 0 || doc.Kind == models.KindCollection || !strings.HasPrefix(doc.ContentType, "text/") {
+		return nil
+	}
+
+	content, err := doc.Content.Bytes()
+	if err != nil {
+		return []models.Rejection{{Code: "unreadable", Reason: err.Error()}}
+	}
+
+	count := 0
+	for _, r := range string(content) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			count++
+			if count >= minimum {
+				return nil
+			}
+		}
+	}
+
+	return []models.Rejection{{
+		Code:   "insufficient_text",
+		Reason: fmt.Sprintf("document has %d letters or digits of text, at least %d are required", count, minimum),
+	}}
+}
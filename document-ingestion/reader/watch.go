@@ -0,0 +1,420 @@
+package reader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/processor"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/spool"
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/storage"
+)
+
+// Suffixes of files that are still being written by editors and copy tools
+var partialSuffixes = []string{"~", ".tmp", ".part", ".partial", ".crdownload", ".swp"}
+
+// DirectoryWatcher ingests files dropped into the configured directories.
+// inotify events trigger a sync of the changed path and a periodic rescan
+// catches whatever the events missed, such as changes on network volumes.
+// Everything runs on one goroutine so the state needs no locking.
+type DirectoryWatcher struct {
+	dirs      []string
+	statePath string
+	rescan    time.Duration
+	settle    time.Duration
+	client    processor.Processor
+	mongodb   *storage.MongoDB
+
+	state   map[string]*watchedFile
+	pending map[string]*time.Timer
+	ready   chan string
+}
+
+// watchedFile is what the state file remembers about an ingested file, so a
+// restart only processes files that changed while the service was down.
+type watchedFile struct {
+	DocumentID string    `json:"document_id,omitempty"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mod_time"`
+	SHA256     string    `json:"sha256"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// NewDirectoryWatcher returns nil when WATCH_DIRS is not set.
+func NewDirectoryWatcher(client processor.Processor, mongodb *storage.MongoDB) (*DirectoryWatcher, error) {
+	var dirs []string
+	for _, dir := range config.List("WATCH_DIRS") {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid watch directory %s: %w", dir, err)
+		}
+		dirs = append(dirs, abs)
+	}
+	if len(dirs) == 0 {
+		return nil, nil
+	}
+
+	statePath, err := filepath.Abs(config.String("WATCH_STATE_FILE", "watch-state.json"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid watch state file: %w", err)
+	}
+
+	watcher := &DirectoryWatcher{
+		dirs:      dirs,
+		statePath: statePath,
+		rescan:    max(config.Duration("WATCH_RESCAN_INTERVAL", 5*time.Minute), time.Second),
+		settle:    config.Duration("WATCH_SETTLE_DELAY", 2*time.Second),
+		client:    client,
+		mongodb:   mongodb,
+		state:     make(map[string]*watchedFile),
+		pending:   make(map[string]*time.Timer),
+		ready:     make(chan string, 64),
+	}
+
+	raw, err := os.ReadFile(watcher.statePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read watch state: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(raw, &watcher.state); err != nil {
+			return nil, fmt.Errorf("failed to decode watch state %s: %w", watcher.statePath, err)
+		}
+	}
+
+	return watcher, nil
+}
+
+func (d *DirectoryWatcher) Run() {
+	var (
+		events <-chan fsnotify.Event
+		errs   <-chan error
+	)
+
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Warning: file events unavailable, relying on rescans every %s: %v", d.rescan, err)
+	} else {
+		defer notify.Close()
+		for _, dir := range d.dirs {
+			d.watchTree(notify, dir)
+		}
+		events, errs = notify.Events, notify.Errors
+	}
+
+	fmt.Printf("Watching %s for documents\n", strings.Join(d.dirs, ", "))
+	d.rescanAll()
+
+	ticker := time.NewTicker(d.rescan)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			d.handleEvent(notify, event)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			log.Printf("Warning: file watcher: %v", err)
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				d.rescanAll()
+			}
+		case path := <-d.ready:
+			delete(d.pending, path)
+			d.sync(path)
+		case <-ticker.C:
+			d.rescanAll()
+		}
+	}
+}
+
+// watchTree adds dir and its subdirectories, inotify watches aren't recursive.
+func (d *DirectoryWatcher) watchTree(notify *fsnotify.Watcher, dir string) {
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return nil
+		}
+		if path != dir && ignoredPath(path) {
+			return filepath.SkipDir
+		}
+		if err := notify.Add(path); err != nil {
+			log.Printf("Warning: failed to watch %s: %v", path, err)
+		}
+		return nil
+	})
+}
+
+func (d *DirectoryWatcher) handleEvent(notify *fsnotify.Watcher, event fsnotify.Event) {
+	if ignoredPath(event.Name) {
+		return
+	}
+
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			// Files moved in together with the directory raise no events
+			d.watchTree(notify, event.Name)
+			d.scanDir(event.Name)
+			return
+		}
+	}
+
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		// A removed directory takes everything that was ingested from it along
+		prefix := event.Name + string(filepath.Separator)
+		for path := range d.state {
+			if strings.HasPrefix(path, prefix) {
+				d.schedule(path)
+			}
+		}
+	}
+
+	d.schedule(event.Name)
+}
+
+// schedule waits until a path has been quiet for the settle delay, a file that
+// is still being copied produces a stream of write events.
+func (d *DirectoryWatcher) schedule(path string) {
+	if timer, ok := d.pending[path]; ok {
+		timer.Reset(d.settle)
+		return
+	}
+	d.pending[path] = time.AfterFunc(d.settle, func() {
+		d.ready <- path
+	})
+}
+
+func (d *DirectoryWatcher) rescanAll() {
+	seen := make(map[string]bool)
+	var unavailable []string
+
+	for _, dir := range d.dirs {
+		if err := d.walk(dir, seen); err != nil {
+			// An unmounted volume must not look like every file was deleted
+			log.Printf("Warning: failed to scan %s: %v", dir, err)
+			unavailable = append(unavailable, dir+string(filepath.Separator))
+		}
+	}
+
+	for path := range d.state {
+		if seen[path] || hasAnyPrefix(path, unavailable) {
+			continue
+		}
+		d.sync(path)
+	}
+}
+
+func (d *DirectoryWatcher) scanDir(dir string) {
+	if err := d.walk(dir, make(map[string]bool)); err != nil {
+		log.Printf("Warning: failed to scan %s: %v", dir, err)
+	}
+}
+
+func (d *DirectoryWatcher) walk(dir string, seen map[string]bool) error {
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			log.Printf("Warning: failed to scan %s: %v", path, err)
+			return nil
+		}
+		if path != dir && ignoredPath(path) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Type().IsRegular() {
+			seen[path] = true
+			if _, busy := d.pending[path]; !busy {
+				d.sync(path)
+			}
+		}
+		return nil
+	})
+}
+
+// sync brings the stored documents in line with the file at path: new and
+// changed files are ingested and deleted files have their document removed.
+func (d *DirectoryWatcher) sync(path string) {
+	if path == d.statePath {
+		return
+	}
+	entry := d.state[path]
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		if entry != nil {
+			d.forget(path, entry)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("Warning: failed to stat %s: %v", path, err)
+		return
+	}
+	if !info.Mode().IsRegular() {
+		return
+	}
+
+	// Failed files are retried, rejected ones only once they change
+	if entry != nil && entry.Status != models.StatusFailed && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+		return
+	}
+
+	d.ingest(path, info, entry)
+}
+
+func (d *DirectoryWatcher) ingest(path string, info os.FileInfo, previous *watchedFile) {
+	next := &watchedFile{Size: info.Size(), ModTime: info.ModTime()}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("Warning: failed to open %s: %v", path, err)
+		return
+	}
+	file, err := spool.Write(f, maxUploadSize)
+	f.Close()
+	if err != nil {
+		next.Status = models.StatusRejected
+		next.Error = err.Error()
+		d.replace(path, previous, next)
+		return
+	}
+	next.SHA256 = file.SHA256()
+
+	// Touched but unchanged, nothing to reprocess
+	if previous != nil && previous.Status != models.StatusFailed && previous.SHA256 == next.SHA256 {
+		file.Remove()
+		next.DocumentID, next.Status, next.Error = previous.DocumentID, previous.Status, previous.Error
+		d.state[path] = next
+		d.saveState()
+		return
+	}
+
+	doc, err := ReadSpooled(file, filepath.Base(path))
+	if err != nil && retryable(err) {
+		// Failed files are read again by the next rescan, until then the
+		// document of the previous contents stays
+		log.Printf("Warning: %s will be read again: %v", path, err)
+		next.Status = models.StatusFailed
+		next.Error = err.Error()
+		if previous != nil {
+			next.DocumentID = previous.DocumentID
+		}
+		d.replace(path, previous, next)
+		return
+	}
+	if err != nil {
+		next.Status = models.StatusRejected
+		next.Error = err.Error()
+		if quarantined := recordQuarantine(err, d.mongodb); quarantined != nil {
+			next.Status = quarantined.Status
+			next.DocumentID = quarantined.ID
+		}
+		d.replace(path, previous, next)
+		return
+	}
+	setMetadata(doc, "source_path", path)
+	doc.Processing = defaultProcessing()
+
+	if err := IngestDocument(doc, d.client, d.mongodb); err != nil {
+		next.Error = err.Error()
+	}
+	next.DocumentID = doc.ID
+	next.Status = doc.Status
+
+	fmt.Printf("Ingested %s as document %s (%s)\n", path, doc.ID, doc.Status)
+	d.replace(path, previous, next)
+}
+
+// replace records the new state of a file and drops the document that was
+// ingested from its previous contents.
+func (d *DirectoryWatcher) replace(path string, previous, next *watchedFile) {
+	if previous != nil && previous.DocumentID != "" && previous.DocumentID != next.DocumentID {
+		if err := d.mongodb.DeleteDocument(previous.DocumentID); err != nil {
+			log.Printf("Warning: failed to remove previous document of %s: %v", path, err)
+		}
+	}
+
+	d.state[path] = next
+	d.saveState()
+}
+
+func (d *DirectoryWatcher) forget(path string, entry *watchedFile) {
+	if entry.DocumentID != "" {
+		if err := d.mongodb.DeleteDocument(entry.DocumentID); err != nil {
+			// Kept in the state so the next rescan tries again
+			log.Printf("Warning: failed to remove document of deleted file %s: %v", path, err)
+			return
+		}
+		fmt.Printf("Removed document %s of deleted file %s\n", entry.DocumentID, path)
+	}
+
+	delete(d.state, path)
+	d.saveState()
+}
+
+// saveState rewrites the state file through a rename so a crash leaves either
+// the old or the new state behind.
+func (d *DirectoryWatcher) saveState() {
+	raw, err := json.MarshalIndent(d.state, "", "  ")
+	if err != nil {
+		log.Printf("Warning: failed to encode watch state: %v", err)
+		return
+	}
+
+	tmp := d.statePath + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		log.Printf("Warning: failed to save watch state: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, d.statePath); err != nil {
+		os.Remove(tmp)
+		log.Printf("Warning: failed to save watch state: %v", err)
+	}
+}
+
+// ignoredPath skips hidden files and directories and files that are still
+// being written.
+func ignoredPath(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	for _, suffix := range partialSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(value string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
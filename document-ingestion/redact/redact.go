@@ -0,0 +1,167 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Rule finds one kind of personal data. Valid, when set, confirms a match,
+// e.g. with a checksum, so numbers that only look alike are left alone.
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Valid   func(match string) bool
+	Mask    string
+}
+
+// Finding is a match of a rule in a text, Start and End are byte offsets.
+type Finding struct {
+	Rule  string
+	Start int
+	End   int
+}
+
+// Redactor applies rules in order. Where matches of two rules overlap the
+// earlier rule wins, so specific rules (IBAN, cards) go before loose ones
+// (phone numbers).
+type Redactor struct {
+	rules []*Rule
+}
+
+func New(rules []*Rule) *Redactor {
+	return &Redactor{rules: rules}
+}
+
+// Rules returns the names of the rules in order.
+func (r *Redactor) Rules() []string {
+	names := make([]string, len(r.rules))
+	for i, rule := range r.rules {
+		names[i] = rule.Name
+	}
+	return names
+}
+
+// Find returns the non-overlapping findings in text ordered by position.
+func (r *Redactor) Find(text string) []Finding {
+	var findings []Finding
+	for _, rule := range r.rules {
+		for _, loc := range rule.Pattern.FindAllStringIndex(text, -1) {
+			if rule.Valid != nil && !rule.Valid(text[loc[0]:loc[1]]) {
+				continue
+			}
+			if overlaps(findings, loc[0], loc[1]) {
+				continue
+			}
+			findings = append(findings, Finding{Rule: rule.Name, Start: loc[0], End: loc[1]})
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Start < findings[j].Start })
+	return findings
+}
+
+// Mask replaces every finding with the mask of its rule.
+func (r *Redactor) Mask(text string) (string, []Finding) {
+	findings := r.Find(text)
+	if len(findings) == 0 {
+		return text, nil
+	}
+
+	masks := make(map[string]string, len(r.rules))
+	for _, rule := range r.rules {
+		masks[rule.Name] = rule.Mask
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, finding := range findings {
+		b.WriteString(text[last:finding.Start])
+		b.WriteString(masks[finding.Rule])
+		last = finding.End
+	}
+	b.WriteString(text[last:])
+
+	return b.String(), findings
+}
+
+func overlaps(findings []Finding, start, end int) bool {
+	for _, f := range findings {
+		if start < f.End && f.Start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// Select picks rules by name in the given order. Unknown names are an error
+// so a typo doesn't silently turn a rule off.
+func Select(rules []*Rule, names []string) ([]*Rule, error) {
+	var selected []*Rule
+	for _, name := range names {
+		found := false
+		for _, rule := range rules {
+			if rule.Name == name {
+				selected = append(selected, rule)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown PII rule %s", name)
+		}
+	}
+	return selected, nil
+}
+
+// LoadRules reads additional rules from a JSON file:
+//
+//	[{"name": "employee_id", "pattern": "\\bEMP-\\d{6}\\b", "mask": "[EMPLOYEE_ID]"}]
+//
+// Check may name a built-in checksum: "luhn" or "mod97".
+func LoadRules(path string) ([]*Rule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PII rules: %w", err)
+	}
+
+	var specs []struct {
+		Name    string `json:"name"`
+		Pattern string `json:"pattern"`
+		Mask    string `json:"mask"`
+		Check   string `json:"check"`
+	}
+	if err := json.Unmarshal(content, &specs); err != nil {
+		return nil, fmt.Errorf("invalid PII rules file: %w", err)
+	}
+
+	rules := make([]*Rule, 0, len(specs))
+	for _, spec := range specs {
+		if spec.Name == "" || spec.Pattern == "" {
+			return nil, fmt.Errorf("PII rule needs a name and a pattern")
+		}
+		pattern, err := regexp.Compile(spec.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for PII rule %s: %w", spec.Name, err)
+		}
+
+		rule := &Rule{Name: spec.Name, Pattern: pattern, Mask: spec.Mask}
+		if rule.Mask == "" {
+			rule.Mask = "[" + strings.ToUpper(spec.Name) + "]"
+		}
+		switch spec.Check {
+		case "":
+		case "luhn":
+			rule.Valid = func(match string) bool { return luhn(digits(match)) }
+		case "mod97":
+			rule.Valid = validIBAN
+		default:
+			return nil, fmt.Errorf("unknown check %q for PII rule %s", spec.Check, spec.Name)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
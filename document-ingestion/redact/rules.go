@@ -0,0 +1,161 @@
+package redact
+
+import (
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// Builtin returns the default rules, most specific first.
+func Builtin() []*Rule {
+	return []*Rule{
+		{
+			Name:    "email",
+			Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`),
+			Mask:    "[EMAIL]",
+		},
+		{
+			Name:    "iban",
+			Pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`),
+			Valid:   validIBAN,
+			Mask:    "[IBAN]",
+		},
+		{
+			Name:    "credit_card",
+			Pattern: regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+			Valid:   validCard,
+			Mask:    "[CREDIT_CARD]",
+		},
+		{
+			Name:    "us_ssn",
+			Pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+			Valid:   validSSN,
+			Mask:    "[NATIONAL_ID]",
+		},
+		{
+			Name:    "tr_national_id",
+			Pattern: regexp.MustCompile(`\b[1-9]\d{10}\b`),
+			Valid:   validTCKN,
+			Mask:    "[NATIONAL_ID]",
+		},
+		{
+			Name:    "uk_nino",
+			Pattern: regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`),
+			Mask:    "[NATIONAL_ID]",
+		},
+		{
+			Name: "phone",
+			Pattern: regexp.MustCompile(
+				`\+\d[\d .\-()]{5,20}\d` +
+					`|\(\d{1,5}\)[ .\-]?\d[\d .\-]{4,16}\d` +
+					`|\b\d{2,5}(?:[ .\-]\d{2,5}){1,4}\b`),
+			Valid: validPhone,
+			Mask:  "[PHONE]",
+		},
+	}
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+func luhn(number string) bool {
+	if number == "" {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func validCard(match string) bool {
+	number := digits(match)
+	return len(number) >= 13 && len(number) <= 19 && luhn(number)
+}
+
+// validIBAN checks the ISO 13616 mod 97 checksum.
+func validIBAN(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	var numeric strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			numeric.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			numeric.WriteString(big.NewInt(int64(r-'A') + 10).String())
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validSSN drops numbers the SSA never issues.
+func validSSN(match string) bool {
+	area, group, serial := match[:3], match[4:6], match[7:]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// validTCKN checks the two check digits of a Turkish identity number.
+func validTCKN(match string) bool {
+	var d [11]int
+	for i := range d {
+		d[i] = int(match[i] - '0')
+	}
+
+	odd := d[0] + d[2] + d[4] + d[6] + d[8]
+	even := d[1] + d[3] + d[5] + d[7]
+	if ((odd*7-even)%10+10)%10 != d[9] {
+		return false
+	}
+
+	sum := 0
+	for _, v := range d[:10] {
+		sum += v
+	}
+	return sum%10 == d[10]
+}
+
+// validPhone keeps numbers of phone length. Numbers without a country code or
+// area code in brackets need at least nine digits, and dates are skipped.
+func validPhone(match string) bool {
+	number := digits(match)
+	if len(number) < 7 || len(number) > 15 {
+		return false
+	}
+	if match[0] == '+' || match[0] == '(' {
+		return true
+	}
+	if len(number) < 9 {
+		return false
+	}
+
+	groups := strings.FieldsFunc(match, func(r rune) bool { return r == ' ' || r == '.' || r == '-' })
+	if len(groups) >= 3 && len(groups[0]) == 4 && (strings.HasPrefix(groups[0], "19") || strings.HasPrefix(groups[0], "20")) && len(groups[1]) == 2 && groups[1] <= "12" {
+		return false
+	}
+	return true
+}
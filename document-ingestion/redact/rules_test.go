@@ -0,0 +1,77 @@
+package redact
+
+import "testing"
+
+func TestBuiltinRules(t *testing.T) {
+	tests := []struct {
+		text string
+		// rule is the rule expected to find the number, empty for none
+		rule string
+	}{
+		{"4111 1111 1111 1111", "credit_card"},
+		{"5500-0000-0000-0004", "credit_card"},
+		{"378282246310005", "credit_card"},
+		{"4111 1111 1111 1112", ""},
+		{"5500-0000-0000-0005", ""},
+
+		{"GB82 WEST 1234 5698 7654 32", "iban"},
+		{"DE89370400440532013000", "iban"},
+		{"TR330006100519786457841326", "iban"},
+		{"GB82 WEST 1234 5698 7654 33", ""},
+		{"DE89370400440532013001", ""},
+
+		{"123-45-6789", "us_ssn"},
+		{"000-12-3456", ""},
+		{"666-12-3456", ""},
+		{"912-34-5678", ""},
+		{"123-00-4567", ""},
+		{"123-45-0000", ""},
+
+		{"10000000146", "tr_national_id"},
+		{"10000000147", ""},
+		{"10000000156", ""},
+		{"01000000146", ""},
+
+		{"AB 12 34 56 C", "uk_nino"},
+		{"AB123456C", "uk_nino"},
+		{"DA 12 34 56 C", ""},
+		{"AB 12 34 56 E", ""},
+	}
+
+	r := New(Builtin())
+	for _, test := range tests {
+		findings := r.Find("Number: " + test.text + ".")
+		var rule string
+		for _, finding := range findings {
+			if finding.Rule != "phone" {
+				rule = finding.Rule
+			}
+		}
+		if rule != test.rule {
+			t.Errorf("%q found as %q, want %q (findings %+v)", test.text, rule, test.rule, findings)
+		}
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	tests := map[string]bool{
+		"79927398713": true,
+		"79927398710": false,
+		"0":           true,
+		"":            false,
+		"18":          true,
+		"19":          false,
+	}
+	for number, want := range tests {
+		if got := luhn(number); got != want {
+			t.Errorf("luhn(%q) = %v, want %v", number, got, want)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	text, findings := New(Builtin()).Mask("Pay GB82 WEST 1234 5698 7654 32 from jane@example.com")
+	if text != "Pay [IBAN] from [EMAIL]" || len(findings) != 2 {
+		t.Errorf("got %q with %+v", text, findings)
+	}
+}
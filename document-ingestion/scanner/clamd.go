@@ -0,0 +1,182 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const defaultChunkSize = 64 * 1024
+
+// Result is the verdict for one scanned stream.
+type Result struct {
+	Infected  bool
+	Signature string
+}
+
+// Clamd talks to a clamd compatible daemon. Content is streamed with the
+// INSTREAM command, so the daemon doesn't need access to our files.
+type Clamd struct {
+	network   string
+	address   string
+	timeout   time.Duration
+	chunkSize int
+}
+
+// NewClamd accepts "tcp://host:port", "unix:///path/to/clamd.sock", a bare
+// "host:port" or an absolute socket path.
+func NewClamd(address string, timeout time.Duration) (*Clamd, error) {
+	c := &Clamd{timeout: timeout, chunkSize: defaultChunkSize}
+
+	switch {
+	case strings.HasPrefix(address, "tcp://"):
+		c.network, c.address = "tcp", strings.TrimPrefix(address, "tcp://")
+	case strings.HasPrefix(address, "unix://"):
+		c.network, c.address = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "/"):
+		c.network, c.address = "unix", address
+	default:
+		c.network, c.address = "tcp", address
+	}
+
+	if c.address == "" {
+		return nil, fmt.Errorf("clamd address is empty")
+	}
+	if c.network == "tcp" {
+		if _, _, err := net.SplitHostPort(c.address); err != nil {
+			return nil, fmt.Errorf("invalid clamd address %q: %w", address, err)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Clamd) String() string {
+	return c.network + "://" + c.address
+}
+
+// Ping checks that the daemon is reachable.
+func (c *Clamd) Ping(ctx context.Context) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return fmt.Errorf("failed to send PING: %w", err)
+	}
+	reply, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("unexpected clamd reply %q", reply)
+	}
+	return nil
+}
+
+// Scan streams r to the daemon and returns its verdict.
+func (c *Clamd) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	writeErr := c.stream(conn, r)
+
+	// clamd answers and closes the connection when it refuses the stream, e.g.
+	// over StreamMaxLength, so its reply explains a failed write
+	reply, err := readReply(conn)
+	if err != nil {
+		if writeErr != nil {
+			return nil, writeErr
+		}
+		return nil, err
+	}
+
+	return parseReply(reply)
+}
+
+func (c *Clamd) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd at %s: %w", c, err)
+	}
+	if c.timeout > 0 {
+		conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	return conn, nil
+}
+
+// stream sends the INSTREAM command followed by length prefixed chunks and a
+// zero length chunk that ends the stream.
+func (c *Clamd) stream(conn net.Conn, r io.Reader) error {
+	w := bufio.NewWriterSize(conn, c.chunkSize+4)
+	if _, err := w.WriteString("zINSTREAM\x00"); err != nil {
+		return fmt.Errorf("failed to send INSTREAM: %w", err)
+	}
+
+	chunk := make([]byte, c.chunkSize)
+	var size [4]byte
+	for {
+		n, err := io.ReadFull(r, chunk)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, werr := w.Write(size[:]); werr != nil {
+				return fmt.Errorf("failed to stream to clamd: %w", werr)
+			}
+			if _, werr := w.Write(chunk[:n]); werr != nil {
+				return fmt.Errorf("failed to stream to clamd: %w", werr)
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read content: %w", err)
+		}
+	}
+
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := w.Write(size[:]); err != nil {
+		return fmt.Errorf("failed to stream to clamd: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to stream to clamd: %w", err)
+	}
+	return nil
+}
+
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(errors.Is(err, io.EOF) && reply != "") {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return strings.TrimSpace(strings.TrimRight(reply, "\x00")), nil
+}
+
+// parseReply reads "stream: OK", "stream: <signature> FOUND" or
+// "<message> ERROR".
+func parseReply(reply string) (*Result, error) {
+	verdict := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+
+	switch {
+	case verdict == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	case strings.HasSuffix(verdict, " ERROR"):
+		return nil, fmt.Errorf("clamd error: %s", strings.TrimSuffix(verdict, " ERROR"))
+	default:
+		return nil, fmt.Errorf("unexpected clamd reply %q", reply)
+	}
+}
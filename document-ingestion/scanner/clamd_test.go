@@ -0,0 +1,197 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd serves the clamd protocol on a local port. reply answers a
+// command, for INSTREAM with the content reassembled from its chunks.
+func fakeClamd(t *testing.T, reply func(command string, content []byte) string) (string, <-chan [][]byte) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	// The chunks of every stream, to check the framing
+	chunks := make(chan [][]byte, 8)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				command, err := r.ReadString(0)
+				if err != nil {
+					return
+				}
+				command = strings.TrimSuffix(strings.TrimPrefix(command, "z"), "\x00")
+
+				var content []byte
+				if command == "INSTREAM" {
+					var received [][]byte
+					for {
+						var size [4]byte
+						if _, err := io.ReadFull(r, size[:]); err != nil {
+							return
+						}
+						n := binary.BigEndian.Uint32(size[:])
+						if n == 0 {
+							break
+						}
+						chunk := make([]byte, n)
+						if _, err := io.ReadFull(r, chunk); err != nil {
+							return
+						}
+						received = append(received, chunk)
+						content = append(content, chunk...)
+					}
+					chunks <- received
+				}
+				conn.Write([]byte(reply(command, content) + "\x00"))
+			}()
+		}
+	}()
+
+	return "tcp://" + listener.Addr().String(), chunks
+}
+
+func TestScanStreamsChunks(t *testing.T) {
+	content := []byte("a document that is sent in several chunks")
+	address, chunks := fakeClamd(t, func(command string, received []byte) string {
+		if command != "INSTREAM" {
+			return "UNKNOWN COMMAND"
+		}
+		if !bytes.Equal(received, content) {
+			return "stream: content mismatch ERROR"
+		}
+		return "stream: OK"
+	})
+
+	c, err := NewClamd(address, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.chunkSize = 8
+
+	result, err := c.Scan(context.Background(), bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	if result.Infected {
+		t.Errorf("clean content reported infected: %+v", result)
+	}
+
+	received := <-chunks
+	if want := (len(content) + c.chunkSize - 1) / c.chunkSize; len(received) != want {
+		t.Errorf("content sent in %d chunks, want %d", len(received), want)
+	}
+	for i, chunk := range received[:len(received)-1] {
+		if len(chunk) != c.chunkSize {
+			t.Errorf("chunk %d has %d bytes, want %d", i, len(chunk), c.chunkSize)
+		}
+	}
+}
+
+func TestScanFound(t *testing.T) {
+	address, _ := fakeClamd(t, func(string, []byte) string {
+		return "stream: Eicar-Test-Signature FOUND"
+	})
+	c, err := NewClamd(address, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.Scan(context.Background(), strings.NewReader("X5O!P%@AP"))
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	if !result.Infected || result.Signature != "Eicar-Test-Signature" {
+		t.Errorf("got %+v, want infected with Eicar-Test-Signature", result)
+	}
+}
+
+func TestScanError(t *testing.T) {
+	address, _ := fakeClamd(t, func(string, []byte) string {
+		return "INSTREAM size limit exceeded. ERROR"
+	})
+	c, err := NewClamd(address, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Scan(context.Background(), strings.NewReader("content")); err == nil {
+		t.Error("clamd ERROR reply did not fail the scan")
+	}
+}
+
+func TestPing(t *testing.T) {
+	address, _ := fakeClamd(t, func(command string, _ []byte) string {
+		if command == "PING" {
+			return "PONG"
+		}
+		return "UNKNOWN COMMAND"
+	})
+	c, err := NewClamd(address, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Ping(context.Background()); err != nil {
+		t.Errorf("ping failed: %v", err)
+	}
+}
+
+func TestScanUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	c, err := NewClamd(address, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Scan(context.Background(), strings.NewReader("content")); err == nil {
+		t.Error("scan against a closed port succeeded")
+	}
+}
+
+func TestNewClamdAddresses(t *testing.T) {
+	for address, want := range map[string]string{
+		"tcp://clamav:3310":            "tcp://clamav:3310",
+		"clamav:3310":                  "tcp://clamav:3310",
+		"unix:///run/clamav/clamd.ctl": "unix:///run/clamav/clamd.ctl",
+		"/run/clamav/clamd.ctl":        "unix:///run/clamav/clamd.ctl",
+	} {
+		c, err := NewClamd(address, time.Second)
+		if err != nil {
+			t.Errorf("%s: %v", address, err)
+			continue
+		}
+		if c.String() != want {
+			t.Errorf("%s: got %s, want %s", address, c, want)
+		}
+	}
+
+	for _, address := range []string{"", "tcp://", "clamav"} {
+		if _, err := NewClamd(address, time.Second); err == nil {
+			t.Errorf("%q: invalid address accepted", address)
+		}
+	}
+}
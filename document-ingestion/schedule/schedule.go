@@ -0,0 +1,263 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a recurring job runs next. MinInterval is the shortest
+// time between two of its runs.
+type Schedule interface {
+	Next(after time.Time) time.Time
+	MinInterval() time.Duration
+}
+
+// Every runs at a fixed interval.
+type Every time.Duration
+
+func (e Every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+func (e Every) MinInterval() time.Duration {
+	return time.Duration(e)
+}
+
+// Cron is a five field cron expression: minute, hour, day of month, month and
+// day of week.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+var shorthands = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+	dayNames   = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// Parse accepts a cron expression, one of the @hourly style shorthands or
+// "@every <duration>".
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %q: %w", rest, err)
+		}
+		if interval < time.Minute {
+			return nil, fmt.Errorf("interval must be at least one minute")
+		}
+		return Every(interval), nil
+	}
+	if expanded, ok := shorthands[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in %q, got %d", spec, len(fields))
+	}
+
+	var (
+		c   Cron
+		err error
+	)
+	if c.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if c.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if c.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if c.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if c.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+
+	// 7 is another name for Sunday
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = fields[2] == "*" || fields[2] == "?"
+	c.dowAny = fields[4] == "*" || fields[4] == "?"
+
+	return &c, nil
+}
+
+// parseField turns a comma separated list of values, ranges and steps into a
+// bit set.
+func parseField(field string, low, high int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		start, end := low, high
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = fieldValue(from, low, high, names); err != nil {
+				return 0, err
+			}
+			if end, err = fieldValue(to, low, high, names); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			value, err := fieldValue(rangePart, low, high, names)
+			if err != nil {
+				return 0, err
+			}
+			start = value
+			// "5/10" means every 10 starting at 5
+			if !hasStep {
+				end = value
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func fieldValue(value string, low, high int, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < low || n > high {
+		return 0, fmt.Errorf("value %q out of range %d-%d", value, low, high)
+	}
+	return n, nil
+}
+
+// Next returns the first matching minute after the given time, in the
+// location of that time. Expressions that never match, like February 30th,
+// return the zero time.
+func (c *Cron) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Weekdays, month lengths and leap years line up the same way every 28 years
+const calendarCycle = 28
+
+// MinInterval compares the runs within a day and between the closest two days
+// the expression runs on over a whole calendar cycle. Daylight saving shifts
+// are left out.
+func (c *Cron) MinInterval() time.Duration {
+	var times []int
+	for h := 0; h < 24; h++ {
+		for m := 0; m < 60; m++ {
+			if c.hour&(1<<uint(h)) != 0 && c.minute&(1<<uint(m)) != 0 {
+				times = append(times, h*60+m)
+			}
+		}
+	}
+	if len(times) == 0 {
+		return 0
+	}
+
+	shortest := -1
+	for i := 1; i < len(times); i++ {
+		if gap := times[i] - times[i-1]; shortest < 0 || gap < shortest {
+			shortest = gap
+		}
+	}
+
+	// The fewest days between two days that run
+	fewestDays := -1
+	start := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	previous := -1
+	for day := 0; day < calendarCycle*366; day++ {
+		t := start.AddDate(0, 0, day)
+		if c.month&(1<<uint(t.Month())) == 0 || !c.dayMatches(t) {
+			continue
+		}
+		if previous >= 0 && (fewestDays < 0 || day-previous < fewestDays) {
+			fewestDays = day - previous
+		}
+		previous = day
+	}
+	if fewestDays > 0 {
+		gap := fewestDays*24*60 - times[len(times)-1] + times[0]
+		if shortest < 0 || gap < shortest {
+			shortest = gap
+		}
+	}
+
+	if shortest < 0 {
+		return 0
+	}
+	return time.Duration(shortest) * time.Minute
+}
+
+// dayMatches follows cron: when both day fields are restricted a day matching
+// either of them is enough.
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
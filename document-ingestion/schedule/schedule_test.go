@@ -0,0 +1,112 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		ok   bool
+	}{
+		{"*/15 * * * *", true},
+		{"0 9-17 * * mon-fri", true},
+		{"0 0 1 JAN,jul *", true},
+		{"5/10 * * * 7", true},
+		{"@daily", true},
+		{"@every 90m", true},
+		{"@every 30s", false},
+		{"@every soon", false},
+		{"* * * *", false},
+		{"60 * * * *", false},
+		{"0 24 * * *", false},
+		{"0 0 0 * *", false},
+		{"0 0 * 13 *", false},
+		{"0 0 * * 8", false},
+		{"0 17-9 * * *", false},
+		{"*/0 * * * *", false},
+		{"0 0 * * funday", false},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.spec)
+		if (err == nil) != test.ok {
+			t.Errorf("Parse(%q) returned %v", test.spec, err)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// A Wednesday
+	after := time.Date(2026, time.March, 4, 10, 7, 30, 0, time.UTC)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", at(time.March, 4, 10, 8)},
+		{"*/15 * * * *", at(time.March, 4, 10, 15)},
+		{"5/10 * * * *", at(time.March, 4, 10, 15)},
+		{"0 9-11 * * *", at(time.March, 4, 11, 0)},
+		{"30 8 * * *", at(time.March, 5, 8, 30)},
+		{"0 0 * * sat", at(time.March, 7, 0, 0)},
+		{"0 0 * * 7", at(time.March, 8, 0, 0)},
+		{"0 0 1 apr *", at(time.April, 1, 0, 0)},
+		{"@monthly", at(time.April, 1, 0, 0)},
+		// Either day field matches when both are restricted
+		{"0 0 20 * fri", at(time.March, 6, 0, 0)},
+		{"0 0 5 * mon", at(time.March, 5, 0, 0)},
+		{"@every 2h", after.Add(2 * time.Hour)},
+	}
+	for _, test := range tests {
+		s, err := Parse(test.spec)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.spec, err)
+			continue
+		}
+		if got := s.Next(after); !got.Equal(test.want) {
+			t.Errorf("%q runs next at %s, want %s", test.spec, got, test.want)
+		}
+	}
+
+	never, err := Parse("0 0 30 feb *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := never.Next(after); !got.IsZero() {
+		t.Errorf("February 30th runs at %s", got)
+	}
+}
+
+func TestMinInterval(t *testing.T) {
+	tests := []struct {
+		spec string
+		want time.Duration
+	}{
+		{"*/15 * * * *", 15 * time.Minute},
+		{"@every 90m", 90 * time.Minute},
+		{"@daily", 24 * time.Hour},
+		{"@weekly", 7 * 24 * time.Hour},
+		// The two runs far apart within an hour are close across hours
+		{"0,50 * * * *", 10 * time.Minute},
+		// Far apart within a day, close across midnight
+		{"0 0,23 * * *", time.Hour},
+		// The 31st and the 1st follow each other in some months only
+		{"0 0 1,31 * *", 24 * time.Hour},
+		{"0 0 1,15 * *", 14 * 24 * time.Hour},
+		{"0 12 29 feb *", 1461 * 24 * time.Hour},
+	}
+	for _, test := range tests {
+		s, err := Parse(test.spec)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.spec, err)
+			continue
+		}
+		if got := s.MinInterval(); got != test.want {
+			t.Errorf("%q runs every %s at the least, want %s", test.spec, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package spool
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/config"
+)
+
+// HeadSize is how much of the start of a file is kept in memory for MIME
+// detection and format sniffing.
+const HeadSize = 8192
+
+var ErrTooLarge = errors.New("content exceeds the size limit")
+
+// ErrBudgetExhausted is returned when the memory budget stayed taken by other
+// uploads for UPLOAD_MEMORY_WAIT. It passes, the upload can be tried again.
+var ErrBudgetExhausted = errors.New("upload memory budget exhausted")
+
+// Reader gives random access to spooled content, zip archives need ReaderAt.
+type Reader interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+// File is upload content that lives on disk once it grows past the memory
+// threshold. It is hashed and its head captured while being written, so the
+// whole file never has to be loaded just to identify it.
+type File struct {
+	data []byte
+	path string
+	size int64
+	hash string
+	head []byte
+}
+
+var (
+	settingsOnce    sync.Once
+	spoolDir        string
+	memoryThreshold int64
+	budget          *semaphore.Weighted
+	budgetSize      int64
+	budgetWait      time.Duration
+)
+
+func settings() {
+	settingsOnce.Do(func() {
+		spoolDir = config.String("SPOOL_DIR", os.TempDir())
+		memoryThreshold = config.Int64("SPOOL_MEMORY_THRESHOLD", 256*1024) // 256KB
+		budgetSize = config.Int64("UPLOAD_MEMORY_BUDGET", 256*1024*1024)   // 256MB
+		budgetWait = config.Duration("UPLOAD_MEMORY_WAIT", 2*time.Minute)
+		budget = semaphore.NewWeighted(budgetSize)
+
+		if err := os.MkdirAll(spoolDir, 0o700); err != nil {
+			log.Printf("Warning: failed to create spool directory %s: %v", spoolDir, err)
+		}
+	})
+}
+
+// Write copies r into a new spooled file, failing with ErrTooLarge once more
+// than limit bytes have been read.
+func Write(r io.Reader, limit int64) (*File, error) {
+	settings()
+
+	hasher := sha256.New()
+	f := &File{}
+
+	var memory bytes.Buffer
+	reader := io.TeeReader(io.LimitReader(r, limit+1), hasher)
+
+	// Small files never touch the disk
+	n, err := io.CopyN(&memory, reader, max(memoryThreshold, HeadSize)+1)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read content: %w", err)
+	}
+	f.size = n
+	f.head = bytes.Clone(memory.Bytes()[:min(memory.Len(), HeadSize)])
+
+	if n > memoryThreshold {
+		tmp, err := os.CreateTemp(spoolDir, "upload-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create spool file: %w", err)
+		}
+		f.path = tmp.Name()
+
+		written, err := io.Copy(tmp, io.MultiReader(&memory, reader))
+		closeErr := tmp.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			f.Remove()
+			return nil, fmt.Errorf("failed to spool content: %w", err)
+		}
+		f.size = written
+	} else {
+		f.data = memory.Bytes()
+	}
+
+	if f.size > limit {
+		f.Remove()
+		return nil, ErrTooLarge
+	}
+
+	f.hash = hex.EncodeToString(hasher.Sum(nil))
+	return f, nil
+}
+
+// Adopt takes ownership of a file that is already on disk, such as a
+// completed resumable upload. It is read once to hash it and capture its head.
+func Adopt(path string) (*File, error) {
+	settings()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	var head bytes.Buffer
+	size, err := io.Copy(io.MultiWriter(hasher, &headWriter{buf: &head}), file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return &File{
+		path: path,
+		size: size,
+		hash: hex.EncodeToString(hasher.Sum(nil)),
+		head: head.Bytes(),
+	}, nil
+}
+
+func FromBytes(content []byte) (*File, error) {
+	return Write(bytes.NewReader(content), int64(len(content)))
+}
+
+func (f *File) Size() int64 {
+	if f == nil {
+		return 0
+	}
+	return f.size
+}
+
+func (f *File) SHA256() string {
+	if f == nil {
+		return ""
+	}
+	return f.hash
+}
+
+// Head returns up to HeadSize bytes from the start of the content.
+func (f *File) Head() []byte {
+	if f == nil {
+		return nil
+	}
+	return f.head
+}
+
+func (f *File) Open() (Reader, error) {
+	if f == nil {
+		return nil, fmt.Errorf("no content")
+	}
+	if f.path == "" {
+		return nopCloser{bytes.NewReader(f.data)}, nil
+	}
+
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool file: %w", err)
+	}
+	return file, nil
+}
+
+// Bytes loads the whole content. Callers handling uploads should hold a
+// Reserve for the size while the bytes are alive.
+func (f *File) Bytes() ([]byte, error) {
+	if f == nil {
+		return nil, nil
+	}
+	if f.path == "" {
+		return f.data, nil
+	}
+
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool file: %w", err)
+	}
+	return content, nil
+}
+
+func (f *File) Remove() error {
+	if f == nil || f.path == "" {
+		return nil
+	}
+
+	err := os.Remove(f.path)
+	f.path = ""
+	f.data = nil
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove spool file: %w", err)
+	}
+	return nil
+}
+
+// Reserve takes n bytes from the global upload memory budget, waiting for
+// other uploads to release theirs. The returned func gives the bytes back.
+func Reserve(n int64) (func(), error) {
+	settings()
+
+	if n <= 0 {
+		return func() {}, nil
+	}
+	if n > budgetSize {
+		return nil, fmt.Errorf("content of %d bytes exceeds the upload memory budget", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), budgetWait)
+	defer cancel()
+
+	if err := budget.Acquire(ctx, n); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBudgetExhausted, err)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { budget.Release(n) })
+	}, nil
+}
+
+// headWriter keeps the first HeadSize bytes written to it
+type headWriter struct {
+	buf *bytes.Buffer
+}
+
+func (h *headWriter) Write(p []byte) (int, error) {
+	if remaining := HeadSize - h.buf.Len(); remaining > 0 {
+		h.buf.Write(p[:min(len(p), remaining)])
+	}
+	return len(p), nil
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
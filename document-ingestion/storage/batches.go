@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (m *MongoDB) InsertBatch(batch *models.Batch) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := m.batches.InsertOne(ctx, batch); err != nil {
+		return fmt.Errorf("failed to save batch: %w", err)
+	}
+
+	return nil
+}
+
+func (m *MongoDB) UpdateBatchItem(batchID string, index int, item models.BatchItem) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	prefix := fmt.Sprintf("items.%d.", index)
+	_, err := m.batches.UpdateOne(ctx, bson.M{"id": batchID}, bson.M{"$set": bson.M{
+		prefix + "document_id": item.DocumentID,
+		prefix + "status":      item.Status,
+		prefix + "error":       item.Error,
+		"updated_at":           time.Now(),
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to update batch item: %w", err)
+	}
+
+	return nil
+}
+
+func (m *MongoDB) UpdateBatchStatus(batchID, status string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := m.batches.UpdateOne(ctx, bson.M{"id": batchID}, bson.M{"$set": bson.M{
+		"status":     status,
+		"updated_at": time.Now(),
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to update batch status: %w", err)
+	}
+
+	return nil
+}
+
+func (m *MongoDB) GetBatch(batchID string) (*models.Batch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var batch models.Batch
+	err := m.batches.FindOne(ctx, bson.M{"id": batchID}).Decode(&batch)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+
+	return &batch, nil
+}
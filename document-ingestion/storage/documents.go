@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetDocument returns nil without an error when the document doesn't exist.
+func (m *MongoDB) GetDocument(documentID string) (*models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var doc models.Document
+	err := m.documents.FindOne(ctx, bson.M{"id": documentID}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+
+	return &doc, nil
+}
+
+// SupersedeDocument marks a document and its children as replaced by a newer
+// version, they are kept for history but no longer returned by search.
+func (m *MongoDB) SupersedeDocument(documentID, newerID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"$or": bson.A{bson.M{"id": documentID}, bson.M{"parent_id": documentID}}}
+	_, err := m.documents.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"superseded_by": newerID}})
+	if err != nil {
+		return fmt.Errorf("failed to supersede document: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateDocumentSource records a fetch that didn't change the document.
+func (m *MongoDB) UpdateDocumentSource(documentID string, source *models.Source) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := m.documents.UpdateOne(ctx, bson.M{"id": documentID}, bson.M{"$set": bson.M{"source": source}})
+	if err != nil {
+		return fmt.Errorf("failed to update document source: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDocument removes a document together with its child documents and
+// the chunks of all of them.
+func (m *MongoDB) DeleteDocument(documentID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	ids := []string{documentID}
+	for i := 0; i < len(ids); i++ {
+		cursor, err := m.documents.Find(ctx, bson.M{"parent_id": ids[i]}, options.Find().SetProjection(bson.M{"id": 1}))
+		if err != nil {
+			return fmt.Errorf("failed to find child documents: %w", err)
+		}
+
+		var children []struct {
+			ID string `bson:"id"`
+		}
+		err = cursor.All(ctx, &children)
+		cursor.Close(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to decode child documents: %w", err)
+		}
+
+		for _, child := range children {
+			ids = append(ids, child.ID)
+		}
+	}
+
+	if _, err := m.chunks.DeleteMany(ctx, bson.M{"document_id": bson.M{"$in": ids}}); err != nil {
+		return fmt.Errorf("failed to delete chunks: %w", err)
+	}
+	if _, err := m.documents.DeleteMany(ctx, bson.M{"id": bson.M{"$in": ids}}); err != nil {
+		return fmt.Errorf("failed to delete documents: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/ozgurnsahin/document-processor-pp/document-ingestion/language"
+)
+
+// textSearchLanguages names the languages MongoDB stems, by ISO 639-1 code.
+// Words of other languages are indexed as they are.
+var textSearchLanguages = map[string]string{
+	"da": "danish", "de": "german", "en": "english", "es": "spanish",
+	"fi": "finnish", "fr": "french", "hu": "hungarian", "it": "italian",
+	"nb": "norwegian", "nl": "dutch", "pt": "portuguese", "ro": "romanian",
+	"ru": "russian", "sv": "swedish", "tr": "turkish",
+}
+
+func textSearchLanguage(code string) string {
+	if name, ok := textSearchLanguages[code]; ok {
+		return name
+	}
+	return "none"
+}
+
+// keywords are the words of a chunk the keyword index stores, tokenized by
+// the rules of its language.
+func keywords(text, code string) string {
+	return strings.Join(language.Keywords(text, code), " ")
+}
+
+// KeywordSearch finds documents whose chunks contain the words of the query,
+// stemmed by the rules of the language. A language limits the search to
+// chunks in that language. Without one the query's language is detected, and
+// when it can't be the query is stemmed for each supported language in turn.
+func (m *MongoDB) KeywordSearch(query string, code string) ([]string, error) {
+	codes := []string{code}
+	if code == "" {
+		if detected := language.Detect(query); detected != "" {
+			codes = []string{detected}
+		} else {
+			codes = language.Supported()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	documentIDs := make(map[string]bool)
+	for _, c := range codes {
+		words := language.Keywords(query, c)
+		if len(words) == 0 {
+			continue
+		}
+		filter := bson.M{"$text": bson.M{
+			"$search":   strings.Join(words, " "),
+			"$language": textSearchLanguage(c),
+		}}
+		if code != "" {
+			filter["language"] = code
+		}
+
+		score := bson.M{"$meta": "textScore"}
+		cursor, err := m.chunks.Find(ctx, filter, options.Find().
+			SetProjection(bson.M{"document_id": 1, "score": score}).
+			SetSort(bson.M{"score": score}).
+			SetLimit(5))
+		if err != nil {
+			return nil, fmt.Errorf("keyword search failed: %w", err)
+		}
+
+		var results []struct {
+			DocumentID string `bson:"document_id"`
+		}
+		err = cursor.All(ctx, &results)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode keyword results: %w", err)
+		}
+		for _, result := range results {
+			documentIDs[result.DocumentID] = true
+		}
+	}
+
+	if len(documentIDs) == 0 {
+		return []string{}, nil
+	}
+	return m.getDocuments(documentIDs)
+}
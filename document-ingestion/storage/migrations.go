@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Chunks have two vector slots. Search reads one while a migration fills the
+// other, each slot has its own model field and vector index.
+const (
+	VectorPath     = "vector"
+	NextVectorPath = "vector_next"
+)
+
+const searchSlotID = "search_slot"
+
+// ModelField is the field holding the model of the vectors at path.
+func ModelField(path string) string {
+	if path == NextVectorPath {
+		return "model_next"
+	}
+	return "model"
+}
+
+// IndexName is the Atlas vector index over path.
+func IndexName(path string) string {
+	if path == NextVectorPath {
+		return "vector_index_next"
+	}
+	return "vector_index"
+}
+
+// OtherPath is the slot a migration writes to while search reads path.
+func OtherPath(path string) string {
+	if path == NextVectorPath {
+		return VectorPath
+	}
+	return NextVectorPath
+}
+
+// ChunkText is a chunk waiting to be embedded by a migration.
+type ChunkText struct {
+	ID   primitive.ObjectID `bson:"_id"`
+	Text string             `bson:"text"`
+}
+
+// SearchSlot returns the slot search reads. Before the first switch that is
+// the first slot, with an empty model as the configured one applies.
+func (m *MongoDB) SearchSlot() (*models.VectorSlot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var slot models.VectorSlot
+	err := m.settings.FindOne(ctx, bson.M{"_id": searchSlotID}).Decode(&slot)
+	if err == mongo.ErrNoDocuments {
+		return &models.VectorSlot{Path: VectorPath}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search slot: %w", err)
+	}
+
+	return &slot, nil
+}
+
+// SwitchSlot points search at slot. It is a single write, so every search
+// after it uses the new slot.
+func (m *MongoDB) SwitchSlot(slot models.VectorSlot) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := m.settings.UpdateOne(ctx,
+		bson.M{"_id": searchSlotID},
+		bson.M{"$set": slot},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to switch search slot: %w", err)
+	}
+
+	return nil
+}
+
+// ResetSlot removes the vectors at path and forgets their dimension, so a
+// migration to a model of another dimension can fill it.
+func (m *MongoDB) ResetSlot(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 600*time.Second)
+	defer cancel()
+
+	_, err := m.chunks.UpdateMany(ctx,
+		bson.M{path: bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{path: "", ModelField(path): ""}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reset %s: %w", path, err)
+	}
+
+	if _, err := m.vectorFields.DeleteOne(ctx, bson.M{"path": path}); err != nil {
+		return fmt.Errorf("failed to reset %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// PendingChunks returns up to limit chunks after the checkpoint that have no
+// vector of model at path, in insertion order.
+func (m *MongoDB) PendingChunks(path, model, after string, limit int) ([]ChunkText, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	filter := bson.M{ModelField(path): bson.M{"$ne": model}}
+	if after != "" {
+		id, err := primitive.ObjectIDFromHex(after)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checkpoint %q: %w", after, err)
+		}
+		filter["_id"] = bson.M{"$gt": id}
+	}
+
+	cursor, err := m.chunks.Find(ctx, filter, options.Find().
+		SetSort(bson.D{bson.E{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"text": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending chunks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var chunks []ChunkText
+	if err := cursor.All(ctx, &chunks); err != nil {
+		return nil, fmt.Errorf("failed to decode pending chunks: %w", err)
+	}
+
+	return chunks, nil
+}
+
+func (m *MongoDB) CountChunks() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	count, err := m.chunks.EstimatedDocumentCount(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count chunks: %w", err)
+	}
+
+	return count, nil
+}
+
+// SetChunkVectors stores vectors of model at path, vectors[i] belongs to
+// chunks[i].
+func (m *MongoDB) SetChunkVectors(path, model string, chunks []ChunkText, vectors [][]float32) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	if len(vectors) != len(chunks) {
+		return fmt.Errorf("got %d vectors for %d chunks", len(vectors), len(chunks))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	if err := m.checkDimensions(ctx, path, vectors); err != nil {
+		return err
+	}
+
+	writes := make([]mongo.WriteModel, len(chunks))
+	for i, chunk := range chunks {
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": chunk.ID}).
+			SetUpdate(bson.M{"$set": bson.M{path: vectors[i], ModelField(path): model}})
+	}
+
+	if _, err := m.chunks.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to store vectors: %w", err)
+	}
+
+	return nil
+}
+
+func (m *MongoDB) SaveMigration(migration *models.Migration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := m.migrations.ReplaceOne(ctx,
+		bson.M{"id": migration.ID},
+		migration,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save migration: %w", err)
+	}
+
+	return nil
+}
+
+func (m *MongoDB) GetMigration(id string) (*models.Migration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var migration models.Migration
+	err := m.migrations.FindOne(ctx, bson.M{"id": id}).Decode(&migration)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration: %w", err)
+	}
+
+	return &migration, nil
+}
+
+// ListMigrations returns the latest migrations first.
+func (m *MongoDB) ListMigrations(limit int) ([]models.Migration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := m.migrations.Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.D{bson.E{Key: "started_at", Value: -1}}).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	migrations := []models.Migration{}
+	if err := cursor.All(ctx, &migrations); err != nil {
+		return nil, fmt.Errorf("failed to decode migrations: %w", err)
+	}
+
+	return migrations, nil
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (m *MongoDB) LoadQueryCache() ([]models.QueryCacheEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := m.queryCache.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load query cache: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.QueryCacheEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode query cache: %w", err)
+	}
+
+	return entries, nil
+}
+
+// SaveQueryCache replaces the stored cache with a snapshot of its entries.
+func (m *MongoDB) SaveQueryCache(entries []models.QueryCacheEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if _, err := m.queryCache.DeleteMany(ctx, bson.M{}); err != nil {
+		return fmt.Errorf("failed to clear query cache: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, len(entries))
+	for i := range entries {
+		documents[i] = entries[i]
+	}
+	if _, err := m.queryCache.InsertMany(ctx, documents); err != nil {
+		return fmt.Errorf("failed to save query cache: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "github.com/ozgurnsahin/document-processor-pp/document-ingestion/data_models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SaveSource stores a new source.
+func (m *MongoDB) SaveSource(source *models.RefreshSource) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	opt := options.Replace().SetUpsert(true)
+	if _, err := m.sources.ReplaceOne(ctx, bson.M{"id": source.ID}, source, opt); err != nil {
+		return fmt.Errorf("failed to save source: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSourceSettings saves the schedule, next run and enabled state of a
+// source. The fields a refresh records are left alone, so a refresh finishing
+// meanwhile isn't overwritten with what was read before it.
+func (m *MongoDB) UpdateSourceSettings(source *models.RefreshSource) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"schedule": source.Schedule,
+		"next_run": source.NextRun,
+		"enabled":  source.Enabled,
+	}
+	if _, err := m.sources.UpdateOne(ctx, bson.M{"id": source.ID}, bson.M{"$set": update}); err != nil {
+		return fmt.Errorf("failed to update source: %w", err)
+	}
+
+	return nil
+}
+
+// GetSource returns nil without an error when the source doesn't exist.
+func (m *MongoDB) GetSource(sourceID string) (*models.RefreshSource, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var source models.RefreshSource
+	err := m.sources.FindOne(ctx, bson.M{"id": sourceID}).Decode(&source)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source: %w", err)
+	}
+
+	return &source, nil
+}
+
+func (m *MongoDB) ListSources() ([]models.RefreshSource, error) {
+	return m.findSources(bson.M{})
+}
+
+// DueSources returns the enabled sources whose next run is not after now.
+func (m *MongoDB) DueSources(now time.Time) ([]models.RefreshSource, error) {
+	return m.findSources(bson.M{"enabled": true, "next_run": bson.M{"$lte": now}})
+}
+
+func (m *MongoDB) findSources(filter bson.M) ([]models.RefreshSource, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := m.sources.Find(ctx, filter, options.Find().SetSort(bson.D{bson.E{Key: "next_run", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sources: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	sources := []models.RefreshSource{}
+	if err := cursor.All(ctx, &sources); err != nil {
+		return nil, fmt.Errorf("failed to decode sources: %w", err)
+	}
+
+	return sources, nil
+}
+
+// ClaimSource moves the next run of a due source forward. Only one of several
+// instances polling at the same time succeeds, so a source is refreshed once.
+func (m *MongoDB) ClaimSource(sourceID string, due, next time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := m.sources.UpdateOne(
+		ctx,
+		bson.M{"id": sourceID, "next_run": due},
+		bson.M{"$set": bson.M{"next_run": next}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim source: %w", err)
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// RecordRefresh appends to the refresh history and updates the source with
+// its outcome.
+func (m *MongoDB) RecordRefresh(refresh *models.Refresh, documentID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := m.refreshes.InsertOne(ctx, refresh); err != nil {
+		return fmt.Errorf("failed to save refresh: %w", err)
+	}
+
+	update := bson.M{
+		"last_run":    refresh.StartedAt,
+		"last_result": refresh.Result,
+	}
+	if documentID != "" {
+		update["document_id"] = documentID
+	}
+	if _, err := m.sources.UpdateOne(ctx, bson.M{"id": refresh.SourceID}, bson.M{"$set": update}); err != nil {
+		return fmt.Errorf("failed to update source: %w", err)
+	}
+
+	return nil
+}
+
+// RefreshHistory returns the latest refreshes of a source, newest first.
+func (m *MongoDB) RefreshHistory(sourceID string, limit int64) ([]models.Refresh, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	opt := options.Find().SetSort(bson.D{bson.E{Key: "started_at", Value: -1}}).SetLimit(limit)
+	cursor, err := m.refreshes.Find(ctx, bson.M{"source_id": sourceID}, opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh history: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	history := []models.Refresh{}
+	if err := cursor.All(ctx, &history); err != nil {
+		return nil, fmt.Errorf("failed to decode refresh history: %w", err)
+	}
+
+	return history, nil
+}
+
+// DeleteSource stops refreshing a source, its documents and history are kept.
+func (m *MongoDB) DeleteSource(sourceID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := m.sources.DeleteOne(ctx, bson.M{"id": sourceID})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete source: %w", err)
+	}
+
+	return result.DeletedCount == 1, nil
+}
@@ -19,6 +19,13 @@ type MongoDB struct{
 	database  *mongo.Database
 	documents *mongo.Collection
 	chunks	  *mongo.Collection	
+	batches   *mongo.Collection
+	sources   *mongo.Collection
+	refreshes *mongo.Collection
+	queryCache *mongo.Collection
+	vectorFields *mongo.Collection
+	migrations *mongo.Collection
+	settings   *mongo.Collection
 }
 
 
@@ -51,6 +58,13 @@ func NewMongoClient() (*MongoDB, error) {
 	db := client.Database(dbName)
 	documents := db.Collection("documents")
 	chunks := db.Collection("chunks")
+	batches := db.Collection("batches")
+	sources := db.Collection("sources")
+	refreshes := db.Collection("refreshes")
+	queryCache := db.Collection("query_cache")
+	vectorFields := db.Collection("vector_fields")
+	migrations := db.Collection("migrations")
+	settings := db.Collection("settings")
 
 	_, err = documents.Indexes().CreateOne(ctx, mongo.IndexModel{
 		Keys: bson.D{bson.E{Key: "id", Value: 1}},
@@ -60,6 +74,13 @@ func NewMongoClient() (*MongoDB, error) {
         log.Printf("Warning: Failed to create document index: %v", err)
     }
     
+    _, err = documents.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{bson.E{Key: "parent_id", Value: 1}},
+    })
+    if err != nil {
+        log.Printf("Warning: Failed to create document parent index: %v", err)
+    }
+
     _, err = chunks.Indexes().CreateOne(ctx, mongo.IndexModel{
 		Keys: bson.D{bson.E{Key: "document_id", Value: 1}},
     })
@@ -67,6 +88,54 @@ func NewMongoClient() (*MongoDB, error) {
         log.Printf("Warning: Failed to create chunks index: %v", err)
     }
     
+    // The keyword index stems the words of each chunk by its language
+    _, err = chunks.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{bson.E{Key: "keywords", Value: "text"}},
+        Options: options.Index().SetDefaultLanguage("none").SetLanguageOverride("keyword_language"),
+    })
+    if err != nil {
+        log.Printf("Warning: Failed to create chunks keyword index: %v", err)
+    }
+
+    _, err = batches.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{bson.E{Key: "id", Value: 1}},
+        Options: options.Index().SetUnique(true),
+    })
+    if err != nil {
+        log.Printf("Warning: Failed to create batches index: %v", err)
+    }
+
+    _, err = sources.Indexes().CreateMany(ctx, []mongo.IndexModel{
+        {Keys: bson.D{bson.E{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+        {Keys: bson.D{bson.E{Key: "enabled", Value: 1}, bson.E{Key: "next_run", Value: 1}}},
+    })
+    if err != nil {
+        log.Printf("Warning: Failed to create sources index: %v", err)
+    }
+
+    _, err = refreshes.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{bson.E{Key: "source_id", Value: 1}, bson.E{Key: "started_at", Value: -1}},
+    })
+    if err != nil {
+        log.Printf("Warning: Failed to create refreshes index: %v", err)
+    }
+
+    _, err = vectorFields.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{bson.E{Key: "path", Value: 1}},
+        Options: options.Index().SetUnique(true),
+    })
+    if err != nil {
+        log.Printf("Warning: Failed to create vector fields index: %v", err)
+    }
+
+    _, err = migrations.Indexes().CreateMany(ctx, []mongo.IndexModel{
+        {Keys: bson.D{bson.E{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+        {Keys: bson.D{bson.E{Key: "started_at", Value: -1}}},
+    })
+    if err != nil {
+        log.Printf("Warning: Failed to create migrations index: %v", err)
+    }
+
     log.Printf("Connected to MongoDB: %s", mongoURI)
     
     return &MongoDB{
@@ -74,6 +143,13 @@ func NewMongoClient() (*MongoDB, error) {
         database:  db,
         documents: documents,
         chunks:    chunks,
+        batches:   batches,
+        sources:   sources,
+        refreshes: refreshes,
+        queryCache: queryCache,
+        vectorFields: vectorFields,
+        migrations: migrations,
+        settings:   settings,
     }, nil
 }
 
@@ -85,9 +161,23 @@ func (m *MongoDB) InsertDocuments(doc *models.Document) error{
 		"id":           doc.ID,
         "filename":     doc.FileName,
         "content_type": doc.ContentType,
+        "format":       doc.Format,
+        "encoding":     doc.Encoding,
         "size":         doc.Size,
+        "sha256":       doc.SHA256,
         "uploaded_at":  doc.UploadedAt,
         "status":       doc.Status,
+        "error":        doc.Error,
+        "kind":         doc.Kind,
+        "parent_id":    doc.ParentID,
+        "metadata":     doc.Metadata,
+        "rejected":     doc.Rejected,
+        "source":       doc.Source,
+        "version":      doc.Version,
+        "pii":          doc.PII,
+        "processing":   doc.Processing,
+        "ocr_pages":    doc.OCRPages,
+        "language":     doc.Language,
 	}
 
 	opt := options.Update().SetUpsert(true)
@@ -113,7 +203,22 @@ func (m *MongoDB) InsertChunks(documentID string, chunks []*models.DocumentChunk
 	ctx, cancel := context.WithTimeout(context.Background(), 300* time.Second)
 	defer cancel()
 
-	_, err := m.chunks.DeleteMany(ctx, bson.M{"document_id": documentID})
+	// New chunks go to the slot search reads, a running migration embeds them
+	// for its slot when it reaches them
+	slot, err := m.SearchSlot()
+	if err != nil {
+		return err
+	}
+
+	vectors := make([][]float32, len(chunks))
+	for i, chunk := range chunks {
+		vectors[i] = chunk.Vector
+	}
+	if err := m.checkDimensions(ctx, slot.Path, vectors); err != nil {
+		return err
+	}
+
+	_, err = m.chunks.DeleteMany(ctx, bson.M{"document_id": documentID})
 
 	if err != nil {
 		return fmt.Errorf("failed to delete existing chunks: %w", err)
@@ -125,7 +230,16 @@ func (m *MongoDB) InsertChunks(documentID string, chunks []*models.DocumentChunk
             "document_id": chunk.DocumentID,
             "chunk_index": chunk.ChunkIndex,
             "text":        chunk.Text,
-            "vector":      chunk.Vector,
+            slot.Path:     chunk.Vector,
+            "pii":         chunk.PII,
+            ModelField(slot.Path): chunk.Model,
+            "start_offset": chunk.StartOffset,
+            "end_offset":  chunk.EndOffset,
+            "heading":     chunk.Heading,
+            "page":        chunk.Page,
+            "language":    chunk.Language,
+            "keywords":    keywords(chunk.Text, chunk.Language),
+            "keyword_language": textSearchLanguage(chunk.Language),
         })
     }
 
@@ -137,18 +251,32 @@ func (m *MongoDB) InsertChunks(documentID string, chunks []*models.DocumentChunk
 	return nil
 }
 
-func (m *MongoDB) SearchDocumetns(queryVector []float32) ([]string, error){
+// SearchDocumetns only compares against chunks embedded by the query's model,
+// vectors of different models aren't comparable. A language limits the
+// search to chunks in that language.
+func (m *MongoDB) SearchDocumetns(queryVector []float32, model string, language string) ([]string, error){
+	slot, err := m.SearchSlot()
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{ModelField(slot.Path): model}
+	if language != "" {
+		filter["language"] = language
+	}
+
 	context, cancel := context.WithTimeout(context.Background(), 30 * time.Second)
 	defer cancel()
 
 	pipeline := bson.A{
 		bson.M{
 			"$vectorSearch": bson.M{
-				"index":         "vector_index",
-				"path":          "vector", 
+				"index":         IndexName(slot.Path),
+				"path":          slot.Path, 
 				"queryVector":   queryVector,
 				"numCandidates": 100,
 				"limit":         5,
+				"filter":        filter,
 			},
 		},
 		bson.M{
@@ -207,7 +335,11 @@ func (m *MongoDB) getDocuments(documentids map[string]bool) ([]string, error){
 		ids = append(ids, id)
 	}
 
-	cursor, err := m.documents.Find(context,  bson.M{"id": bson.M{"$in": ids}})
+	// Older versions of refreshed documents keep their chunks but aren't results
+	cursor, err := m.documents.Find(context,  bson.M{
+		"id":            bson.M{"$in": ids},
+		"superseded_by": bson.M{"$in": bson.A{nil, ""}},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get document names: %w", err)
 	}
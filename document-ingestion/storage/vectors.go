@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrDimensionMismatch is returned for vectors that don't fit the vector
+// index of the collection.
+var ErrDimensionMismatch = errors.New("vector dimension mismatch")
+
+// VectorField records the dimension of the vectors stored at a path of the
+// chunks collection. The first vectors written pin it, as the vector index
+// only accepts one dimension.
+type VectorField struct {
+	Path       string    `json:"path" bson:"path"`
+	Dimensions int       `json:"dimensions" bson:"dimensions"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+}
+
+func (m *MongoDB) checkDimensions(ctx context.Context, path string, vectors [][]float32) error {
+	if len(vectors) == 0 {
+		return nil
+	}
+
+	dimensions := len(vectors[0])
+	for i, vector := range vectors {
+		if len(vector) != dimensions {
+			return fmt.Errorf("%w: vector %d has %d dimensions, vector 0 has %d", ErrDimensionMismatch, i, len(vector), dimensions)
+		}
+	}
+
+	field, err := m.vectorField(ctx, path, dimensions)
+	if err != nil {
+		return err
+	}
+	if field.Dimensions != dimensions {
+		return fmt.Errorf("%w: vectors have %d dimensions, %s holds %d", ErrDimensionMismatch, dimensions, path, field.Dimensions)
+	}
+
+	return nil
+}
+
+// vectorField returns the field at path, recording it with dimensions when it
+// is new.
+func (m *MongoDB) vectorField(ctx context.Context, path string, dimensions int) (*VectorField, error) {
+	var field VectorField
+	find := func() error {
+		return m.vectorFields.FindOneAndUpdate(ctx,
+			bson.M{"path": path},
+			bson.M{"$setOnInsert": VectorField{Path: path, Dimensions: dimensions, CreatedAt: time.Now()}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&field)
+	}
+
+	err := find()
+	if mongo.IsDuplicateKeyError(err) {
+		// Another writer recorded the field at the same time
+		err = find()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vector field %s: %w", path, err)
+	}
+
+	return &field, nil
+}
+
+// LabelChunks records model on chunks stored before chunks carried their
+// model, so search keeps finding them. Those chunks only have vectors in the
+// first slot.
+func (m *MongoDB) LabelChunks(model string) (int64, error) {
+	slot, err := m.SearchSlot()
+	if err != nil {
+		return 0, err
+	}
+	if slot.Path != VectorPath {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+
+	result, err := m.chunks.UpdateMany(ctx,
+		bson.M{
+			VectorPath: bson.M{"$exists": true},
+			"$or":      bson.A{bson.M{"model": bson.M{"$exists": false}}, bson.M{"model": ""}},
+		},
+		bson.M{"$set": bson.M{"model": model}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to label chunks: %w", err)
+	}
+
+	return result.ModifiedCount, nil
+}
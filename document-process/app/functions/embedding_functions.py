@@ -1,3 +1,4 @@
+import os
 import numpy as np
 from openai import OpenAI
 from dotenv import load_dotenv
@@ -7,7 +8,16 @@ from typing import List
 class EmbeddingFunctions:
     def __init__(self):
         load_dotenv()
-        self.client = OpenAI()
+        self._client = None
+        self.model = os.getenv("EMBEDDING_MODEL", "text-embedding-3-small")
+
+    @property
+    def client(self) -> OpenAI:
+        # Created on first use so the service starts without an API key when
+        # the ingestion service embeds the chunks itself
+        if self._client is None:
+            self._client = OpenAI()
+        return self._client
 
     def create_embeddings_from_sentences(
         self, sentences: List[str], chunk_size: int = 2000
@@ -15,7 +25,7 @@ class EmbeddingFunctions:
         file_embeddings = []
         for chunk_index in range(0, len(sentences), chunk_size):
             chunk_embeddings = self.client.embeddings.create(
-                model="text-embedding-3-small",
+                model=self.model,
                 input=sentences[chunk_index : chunk_index + chunk_size],
             )
             chunk_array = np.array(
@@ -27,9 +37,24 @@ class EmbeddingFunctions:
 
         return np.vstack(file_embeddings)
 
+    def create_embeddings_from_inputs(
+        self, sentences: List[str], model: str = None, chunk_size: int = 2000
+    ) -> List[np.ndarray]:
+        embeddings = []
+        for chunk_index in range(0, len(sentences), chunk_size):
+            chunk_embeddings = self.client.embeddings.create(
+                model=model or self.model,
+                input=sentences[chunk_index : chunk_index + chunk_size],
+            )
+            embeddings.extend(
+                np.array(x.embedding, dtype=np.float32) for x in chunk_embeddings.data
+            )
+
+        return embeddings
+
     def create_embedding_from_input(self, sentence: str) -> np.ndarray:
         query_embedding = self.client.embeddings.create(
-            model="text-embedding-3-small",
+            model=self.model,
             input=sentence,
         )
 
@@ -1,5 +1,6 @@
 from typing import List, Dict
 from langchain_text_splitters import (
+    CharacterTextSplitter,
     RecursiveCharacterTextSplitter,
     MarkdownHeaderTextSplitter,
 )
@@ -7,14 +8,17 @@ import fitz
 import pymupdf4llm
 import io
 
+DEFAULT_CHUNK_SIZE = 200
+DEFAULT_CHUNK_OVERLAP = 50
+SENTENCE_SEPARATORS = ["\n\n", "\n", ". ", "! ", "? ", "; ", ", ", " ", ""]
+# Tesseract names of the languages OCR is asked for
+OCR_LANGUAGES = {"en": "eng", "de": "deu", "tr": "tur"}
+
 
 class ProcessorFunctions:
     def __init__(self):
-        self.text_splitters = RecursiveCharacterTextSplitter(
-            chunk_size=200,
-            chunk_overlap=50,
-            length_function=len,
-            is_separator_regex=False,
+        self.text_splitters = self._text_splitter(
+            "recursive", DEFAULT_CHUNK_SIZE, DEFAULT_CHUNK_OVERLAP
         )
         self.headers_to_split_on = [
             ("#", "Header 1"),
@@ -26,40 +30,133 @@ class ProcessorFunctions:
             self.headers_to_split_on, strip_headers=False, return_each_line=True
         )
 
-    def read_file(self, file_bytes: bytes, content_type: str):
+    def read_file(self, file_bytes: bytes, content_type: str, options=None):
+        settings = self._settings(options)
         if content_type == "application/pdf":
-            return self._process_pdf(file_bytes=file_bytes)
+            return self._process_pdf(file_bytes=file_bytes, settings=settings)
         elif content_type in ["text/plain; charset=utf-8", "text/rtf; charset=utf-8"]:
-            return self._process_txt(file_bytes=file_bytes)
+            return self._process_txt(file_bytes=file_bytes, settings=settings)
+        elif content_type == "text/markdown; charset=utf-8":
+            return self._process_markdown(file_bytes=file_bytes, settings=settings)
         else:
             raise ValueError(f"Unsupported file type: {content_type}")
 
+    def _settings(self, options) -> Dict[str, any]:
+        """Turns the ProcessingOptions of a request into splitters, unset
+        fields keep the defaults."""
+        settings = {
+            "strategy": "",
+            "splitter": self.text_splitters,
+            "ocr": False,
+            "ocr_language": "eng",
+        }
+        if options is None:
+            return settings
+
+        strategy = options.chunk_strategy
+        if strategy or options.chunk_size or options.chunk_overlap:
+            chunk_size = options.chunk_size or DEFAULT_CHUNK_SIZE
+            chunk_overlap = options.chunk_overlap
+            if not options.chunk_size and not options.chunk_overlap:
+                chunk_overlap = DEFAULT_CHUNK_OVERLAP
+            settings["strategy"] = strategy
+            settings["splitter"] = self._text_splitter(
+                strategy or "recursive", chunk_size, chunk_overlap
+            )
+
+        settings["ocr"] = options.ocr
+        if options.language:
+            if options.ocr and options.language not in OCR_LANGUAGES:
+                raise ValueError(f"OCR does not support language: {options.language}")
+            settings["ocr_language"] = OCR_LANGUAGES.get(options.language, "eng")
+        return settings
+
+    def _text_splitter(self, strategy: str, chunk_size: int, chunk_overlap: int):
+        if strategy == "token":
+            return RecursiveCharacterTextSplitter.from_tiktoken_encoder(
+                chunk_size=chunk_size, chunk_overlap=chunk_overlap
+            )
+        if strategy == "fixed":
+            return CharacterTextSplitter(
+                separator="",
+                chunk_size=chunk_size,
+                chunk_overlap=chunk_overlap,
+            )
+        if strategy in ("recursive", "markdown"):
+            separators = None
+        elif strategy == "sentence":
+            separators = SENTENCE_SEPARATORS
+        else:
+            raise ValueError(f"Unknown chunking strategy: {strategy}")
+        return RecursiveCharacterTextSplitter(
+            separators=separators,
+            chunk_size=chunk_size,
+            chunk_overlap=chunk_overlap,
+            length_function=len,
+            is_separator_regex=False,
+        )
+
     def process_document_content(self, file) -> List[Dict[str, any]]:
         processed_text = self.read_file(file)
 
         return processed_text
 
-    def _process_pdf(self, file_bytes: bytes):
+    def _process_pdf(self, file_bytes: bytes, settings: Dict[str, any]):
         pdf_file = io.BytesIO(file_bytes)
         pdf_data = {"sentences": [], "page_number": []}
         with fitz.open(stream=pdf_file, filetype="pdf") as pdf:
             markdown_pages = pymupdf4llm.to_markdown(
                 pdf, page_chunks=True, show_progress=False, margins=0
             )
+            if settings["ocr"]:
+                self._ocr_empty_pages(pdf, markdown_pages, settings["ocr_language"])
             for i, page in enumerate(markdown_pages):
-                splits = self.markdown_splitter.split_text(page["text"])
+                # Pages are split at headings unless another strategy was asked for
+                if settings["strategy"] in ("", "markdown"):
+                    splits = [
+                        split.page_content
+                        for split in self.markdown_splitter.split_text(page["text"])
+                    ]
+                    if settings["strategy"] == "markdown":
+                        splits = [
+                            part
+                            for split in splits
+                            for part in settings["splitter"].split_text(split)
+                        ]
+                else:
+                    splits = settings["splitter"].split_text(page["text"])
                 for split in splits:
-                    if not len(split.page_content) > 5:
+                    if not len(split) > 5:
                         continue
                     else:
-                        pdf_data["sentences"].append(split.page_content)
+                        pdf_data["sentences"].append(split)
                         pdf_data["page_number"].append(i + 1)
         return pdf_data
 
-    def _process_txt(self, file_bytes: bytes):
+    def _ocr_empty_pages(self, pdf, markdown_pages, language: str):
+        """Recognizes the text of pages that only hold images, which needs
+        Tesseract with the language data installed."""
+        for i, page in enumerate(pdf):
+            if markdown_pages[i]["text"].strip():
+                continue
+            textpage = page.get_textpage_ocr(language=language, dpi=300, full=True)
+            markdown_pages[i]["text"] = page.get_text(textpage=textpage)
+
+    def _process_markdown(self, file_bytes: bytes, settings: Dict[str, any]):
+        markdown_data = {"sentences": [], "page_number": []}
+        text = file_bytes.decode("utf-8", errors="ignore")
+        for split in self.markdown_splitter.split_text(text):
+            if not len(split.page_content) > 5:
+                continue
+            for sentence in settings["splitter"].split_text(split.page_content):
+                markdown_data["sentences"].append(sentence.strip())
+                markdown_data["page_number"].append(1)
+        return markdown_data
+
+    def _process_txt(self, file_bytes: bytes, settings: Dict[str, any]):
         text_data = {"sentences": [], "page_number": []}
         text = file_bytes.decode("utf-8", errors="ignore")
-        splits = self.text_splitters.split_text(text)
+        splits = settings["splitter"].split_text(text)
         for sentence in splits:
             text_data["sentences"].append(sentence.strip())
         text_data["page_number"].extend([1] * len(splits))
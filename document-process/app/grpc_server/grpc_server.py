@@ -26,19 +26,37 @@ class ProcessorService:
                 f"Received document: {document_id}, {filename}, size: {len(content)} bytes"
             )
 
+            options = request.options if request.HasField("options") else None
             processed_data = self.processor.read_file(
-                file_bytes=content, content_type=content_type
+                file_bytes=content, content_type=content_type, options=options
             )
 
             logger.info(f"Processed document: {document_id}")
 
+            # The caller embeds the chunks itself, e.g. offline
+            if request.skip_embedding:
+                response = pb2.ProcessResponse(
+                    document_id=document_id, status="completed"
+                )
+                for sen in processed_data["sentences"]:
+                    response.chunks.append(pb2.ProcessedChunk(text=sen))
+                logger.info(
+                    f"Successfully processed document {document_id} without embedding: {len(processed_data['sentences'])} chunks"
+                )
+                return response
+
             embeddings = self.embedder.create_embeddings_from_sentences(
                 sentences=processed_data["sentences"]
             )
 
             logger.info(f"Embeded sentences of document: {document_id}")
 
-            response = pb2.ProcessResponse(document_id=document_id, status="completed")
+            response = pb2.ProcessResponse(
+                document_id=document_id,
+                status="completed",
+                model=self.embedder.model,
+                dimensions=embeddings.shape[1] if len(embeddings) else 0,
+            )
 
             for sen, embed in zip(processed_data["sentences"], embeddings):
                 chunk = pb2.ProcessedChunk(text=sen, vector=embed)
@@ -62,7 +80,11 @@ class ProcessorService:
 
             embeddings = self.embedder.create_embedding_from_input(text)
 
-            response = pb2.EmbeddingResponse(vector=embeddings)
+            response = pb2.EmbeddingResponse(
+                vector=embeddings,
+                model=self.embedder.model,
+                dimensions=len(embeddings),
+            )
 
             logger.info("Successfully created embedding")
             return response
@@ -70,6 +92,27 @@ class ProcessorService:
             logger.error(f"error creating embedding: {e}")
             return pb2.EmbeddingResponse(error=str(e))
 
+    def CreateEmbeddings(self, request, context):
+        try:
+            texts = list(request.texts)
+            model = request.model or self.embedder.model
+            logger.info(f"Creating embeddings for {len(texts)} texts with {model}")
+
+            response = pb2.EmbeddingsResponse(model=model)
+            if texts:
+                embeddings = self.embedder.create_embeddings_from_inputs(
+                    texts, model=model
+                )
+                for embedding in embeddings:
+                    response.embeddings.append(pb2.Embedding(vector=embedding))
+                response.dimensions = len(embeddings[0])
+
+            logger.info("Successfully created embeddings")
+            return response
+        except Exception as e:
+            logger.error(f"error creating embeddings: {e}")
+            return pb2.EmbeddingsResponse(error=str(e))
+
 
 class GRPCServer:
     def __init__(self, port=50052, max_workers=10):
@@ -13,7 +13,7 @@ _sym_db = _symbol_database.Default()
 
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x1cproto/document_process.proto\x12\x08\x64ocument\"^\n\x0eProcessRequest\x12\x0f\n\x07\x63ontent\x18\x01 \x01(\x0c\x12\x10\n\x08\x66ilename\x18\x02 \x01(\t\x12\x14\n\x0c\x63ontent_type\x18\x03 \x01(\t\x12\x13\n\x0b\x64ocument_id\x18\x04 \x01(\t\"o\n\x0fProcessResponse\x12\x13\n\x0b\x64ocument_id\x18\x01 \x01(\t\x12\x0e\n\x06status\x18\x02 \x01(\t\x12\r\n\x05\x65rror\x18\x03 \x01(\t\x12(\n\x06\x63hunks\x18\x04 \x03(\x0b\x32\x18.document.ProcessedChunk\".\n\x0eProcessedChunk\x12\x0c\n\x04text\x18\x01 \x01(\t\x12\x0e\n\x06vector\x18\x02 \x03(\x02\" \n\x10\x45mbeddingRequest\x12\x0c\n\x04text\x18\x01 \x01(\t\"2\n\x11\x45mbeddingResponse\x12\x0e\n\x06vector\x18\x01 \x03(\x02\x12\r\n\x05\x65rror\x18\x02 \x01(\t2\xae\x01\n\x18\x44ocumentProcessorService\x12\x46\n\x0fProcessDocument\x12\x18.document.ProcessRequest\x1a\x19.document.ProcessResponse\x12J\n\x0f\x43reateEmbedding\x12\x1a.document.EmbeddingRequest\x1a\x1b.document.EmbeddingResponseB4Z2github.com/ozgurnsahin/document-processor-pp/protob\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x1cproto/document_process.proto\x12\x08\x64ocument\"\xa4\x01\n\x0eProcessRequest\x12\x0f\n\x07\x63ontent\x18\x01 \x01(\x0c\x12\x10\n\x08\x66ilename\x18\x02 \x01(\t\x12\x14\n\x0c\x63ontent_type\x18\x03 \x01(\t\x12\x13\n\x0b\x64ocument_id\x18\x04 \x01(\t\x12,\n\x07options\x18\x05 \x01(\x0b\x32\x1b.document.ProcessingOptions\x12\x16\n\x0eskip_embedding\x18\x06 \x01(\x08\"u\n\x11ProcessingOptions\x12\x16\n\x0e\x63hunk_strategy\x18\x01 \x01(\t\x12\x12\n\nchunk_size\x18\x02 \x01(\x05\x12\x15\n\rchunk_overlap\x18\x03 \x01(\x05\x12\x10\n\x08language\x18\x04 \x01(\t\x12\x0b\n\x03ocr\x18\x05 \x01(\x08\"\x92\x01\n\x0fProcessResponse\x12\x13\n\x0b\x64ocument_id\x18\x01 \x01(\t\x12\x0e\n\x06status\x18\x02 \x01(\t\x12\r\n\x05\x65rror\x18\x03 \x01(\t\x12(\n\x06\x63hunks\x18\x04 \x03(\x0b\x32\x18.document.ProcessedChunk\x12\r\n\x05model\x18\x05 \x01(\t\x12\x12\n\ndimensions\x18\x06 \x01(\x05\".\n\x0eProcessedChunk\x12\x0c\n\x04text\x18\x01 \x01(\t\x12\x0e\n\x06vector\x18\x02 \x03(\x02\" \n\x10\x45mbeddingRequest\x12\x0c\n\x04text\x18\x01 \x01(\t\"U\n\x11\x45mbeddingResponse\x12\x0e\n\x06vector\x18\x01 \x03(\x02\x12\r\n\x05\x65rror\x18\x02 \x01(\t\x12\r\n\x05model\x18\x03 \x01(\t\x12\x12\n\ndimensions\x18\x04 \x01(\x05\"1\n\x11\x45mbeddingsRequest\x12\r\n\x05texts\x18\x01 \x03(\t\x12\r\n\x05model\x18\x02 \x01(\t\"o\n\x12\x45mbeddingsResponse\x12\'\n\nembeddings\x18\x01 \x03(\x0b\x32\x13.document.Embedding\x12\r\n\x05\x65rror\x18\x02 \x01(\t\x12\r\n\x05model\x18\x03 \x01(\t\x12\x12\n\ndimensions\x18\x04 \x01(\x05\"\x1b\n\tEmbedding\x12\x0e\n\x06vector\x18\x01 \x03(\x02\x32\xfd\x01\n\x18\x44ocumentProcessorService\x12\x46\n\x0fProcessDocument\x12\x18.document.ProcessRequest\x1a\x19.document.ProcessResponse\x12J\n\x0f\x43reateEmbedding\x12\x1a.document.EmbeddingRequest\x1a\x1b.document.EmbeddingResponse\x12M\n\x10\x43reateEmbeddings\x12\x1b.document.EmbeddingsRequest\x1a\x1c.document.EmbeddingsResponseB4Z2github.com/ozgurnsahin/document-processor-pp/protob\x06proto3')
 
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, globals())
 _builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, 'proto.document_process_pb2', globals())
@@ -21,16 +21,24 @@ if _descriptor._USE_C_DESCRIPTORS == False:
 
   DESCRIPTOR._options = None
   DESCRIPTOR._serialized_options = b'Z2github.com/ozgurnsahin/document-processor-pp/proto'
-  _PROCESSREQUEST._serialized_start=42
-  _PROCESSREQUEST._serialized_end=136
-  _PROCESSRESPONSE._serialized_start=138
-  _PROCESSRESPONSE._serialized_end=249
-  _PROCESSEDCHUNK._serialized_start=251
-  _PROCESSEDCHUNK._serialized_end=297
-  _EMBEDDINGREQUEST._serialized_start=299
-  _EMBEDDINGREQUEST._serialized_end=331
-  _EMBEDDINGRESPONSE._serialized_start=333
-  _EMBEDDINGRESPONSE._serialized_end=383
-  _DOCUMENTPROCESSORSERVICE._serialized_start=386
-  _DOCUMENTPROCESSORSERVICE._serialized_end=560
+  _PROCESSREQUEST._serialized_start=43
+  _PROCESSREQUEST._serialized_end=207
+  _PROCESSINGOPTIONS._serialized_start=209
+  _PROCESSINGOPTIONS._serialized_end=326
+  _PROCESSRESPONSE._serialized_start=329
+  _PROCESSRESPONSE._serialized_end=475
+  _PROCESSEDCHUNK._serialized_start=477
+  _PROCESSEDCHUNK._serialized_end=523
+  _EMBEDDINGREQUEST._serialized_start=525
+  _EMBEDDINGREQUEST._serialized_end=557
+  _EMBEDDINGRESPONSE._serialized_start=559
+  _EMBEDDINGRESPONSE._serialized_end=644
+  _EMBEDDINGSREQUEST._serialized_start=646
+  _EMBEDDINGSREQUEST._serialized_end=695
+  _EMBEDDINGSRESPONSE._serialized_start=697
+  _EMBEDDINGSRESPONSE._serialized_end=808
+  _EMBEDDING._serialized_start=810
+  _EMBEDDING._serialized_end=837
+  _DOCUMENTPROCESSORSERVICE._serialized_start=840
+  _DOCUMENTPROCESSORSERVICE._serialized_end=1093
 # @@protoc_insertion_point(module_scope)
@@ -24,6 +24,11 @@ class DocumentProcessorServiceStub(object):
                 request_serializer=proto_dot_document__process__pb2.EmbeddingRequest.SerializeToString,
                 response_deserializer=proto_dot_document__process__pb2.EmbeddingResponse.FromString,
                 )
+        self.CreateEmbeddings = channel.unary_unary(
+                '/document.DocumentProcessorService/CreateEmbeddings',
+                request_serializer=proto_dot_document__process__pb2.EmbeddingsRequest.SerializeToString,
+                response_deserializer=proto_dot_document__process__pb2.EmbeddingsResponse.FromString,
+                )
 
 
 class DocumentProcessorServiceServicer(object):
@@ -41,6 +46,12 @@ class DocumentProcessorServiceServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def CreateEmbeddings(self, request, context):
+        """Missing associated documentation comment in .proto file."""
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
 
 def add_DocumentProcessorServiceServicer_to_server(servicer, server):
     rpc_method_handlers = {
@@ -54,6 +65,11 @@ def add_DocumentProcessorServiceServicer_to_server(servicer, server):
                     request_deserializer=proto_dot_document__process__pb2.EmbeddingRequest.FromString,
                     response_serializer=proto_dot_document__process__pb2.EmbeddingResponse.SerializeToString,
             ),
+            'CreateEmbeddings': grpc.unary_unary_rpc_method_handler(
+                    servicer.CreateEmbeddings,
+                    request_deserializer=proto_dot_document__process__pb2.EmbeddingsRequest.FromString,
+                    response_serializer=proto_dot_document__process__pb2.EmbeddingsResponse.SerializeToString,
+            ),
     }
     generic_handler = grpc.method_handlers_generic_handler(
             'document.DocumentProcessorService', rpc_method_handlers)
@@ -97,3 +113,20 @@ class DocumentProcessorService(object):
             proto_dot_document__process__pb2.EmbeddingResponse.FromString,
             options, channel_credentials,
             insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def CreateEmbeddings(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(request, target, '/document.DocumentProcessorService/CreateEmbeddings',
+            proto_dot_document__process__pb2.EmbeddingsRequest.SerializeToString,
+            proto_dot_document__process__pb2.EmbeddingsResponse.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)